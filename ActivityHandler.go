@@ -2,8 +2,12 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"html"
+	"log/slog"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -20,40 +24,271 @@ const (
 	workplanOptionAddEvent         = "addEvent"
 	workplanOptionUpdateEvent      = "updateEvent"
 	workplanOptionDeleteEvent      = "deleteEvent"
+	workplanOptionCreatePoll       = "createPoll"
+	workplanOptionByOrg            = "byOrg"
+	workplanOptionByLead           = "byLead"
+	workplanOptionMine             = "mine"
 
 	workplanViewByMonthCallbackPrefix    = "wpViewByMonth"
 	workplanViewByMonthCallbackOptionAll = "all"
 
-	workplanUpdateEventCallbackPrefix          = "wpUpdateevent"
-	workplanUpdateEventCallbackOptionName      = "name"
-	workplanUpdateEventCallbackOptionStartedAt = "startedAt"
-	workplanUpdateEventCallbackOptionCommittee = "committee"
-	workplanUpdateEventCallbackOptionLead      = "lead"
-	workplanUpdateEventCallbackOptionCoLead    = "coLead"
+	workplanFilterOrgCallbackPrefix = "wpFilterOrg"
+	workplanRestoreCallbackPrefix   = "wpRestore"
+
+	workplanUpdateEventCallbackPrefix            = "wpUpdateevent"
+	workplanUpdateEventCallbackOptionName        = "name"
+	workplanUpdateEventCallbackOptionStartedAt   = "startedAt"
+	workplanUpdateEventCallbackOptionCommittee   = "committee"
+	workplanUpdateEventCallbackOptionLead        = "lead"
+	workplanUpdateEventCallbackOptionCoLead      = "coLead"
+	workplanUpdateEventCallbackOptionEndsAt      = "endsAt"
+	workplanUpdateEventCallbackOptionLocation    = "location"
+	workplanUpdateEventCallbackOptionDescription = "description"
+	workplanUpdateEventCallbackOptionStatus      = "status"
+
+	// activityClearInput clears an optional field
+	activityClearInput = "-"
+
+	workplanActivityIDField = "activityID"
+
+	workplanCommandConflicts = "conflicts"
+	workplanCommandImport    = "import"
+	workplanCommandSearch    = "search"
+	workplanCommandTrash     = "trash"
 )
 
-var (
-	activityStepPrompts = map[int]string{
-		1: "Please provide the name for the activity.",
-		2: "Please enter the start time (e.g., YYYY-MM-DD HH:MM).",
-		3: fmt.Sprintf("Please enter the name of the organizing committee. One of %v", AllOrgs),
-		4: "Please enter the name of the lead.",
-		5: "Please enter the name of the co-lead, separated by semicolon(e.g. Person A; Person B)",
-	}
+// orgButtonsPerRow is the number of organizations per row of the wizard buttons
+const orgButtonsPerRow = 3
+
+// activitySearchLimit is the number of the latest activities shown by a search or filter
+const activitySearchLimit = 50
+
+const (
+	// activityTrashRetentionDays is how long deleted activities can be restored before they are purged
+	activityTrashRetentionDays = 30
+	activityTrashPurgeInterval = time.Hour
+	// activityTrashListLimit is the number of the most recently deleted activities shown by /workplan trash
+	activityTrashListLimit = 20
 )
 
 type ActivityHandler struct {
-	activityDAO *ActivityDAO
+	activityDAO  ActivityRepository
+	orgs         OrgRepository
+	events       EventRepository
+	knownUsers   KnownUserRepository
+	notifier     *LeadNotifier
+	userStates   StateStore
+	chatSettings ChatSettingsRepository
+	addWizard    *Wizard
+	updateWizard *Wizard
+	deleteWizard *Wizard
+	pollWizard   *Wizard
+	importWizard *Wizard
+	leadWizard   *Wizard
+}
+
+func NewActivityHandler(activityDao ActivityRepository, orgs OrgRepository, events EventRepository, knownUsers KnownUserRepository,
+	notifier *LeadNotifier, userStates StateStore, chatSettings ChatSettingsRepository) *ActivityHandler {
+	h := &ActivityHandler{
+		activityDAO:  activityDao,
+		orgs:         orgs,
+		events:       events,
+		knownUsers:   knownUsers,
+		notifier:     notifier,
+		userStates:   userStates,
+		chatSettings: chatSettings,
+	}
+	activityFields := h.activityFields()
+	h.addWizard = &Wizard{
+		StateType:    ADD_ACTIVITY,
+		Fields:       activityFields,
+		OnComplete:   h.completeAddActivity,
+		userStates:   userStates,
+		chatSettings: chatSettings,
+	}
+	h.updateWizard = &Wizard{
+		StateType: UPDATE_ACTIVITY,
+		Fields: []WizardField{
+			{
+				Name:   workplanActivityIDField,
+				Prompt: msgActivityUpdatePrompt,
+				Parse:  h.parseActivityToUpdate,
+			},
+		},
+		EditFields:   slices.Concat(activityFields, h.activityDetailFields()),
+		OnComplete:   h.completeSelectActivityToUpdate,
+		OnEdit:       h.completeUpdateActivity,
+		ShowMenu:     h.sendUpdateActivityMenu,
+		userStates:   userStates,
+		chatSettings: chatSettings,
+	}
+	h.deleteWizard = &Wizard{
+		StateType: DELETE_ACTIVITY,
+		Fields: []WizardField{
+			{
+				Name:    workplanActivityIDField,
+				Prompt:  msgActivityDeletePrompt,
+				Parse:   h.parseActivityToDelete,
+				Confirm: confirmDeleteActivity,
+			},
+		},
+		OnComplete:   h.completeDeleteActivity,
+		userStates:   userStates,
+		chatSettings: chatSettings,
+	}
+	h.pollWizard = &Wizard{
+		StateType: CREATE_ACTIVITY_POLL,
+		Fields: []WizardField{
+			{
+				Name:   workplanActivityIDField,
+				Prompt: msgActivityPollPrompt,
+				Parse:  h.parseActivityToPoll,
+			},
+		},
+		OnComplete:   h.completeCreateActivityPoll,
+		userStates:   userStates,
+		chatSettings: chatSettings,
+	}
+	h.importWizard = &Wizard{
+		StateType: IMPORT_ACTIVITIES,
+		Fields: []WizardField{
+			{
+				Name:       activityImportFileField,
+				Prompt:     msgActivityImportPrompt,
+				PromptArgs: []any{startTimeExamples},
+				Parse:      h.parseActivityImport,
+				Confirm:    confirmActivityImport,
+			},
+		},
+		OnComplete:   h.completeImportActivities,
+		userStates:   userStates,
+		chatSettings: chatSettings,
+	}
+	h.leadWizard = &Wizard{
+		StateType: FILTER_ACTIVITIES_BY_LEAD,
+		Fields: []WizardField{
+			{
+				Name:   workplanUpdateEventCallbackOptionLead,
+				Prompt: msgActivityFilterLeadPrompt,
+				Parse:  h.parseActivityLeadFilter,
+			},
+		},
+		OnComplete:   h.completeFilterByLead,
+		userStates:   userStates,
+		chatSettings: chatSettings,
+	}
+	return h
+}
+
+// activityFields are the fields collected when adding an activity, each of them can be updated
+func (h *ActivityHandler) activityFields() []WizardField {
+	return []WizardField{
+		{
+			Name:   workplanUpdateEventCallbackOptionName,
+			Prompt: msgActivityNamePrompt,
+			Parse:  parseActivityName,
+		},
+		{
+			Name:       workplanUpdateEventCallbackOptionStartedAt,
+			Prompt:     msgActivityStartedPrompt,
+			PromptArgs: []any{startTimeExamples},
+			Parse:      parseActivityStartedAt,
+			Echo:       echoActivityStartedAt,
+			Confirm:    h.confirmActivitySlot,
+			DatePicker: true,
+		},
+		{
+			Name:     workplanUpdateEventCallbackOptionCommittee,
+			Prompt:   msgActivityOrgPrompt,
+			Keyboard: h.getOrgButtonRows,
+			Parse:    h.parseActivityOrg,
+		},
+		{
+			Name:    workplanUpdateEventCallbackOptionLead,
+			Prompt:  msgActivityLeadPrompt,
+			Parse:   h.parseActivityLead,
+			Echo:    echoUnknownLead,
+			Confirm: h.confirmActivityLead,
+		},
+		{
+			Name:   workplanUpdateEventCallbackOptionCoLead,
+			Prompt: msgActivityCoLeadPrompt,
+			Parse:  h.parseActivityCoLeads,
+			Echo:   echoUnknownCoLeads,
+		},
+	}
+}
+
+// activityDetailFields are the optional fields which can only be updated
+func (h *ActivityHandler) activityDetailFields() []WizardField {
+	return []WizardField{
+		{
+			Name:       workplanUpdateEventCallbackOptionEndsAt,
+			Prompt:     msgActivityEndsAtPrompt,
+			PromptArgs: []any{startTimeExamples},
+			Parse:      parseActivityEndsAt,
+			Echo:       echoActivityEndsAt,
+			DatePicker: true,
+		},
+		{
+			Name:   workplanUpdateEventCallbackOptionLocation,
+			Prompt: msgActivityLocationPrompt,
+			Parse:  parseActivityLocation,
+		},
+		{
+			Name:   workplanUpdateEventCallbackOptionDescription,
+			Prompt: msgActivityDescriptionPrompt,
+			Parse:  parseActivityDescription,
+		},
+		{
+			Name:     workplanUpdateEventCallbackOptionStatus,
+			Prompt:   msgActivityStatusPrompt,
+			Keyboard: getActivityStatusButtonRows,
+			Parse:    parseActivityStatusInput,
+		},
+	}
 }
 
-func NewActivityHandler(activityDao *ActivityDAO) *ActivityHandler {
-	return &ActivityHandler{activityDAO: activityDao}
+// wizards returns the wizards driving the multi-step flows of this handler
+func (h *ActivityHandler) wizards() []*Wizard {
+	return []*Wizard{h.addWizard, h.updateWizard, h.deleteWizard, h.pollWizard, h.importWizard, h.leadWizard}
 }
 
-func (h *ActivityHandler) handleWorkplan(ctx context.Context, b *bot.Bot, update *models.Update) {
+func (h *ActivityHandler) handleWorkplan(ctx context.Context, b Messenger, update *models.Update) {
 	chatID := update.Message.Chat.ID
 	msgThreadID := update.Message.MessageThreadID
-	kb, msg := h.getWorkplanMenu()
+	l := h.chatSettings.GetLocalizer(ctx, chatID)
+	if args := getCommandArguments(update); len(args) > 0 {
+		switch args[0] {
+		case workplanCommandConflicts:
+			h.sendConflicts(ctx, b, chatID, msgThreadID, args[1:], l)
+			return
+		case workplanCommandTrash:
+			h.sendTrash(ctx, b, chatID, msgThreadID, l)
+			return
+		case workplanCommandSearch:
+			text := strings.Join(args[1:], " ")
+			if text == "" {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID:          chatID,
+					MessageThreadID: msgThreadID,
+					Text:            l.T(msgActivitySearchUsage),
+				})
+				return
+			}
+			h.sendFilteredActivities(ctx, b, chatID, msgThreadID, l.T(msgActivitySearchLabel, text), ActivityFilter{Text: text}, l)
+			return
+		case workplanCommandImport:
+			from := update.Message.From
+			h.importWizard.Start(ctx, b, getUserStateKey(chatID, msgThreadID, from), &UserState{
+				ChatID:      chatID,
+				MsgThreadID: msgThreadID,
+				Activity:    Activity{CreatedBy: getUserFullName(from), CreatedByID: from.ID},
+			})
+			return
+		}
+	}
+	kb, msg := h.getWorkplanMenu(l)
 	b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:          chatID,
 		MessageThreadID: msgThreadID,
@@ -62,34 +297,42 @@ func (h *ActivityHandler) handleWorkplan(ctx context.Context, b *bot.Bot, update
 	})
 }
 
-func (h *ActivityHandler) getWorkplanMenu() (models.InlineKeyboardMarkup, string) {
+func (h *ActivityHandler) getWorkplanMenu(l *Localizer) (models.InlineKeyboardMarkup, string) {
 	inlineKeyboard := [][]models.InlineKeyboardButton{
 		{
-			{Text: "View This Mo", CallbackData: strings.Join([]string{workplanCallbackPrefix, workplanOptionViewCurrentMonth}, callbackSeparator)},
-			{Text: "View By Mo", CallbackData: strings.Join([]string{workplanCallbackPrefix, workplanOptionViewByMonth}, callbackSeparator)},
-			{Text: "View All", CallbackData: strings.Join([]string{workplanCallbackPrefix, workplanOptionViewCalendar}, callbackSeparator)},
+			{Text: l.T(msgButtonViewThisMonth), CallbackData: strings.Join([]string{workplanCallbackPrefix, workplanOptionViewCurrentMonth}, callbackSeparator)},
+			{Text: l.T(msgButtonViewByMonth), CallbackData: strings.Join([]string{workplanCallbackPrefix, workplanOptionViewByMonth}, callbackSeparator)},
+			{Text: l.T(msgButtonViewAll), CallbackData: strings.Join([]string{workplanCallbackPrefix, workplanOptionViewCalendar}, callbackSeparator)},
+		},
+		{
+			{Text: l.T(msgButtonAddActivity), CallbackData: strings.Join([]string{workplanCallbackPrefix, workplanOptionAddEvent}, callbackSeparator)},
+			{Text: l.T(msgButtonUpdateActivity), CallbackData: strings.Join([]string{workplanCallbackPrefix, workplanOptionUpdateEvent}, callbackSeparator)},
+			{Text: l.T(msgButtonDeleteActivity), CallbackData: strings.Join([]string{workplanCallbackPrefix, workplanOptionDeleteEvent}, callbackSeparator)},
 		},
 		{
-			{Text: "Add Event", CallbackData: strings.Join([]string{workplanCallbackPrefix, workplanOptionAddEvent}, callbackSeparator)},
-			{Text: "Update Event", CallbackData: strings.Join([]string{workplanCallbackPrefix, workplanOptionUpdateEvent}, callbackSeparator)},
-			{Text: "Delete Event", CallbackData: strings.Join([]string{workplanCallbackPrefix, workplanOptionDeleteEvent}, callbackSeparator)},
+			{Text: l.T(msgButtonCreatePoll), CallbackData: strings.Join([]string{workplanCallbackPrefix, workplanOptionCreatePoll}, callbackSeparator)},
+		},
+		{
+			{Text: l.T(msgButtonByOrg), CallbackData: strings.Join([]string{workplanCallbackPrefix, workplanOptionByOrg}, callbackSeparator)},
+			{Text: l.T(msgButtonByLead), CallbackData: strings.Join([]string{workplanCallbackPrefix, workplanOptionByLead}, callbackSeparator)},
+			{Text: l.T(msgButtonMine), CallbackData: strings.Join([]string{workplanCallbackPrefix, workplanOptionMine}, callbackSeparator)},
 		},
 	}
 
 	kb := models.InlineKeyboardMarkup{
 		InlineKeyboard: inlineKeyboard,
 	}
-	messageText := "Please choose an option:"
+	messageText := l.T(msgWorkplanChooseOption)
 	return kb, messageText
 }
 
-func (h *ActivityHandler) handleWorkplanCallback(ctx context.Context, b *bot.Bot, update *models.Update) {
+func (h *ActivityHandler) handleWorkplanCallback(ctx context.Context, b Messenger, update *models.Update) {
 	messageID := update.CallbackQuery.Message.Message.ID
-	log.Println("workpan callback for message", messageID, "from", getUserFullName(&update.CallbackQuery.From), "Data", update.CallbackQuery.Data)
+	slog.InfoContext(ctx, "workplan callback", "message_id", messageID, "data", update.CallbackQuery.Data)
 
 	options := strings.Split(update.CallbackQuery.Data, callbackSeparator)
 	if len(options) < 2 {
-		log.Println("invalid option callback", update.CallbackQuery.Data)
+		slog.WarnContext(ctx, "invalid option callback", "data", update.CallbackQuery.Data)
 		return
 	}
 
@@ -101,22 +344,23 @@ func (h *ActivityHandler) handleWorkplanCallback(ctx context.Context, b *bot.Bot
 	chatID := update.CallbackQuery.Message.Message.Chat.ID
 	msgThreadID := update.CallbackQuery.Message.Message.MessageThreadID
 	userStateKey := getUserStateKey(chatID, msgThreadID, &update.CallbackQuery.From)
+	l := h.chatSettings.GetLocalizer(ctx, chatID)
 
 	switch options[1] {
 	case workplanOptionViewCurrentMonth:
 		// Logic to view current month's activities
-		startTime := getCurrentMonthInUTC()
+		startTime := getCurrentMonth(l.Location)
 		endTime := startTime.AddDate(0, 1, 0).Add(-time.Nanosecond)
-		h.sendActivitiesForPeriod(ctx, b, chatID, msgThreadID, startTime, endTime)
+		h.sendActivitiesForPeriod(ctx, b, chatID, msgThreadID, startTime, endTime, l)
 
 	case workplanOptionViewByMonth:
 		// Logic to view activities by month
-		startTime := getCurrentMonthInUTC()
+		startTime := getCurrentMonth(l.Location)
 		var inlineButtons [][]models.InlineKeyboardButton
 		for i := -2; i < 16; i++ {
 			month := startTime.AddDate(0, i, 0)
 			button := models.InlineKeyboardButton{
-				Text:         month.Format(monthFormat),
+				Text:         l.FormatMonth(month),
 				CallbackData: strings.Join([]string{workplanViewByMonthCallbackPrefix, month.Format(monthFormat)}, callbackSeparator),
 			}
 			if len(inlineButtons) == 0 || len(inlineButtons[len(inlineButtons)-1]) == 4 {
@@ -127,12 +371,12 @@ func (h *ActivityHandler) handleWorkplanCallback(ctx context.Context, b *bot.Bot
 		}
 		// add special buttons
 		button := models.InlineKeyboardButton{
-			Text:         "All",
+			Text:         l.T(msgButtonAll),
 			CallbackData: strings.Join([]string{workplanViewByMonthCallbackPrefix, workplanViewByMonthCallbackOptionAll}, callbackSeparator),
 		}
 		inlineButtons[len(inlineButtons)-1] = append(inlineButtons[len(inlineButtons)-1], button)
 		button = models.InlineKeyboardButton{
-			Text:         "<< back",
+			Text:         l.T(msgButtonBack),
 			CallbackData: strings.Join([]string{workplanViewByMonthCallbackPrefix, callbackNavBack}, callbackSeparator),
 		}
 		inlineButtons[len(inlineButtons)-1] = append(inlineButtons[len(inlineButtons)-1], button)
@@ -141,7 +385,7 @@ func (h *ActivityHandler) handleWorkplanCallback(ctx context.Context, b *bot.Bot
 			InlineKeyboard: inlineButtons,
 		}
 
-		messageText := "Select a month to view activities:"
+		messageText := l.T(msgWorkplanSelectMonth)
 		_, err := b.EditMessageText(ctx, &bot.EditMessageTextParams{
 			ChatID:      chatID,
 			MessageID:   messageID,
@@ -149,62 +393,80 @@ func (h *ActivityHandler) handleWorkplanCallback(ctx context.Context, b *bot.Bot
 			ReplyMarkup: kb,
 		})
 		if err != nil {
-			log.Println("error editing message", err)
+			slog.ErrorContext(ctx, "error editing message", "err", err)
 		}
 
 	case workplanOptionViewCalendar:
-		h.sendAllActivities(ctx, b, chatID, msgThreadID)
+		h.sendAllActivities(ctx, b, chatID, msgThreadID, l)
 
 	case workplanOptionAddEvent:
 		// Logic to add a new event
 		// Initialize user state
-		userStates[userStateKey] = &UserState{Step: 1, StateType: ADD_ACTIVITY}
-
-		b.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID:          chatID,
-			MessageThreadID: msgThreadID,
-			Text:            activityStepPrompts[1],
+		h.addWizard.Start(ctx, b, userStateKey, &UserState{
+			ChatID:      chatID,
+			MsgThreadID: msgThreadID,
+			Activity: Activity{
+				CreatedBy:   getUserFullName(&update.CallbackQuery.From),
+				CreatedByID: update.CallbackQuery.From.ID,
+			},
 		})
 
 	case workplanOptionUpdateEvent:
 		// Logic to update an existing event
-		userStates[userStateKey] = &UserState{Step: 1, StateType: UPDATE_ACTIVITY}
-		b.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID:          chatID,
-			MessageThreadID: msgThreadID,
-			Text:            "Please provide the ID of the activity you want to update.",
-		})
+		h.updateWizard.Start(ctx, b, userStateKey, &UserState{ChatID: chatID, MsgThreadID: msgThreadID})
 
 	case workplanOptionDeleteEvent:
 		// Logic to delete an event
-		userStates[userStateKey] = &UserState{Step: 1, StateType: DELETE_ACTIVITY}
-		b.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID:          chatID,
-			MessageThreadID: msgThreadID,
-			Text:            "Please provide the ID of the activity you want to delete.",
+		h.deleteWizard.Start(ctx, b, userStateKey, &UserState{ChatID: chatID, MsgThreadID: msgThreadID, UserID: update.CallbackQuery.From.ID})
+
+	case workplanOptionByOrg:
+		h.sendOrgFilterMenu(ctx, b, chatID, messageID, l)
+
+	case workplanOptionByLead:
+		h.leadWizard.Start(ctx, b, userStateKey, &UserState{ChatID: chatID, MsgThreadID: msgThreadID})
+
+	case workplanOptionMine:
+		// leads entered as @username before they were known to the bot have no user ID
+		from := &update.CallbackQuery.From
+		filter := ActivityFilter{LeadID: from.ID}
+		if from.Username != "" {
+			filter.LeadName = "@" + from.Username
+		}
+		h.sendFilteredActivities(ctx, b, chatID, msgThreadID, l.T(msgActivityFilterMine), filter, l)
+
+	case workplanOptionCreatePoll:
+		// the creator of the poll is kept in the event until the activity is selected
+		h.pollWizard.Start(ctx, b, userStateKey, &UserState{
+			ChatID:      chatID,
+			MsgThreadID: msgThreadID,
+			Event: Event{
+				CreatedBy:   getUserFullName(&update.CallbackQuery.From),
+				CreatedByID: update.CallbackQuery.From.ID,
+			},
 		})
 	}
 }
 
-func (h *ActivityHandler) handleViewByMonth(ctx context.Context, b *bot.Bot, update *models.Update) {
+func (h *ActivityHandler) handleViewByMonth(ctx context.Context, b Messenger, update *models.Update) {
 	messageID := update.CallbackQuery.Message.Message.ID
-	log.Println("workpan callback for message", messageID, "from", getUserFullName(&update.CallbackQuery.From), "Data", update.CallbackQuery.Data)
+	slog.InfoContext(ctx, "workplan callback", "message_id", messageID, "data", update.CallbackQuery.Data)
 
 	options := strings.Split(update.CallbackQuery.Data, callbackSeparator)
 	if len(options) < 2 {
-		log.Println("invalid option callback", update.CallbackQuery.Data)
+		slog.WarnContext(ctx, "invalid option callback", "data", update.CallbackQuery.Data)
 		return
 	}
 
 	chatID := update.CallbackQuery.Message.Message.Chat.ID
 	msgThreadID := update.CallbackQuery.Message.Message.MessageThreadID
+	l := h.chatSettings.GetLocalizer(ctx, chatID)
 
 	switch options[1] {
 	case workplanViewByMonthCallbackOptionAll:
-		h.sendAllActivities(ctx, b, chatID, msgThreadID)
+		h.sendAllActivities(ctx, b, chatID, msgThreadID, l)
 		return
 	case callbackNavBack:
-		kb, msg := h.getWorkplanMenu()
+		kb, msg := h.getWorkplanMenu(l)
 		_, err := b.EditMessageText(ctx, &bot.EditMessageTextParams{
 			ChatID:      chatID,
 			MessageID:   messageID,
@@ -212,17 +474,17 @@ func (h *ActivityHandler) handleViewByMonth(ctx context.Context, b *bot.Bot, upd
 			ReplyMarkup: kb,
 		})
 		if err != nil {
-			log.Println("error editing message", err)
+			slog.ErrorContext(ctx, "error editing message", "err", err)
 		}
 		return
 	}
 
-	month, err := time.Parse(monthFormat, options[1])
+	month, err := time.ParseInLocation(monthFormat, options[1], l.Location)
 	if err != nil {
-		log.Println("error parsing month", options[1], err)
+		slog.WarnContext(ctx, "error parsing month", "month", options[1], "err", err)
 		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 			CallbackQueryID: update.CallbackQuery.ID,
-			Text:            "cannot get the month",
+			Text:            l.T(msgWorkplanInvalidMonth),
 			ShowAlert:       true,
 		})
 		return
@@ -234,32 +496,21 @@ func (h *ActivityHandler) handleViewByMonth(ctx context.Context, b *bot.Bot, upd
 	})
 
 	endTime := month.AddDate(0, 1, 0).Add(-time.Nanosecond)
-	h.sendActivitiesForPeriod(ctx, b, chatID, msgThreadID, month, endTime)
+	h.sendActivitiesForPeriod(ctx, b, chatID, msgThreadID, month, endTime, l)
 }
 
-func (h *ActivityHandler) handleAddActivitySteps(ctx context.Context, b *bot.Bot, update *models.Update, userStateKey string, userState *UserState) {
-	chatID := update.Message.Chat.ID
-	msgThreadID := update.Message.MessageThreadID
-	ok := h.handleAddOrUpdateActivitySteps(ctx, b, update, userState, 0)
-	if !ok {
-		return
-	}
-
-	userState.Step++
-	if userState.Step < 6 {
-		h.sendAddOrUpdateActivityPrompt(ctx, b, chatID, msgThreadID, userState.Step)
-		return
-	}
-
-	userState.Activity.CreatedBy = getUserFullName(update.Message.From)
-	userState.Activity.CreatedByID = update.Message.From.ID
+// completeAddActivity saves the activity once all fields are collected
+func (h *ActivityHandler) completeAddActivity(ctx context.Context, b Messenger, userStateKey string, userState *UserState) {
+	chatID := userState.ChatID
+	msgThreadID := userState.MsgThreadID
 
 	if _, err := h.activityDAO.Save(&userState.Activity); err != nil {
-		log.Println("failed to save activity", userState.Activity, err)
+		slog.ErrorContext(ctx, "failed to save activity", "activity", userState.Activity.Name, "err", err)
 		b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:          chatID,
 			MessageThreadID: msgThreadID,
-			Text:            "Failed to save activity! Input anything to save again!",
+			Text:            userState.localizer().T(msgActivitySaveFailed),
+			ReplyMarkup:     getCancelKeyboard(userState.localizer()),
 		})
 		return
 	}
@@ -267,218 +518,569 @@ func (h *ActivityHandler) handleAddActivitySteps(ctx context.Context, b *bot.Bot
 	b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:          chatID,
 		MessageThreadID: msgThreadID,
-		Text:            "Activity details collected successfully!\n" + userState.Activity.string(),
+		Text:            userState.localizer().T(msgActivitySaved, userState.Activity.string(userState.localizer())),
 		ParseMode:       "HTML",
 	})
+	h.notifier.notifyChange(ctx, b, nil, userState.Activity, userState.Activity.CreatedByID)
 	// Clean up user state
-	delete(userStates, userStateKey)
+	h.deleteUserState(ctx, userStateKey)
 }
 
-func (h *ActivityHandler) handleAddOrUpdateActivitySteps(ctx context.Context, b *bot.Bot, update *models.Update, userState *UserState, stepOffset int) bool {
-	chatID := update.Message.Chat.ID
-	msgThreadID := update.Message.MessageThreadID
+func parseActivityName(ctx context.Context, input WizardInput, state *UserState) error {
+	// Collect activity name
+	state.Activity.Name = input.Text
+	return nil
+}
 
-	switch userState.Step {
-	case stepOffset + 1:
-		// Collect activity name
-		userState.Activity.Name = update.Message.Text
-	case stepOffset + 2:
-		// Collect start time
-		startTime, err := time.Parse(timeFormat, update.Message.Text)
-		if err != nil {
-			b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:          chatID,
-				MessageThreadID: msgThreadID,
-				Text:            "Invalid input. Please enter a valid start time in the format YYYY-MM-DD HH:MM. For example, " + timeFormat,
-			})
-			return false
-		}
-		userState.Activity.StartedAt = startTime
-	case stepOffset + 3:
-		// Collect organizing committee
-		orgInput := Org(strings.ToUpper(update.Message.Text))
-
-		// Check if the provided organization is valid
-		isValidOrg := false
-		for _, org := range AllOrgs {
-			if orgInput == org {
-				isValidOrg = true
-				break
+func parseActivityStartedAt(ctx context.Context, input WizardInput, state *UserState) error {
+	// Collect start time
+	startTime, err := parseUserInputTime(input.Text, state.location())
+	if err != nil {
+		return errors.New(state.localizer().T(msgInvalidStartTime, startTimeExamples))
+	}
+	state.Activity.StartedAt = startTime
+	return nil
+}
+
+func echoActivityStartedAt(state *UserState) string {
+	l := state.localizer()
+	return l.T(msgStartTimeSet, l.FormatTime(state.Activity.StartedAt))
+}
+
+func parseActivityEndsAt(ctx context.Context, input WizardInput, state *UserState) error {
+	if strings.TrimSpace(input.Text) == activityClearInput {
+		state.Activity.EndsAt = nil
+		return nil
+	}
+	l := state.localizer()
+	endsAt, err := parseUserInputTime(input.Text, state.location())
+	if err != nil || !endsAt.After(state.Activity.StartedAt) {
+		return errors.New(l.T(msgActivityInvalidEndsAt, l.FormatTime(state.Activity.StartedAt)))
+	}
+	state.Activity.EndsAt = &endsAt
+	return nil
+}
+
+func echoActivityEndsAt(state *UserState) string {
+	l := state.localizer()
+	if state.Activity.EndsAt == nil {
+		return l.T(msgEndTimeCleared)
+	}
+	return l.T(msgEndTimeSet, l.FormatTime(*state.Activity.EndsAt))
+}
+
+func parseActivityLocation(ctx context.Context, input WizardInput, state *UserState) error {
+	state.Activity.Location = parseOptionalText(input.Text)
+	return nil
+}
+
+func parseActivityDescription(ctx context.Context, input WizardInput, state *UserState) error {
+	state.Activity.Description = parseOptionalText(input.Text)
+	return nil
+}
+
+// parseOptionalText returns the trimmed input, or nothing if the field is cleared
+func parseOptionalText(input string) string {
+	text := strings.TrimSpace(input)
+	if text == activityClearInput {
+		return ""
+	}
+	return text
+}
+
+// getActivityStatusButtonRows offers the statuses the activity can change to
+func getActivityStatusButtonRows(state *UserState) [][]models.InlineKeyboardButton {
+	l := state.localizer()
+	var row []models.InlineKeyboardButton
+	for _, status := range activityStatusTransitions[state.Activity.status()] {
+		row = append(row, getWizardInputButton(status.label(l), string(status)))
+	}
+	if len(row) == 0 {
+		return nil
+	}
+	return [][]models.InlineKeyboardButton{row}
+}
+
+func parseActivityStatusInput(ctx context.Context, input WizardInput, state *UserState) error {
+	l := state.localizer()
+	current := state.Activity.status()
+	status, ok := parseActivityStatus(input.Text)
+	if !ok {
+		// the status may be typed in the language of the chat
+		for s := range activityStatusTransitions {
+			if strings.EqualFold(s.label(l), strings.TrimSpace(input.Text)) {
+				status, ok = s, true
 			}
 		}
+	}
+	if !ok || !current.canChangeTo(status) {
+		return errors.New(l.T(msgActivityInvalidStatus, current.label(l), strings.TrimSpace(input.Text)))
+	}
+	state.Activity.Status = status
+	return nil
+}
 
-		if !isValidOrg {
-			b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:          chatID,
-				MessageThreadID: msgThreadID,
-				Text:            fmt.Sprintf("Invalid org. Please enter one of %v", AllOrgs),
-			})
-			return false
+// getActivityConflicts returns the clashes of the activity with the activities taking place at the same time
+func (h *ActivityHandler) getActivityConflicts(ctx context.Context, activity Activity) []ActivityClash {
+	overlapping, err := h.activityDAO.GetOverlapping(activity.StartedAt, activity.endTime())
+	if err != nil {
+		// the check is only a warning, do not block the flow
+		slog.ErrorContext(ctx, "error retrieving overlapping activities", "started_at", activity.StartedAt, "err", err)
+		return nil
+	}
+	return findActivityConflicts(activity, overlapping)
+}
+
+// confirmActivitySlot warns about the activities taking place at the start time
+func (h *ActivityHandler) confirmActivitySlot(ctx context.Context, state *UserState) string {
+	clashes := h.getActivityConflicts(ctx, state.Activity)
+	if len(clashes) == 0 {
+		return ""
+	}
+	l := state.localizer()
+	return l.T(msgActivitySlotConflicts, getActivityClashesMessage(clashes, false, l))
+}
+
+// confirmActivityLead warns about the activities the lead or co-leads run at the same time
+func (h *ActivityHandler) confirmActivityLead(ctx context.Context, state *UserState) string {
+	clashes := leadClashes(h.getActivityConflicts(ctx, state.Activity))
+	if len(clashes) == 0 {
+		return ""
+	}
+	l := state.localizer()
+	return l.T(msgActivityLeadConflicts, getActivityClashesMessage(clashes, false, l))
+}
+
+// getOrgButtonRows offers the active organizations as buttons
+func (h *ActivityHandler) getOrgButtonRows(state *UserState) [][]models.InlineKeyboardButton {
+	orgs, err := h.orgs.ListOrgs()
+	if err != nil {
+		slog.Error("error listing orgs", "err", err)
+		return nil
+	}
+	var rows [][]models.InlineKeyboardButton
+	for i, org := range activeOrgs(orgs) {
+		if i%orgButtonsPerRow == 0 {
+			rows = append(rows, nil)
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], getWizardInputButton(org.Label(), string(org.Name)))
+	}
+	return rows
+}
+
+func (h *ActivityHandler) parseActivityOrg(ctx context.Context, input WizardInput, state *UserState) error {
+	// Collect organizing committee
+	orgs, err := h.orgs.ListOrgs()
+	if err != nil {
+		slog.ErrorContext(ctx, "error listing orgs", "err", err)
+		return errors.New(state.localizer().T(msgOrgGetFailed))
+	}
+	orgs = activeOrgs(orgs)
+	org, ok := findOrg(orgs, input.Text)
+	if !ok {
+		return errors.New(state.localizer().T(msgActivityInvalidOrg, orgNames(orgs)))
+	}
+	state.Activity.Org = org.Name
+	return nil
+}
+
+// parseActivityLead collects the lead, who can be mentioned to be notified
+func (h *ActivityHandler) parseActivityLead(ctx context.Context, input WizardInput, state *UserState) error {
+	prev := state.Activity
+	state.Activity.Lead, state.Activity.LeadID = "", 0
+	for _, user := range parseMentionedUsers(input.Text, input.Entities, "", h.lookupUsername(ctx)) {
+		state.Activity.Lead, state.Activity.LeadID = user.Name, user.ID
+		if user.ID == 0 {
+			state.Activity.LeadID = prev.knownLeadID(user.Name)
+		}
+	}
+	return nil
+}
+
+// parseActivityCoLeads collects the co-leads separated by ";", who can be mentioned to be notified
+func (h *ActivityHandler) parseActivityCoLeads(ctx context.Context, input WizardInput, state *UserState) error {
+	prev := state.Activity
+	state.Activity.CoLeads, state.Activity.CoLeadIDs = nil, nil
+	for _, user := range parseMentionedUsers(input.Text, input.Entities, ";", h.lookupUsername(ctx)) {
+		if user.ID == 0 {
+			user.ID = prev.knownLeadID(user.Name)
 		}
-		userState.Activity.Org = orgInput
-	case stepOffset + 4:
-		// Collect lead
-		userState.Activity.Lead = strings.TrimSpace(update.Message.Text)
-
-	case stepOffset + 5:
-		// Collect co-lead
-		coleads := strings.Split(update.Message.Text, ";")
-		// Remove empty options
-		var validColeads []string
-		for _, colead := range coleads {
-			if opt := strings.TrimSpace(colead); opt != "" {
-				validColeads = append(validColeads, opt)
+		state.Activity.CoLeads = append(state.Activity.CoLeads, user.Name)
+		state.Activity.CoLeadIDs = append(state.Activity.CoLeadIDs, user.ID)
+	}
+	return nil
+}
+
+// lookupUsername returns the user ID of an @username the bot has seen, 0 otherwise
+func (h *ActivityHandler) lookupUsername(ctx context.Context) func(username string) int64 {
+	return func(username string) int64 {
+		user, err := h.knownUsers.GetByUsername(username)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				slog.ErrorContext(ctx, "error getting known user", "username", username, "err", err)
 			}
+			return 0
 		}
-		userState.Activity.CoLeads = validColeads
+		return user.ID
 	}
+}
 
-	return true
+// echoUnknownLead warns if the lead is an @username the bot cannot notify yet
+func echoUnknownLead(state *UserState) string {
+	a := state.Activity
+	return echoUnknownUsernames(state.localizer(), []string{a.Lead}, []int64{a.LeadID})
 }
 
-func (h *ActivityHandler) handleDeleteActivitySteps(ctx context.Context, b *bot.Bot, update *models.Update, userStateKey string, userState *UserState) {
-	chatID := update.Message.Chat.ID
-	msgThreadID := update.Message.MessageThreadID
-	activityIDStr := strings.TrimSpace(update.Message.Text)
+// echoUnknownCoLeads warns about the co-leads who are @usernames the bot cannot notify yet
+func echoUnknownCoLeads(state *UserState) string {
+	a := state.Activity
+	return echoUnknownUsernames(state.localizer(), a.CoLeads, a.CoLeadIDs)
+}
+
+func echoUnknownUsernames(l *Localizer, names []string, ids []int64) string {
+	var unknown []string
+	for i, name := range names {
+		if isUsername(name) && (i >= len(ids) || ids[i] == 0) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return ""
+	}
+	return l.T(msgLeadUnknownUsers, strings.Join(unknown, ", "))
+}
+
+// getActivityByInput loads the activity whose ID was sent by the user
+func (h *ActivityHandler) getActivityByInput(ctx context.Context, input WizardInput, l *Localizer) (*Activity, error) {
+	activityIDStr := strings.TrimSpace(input.Text)
 	activityID, err := strconv.ParseInt(activityIDStr, 10, 64)
 	if err != nil {
-		log.Println("invalid activity ID", activityIDStr, err)
+		slog.WarnContext(ctx, "invalid activity ID", "activity_id", activityIDStr, "err", err)
+		return nil, errors.New(l.T(msgActivityInvalidID))
+	}
+
+	activity, err := h.activityDAO.GetByID(activityID)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(l.T(msgActivityNotFound))
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get activity", "activity_id", activityID, "err", err)
+		return nil, errors.New(l.T(msgActivityGetFailed))
+	}
+	return activity, nil
+}
+
+func (h *ActivityHandler) parseActivityToDelete(ctx context.Context, input WizardInput, state *UserState) error {
+	activity, err := h.getActivityByInput(ctx, input, state.localizer())
+	if err != nil {
+		return err
+	}
+	if !h.canDeleteActivity(ctx, input.User, activity) {
+		return errors.New(state.localizer().T(msgActivityDeleteNotAuthorized))
+	}
+	state.Activity = *activity
+	return nil
+}
+
+// canDeleteActivity reports whether the user created or leads the activity, or is an admin of
+// its organization. The bot admins, who appoint the organization admins, count as admins of
+// every organization. Users are matched by ID only, as names are neither unique nor verified,
+// so activities created before user IDs were stored can only be deleted by their leads and admins
+func (h *ActivityHandler) canDeleteActivity(ctx context.Context, user *models.User, activity *Activity) bool {
+	if user == nil || user.ID == 0 {
+		return false
+	}
+	if user.ID == activity.CreatedByID || user.ID == activity.LeadID || slices.Contains(activity.CoLeadIDs, user.ID) ||
+		AppConfig.isAdmin(user) {
+		return true
+	}
+	admins, err := h.orgs.GetOrgAdmins(activity.Org)
+	if err != nil {
+		slog.ErrorContext(ctx, "error getting org admins", "org", activity.Org, "err", err)
+		return false
+	}
+	return slices.Contains(admins, user.ID)
+}
+
+// confirmDeleteActivity always shows the activity to be deleted for the user to confirm
+func confirmDeleteActivity(ctx context.Context, state *UserState) string {
+	l := state.localizer()
+	return l.T(msgActivityDeleteConfirm, state.Activity.string(l))
+}
+
+func (h *ActivityHandler) completeDeleteActivity(ctx context.Context, b Messenger, userStateKey string, userState *UserState) {
+	chatID := userState.ChatID
+	msgThreadID := userState.MsgThreadID
+	activityID := userState.Activity.ID
+	l := userState.localizer()
+
+	affectedRows, err := h.activityDAO.Delete(activityID)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to delete activity", "activity_id", activityID, "err", err)
 		b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:          chatID,
 			MessageThreadID: msgThreadID,
-			Text:            "Invalid activity ID! Please enter a valid number.",
+			Text:            l.T(msgActivityDeleteFailed),
+			ReplyMarkup:     getCancelKeyboard(l),
+		})
+		return
+	}
+	if affectedRows == 0 {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: msgThreadID,
+			Text:            l.T(msgActivityNotFound),
+			ReplyMarkup:     getCancelKeyboard(l),
 		})
 		return
 	}
 
-	affectedRows, err := h.activityDAO.Delete(activityID)
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          chatID,
+		MessageThreadID: msgThreadID,
+		Text:            l.T(msgActivityDeleted, activityTrashRetentionDays),
+	})
+	h.notifier.notifyDeleted(ctx, b, userState.Activity, userState.UserID)
+	// Clean up user state
+	h.deleteUserState(ctx, userStateKey)
+}
+
+// trashSince is the earliest deletion time of the activities which can still be restored
+func trashSince(now time.Time) time.Time {
+	return now.AddDate(0, 0, -activityTrashRetentionDays)
+}
+
+// sendTrash lists the activities deleted within the retention period with buttons to restore them
+func (h *ActivityHandler) sendTrash(ctx context.Context, b Messenger, chatID int64, msgThreadID int, l *Localizer) {
+	activities, err := h.activityDAO.GetDeleted(trashSince(time.Now()))
 	if err != nil {
-		log.Println("failed to delete activity", activityID, err)
+		slog.ErrorContext(ctx, "error retrieving deleted activities", "err", err)
 		b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:          chatID,
 			MessageThreadID: msgThreadID,
-			Text:            "Failed to delete activity! Please try again.",
+			Text:            l.T(msgActivityGetFailed),
 		})
 		return
 	}
-	if affectedRows == 0 {
+	if len(activities) == 0 {
 		b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:          chatID,
 			MessageThreadID: msgThreadID,
-			Text:            "No activity found with the given ID! Please try again.",
+			Text:            l.T(msgActivityTrashEmpty, activityTrashRetentionDays),
 		})
 		return
 	}
 
+	shown := activities[:min(len(activities), activityTrashListLimit)]
+	lines := make([]string, 0, len(shown))
+	var rows [][]models.InlineKeyboardButton
+	for _, activity := range shown {
+		lines = append(lines, activity.string(l)+"\n"+l.T(msgActivityDeletedAt, l.FormatTime(*activity.DeletedAt)))
+		rows = append(rows, []models.InlineKeyboardButton{{
+			Text:         l.T(msgButtonRestore, activity.ID, activity.Name),
+			CallbackData: strings.Join([]string{workplanRestoreCallbackPrefix, strconv.FormatInt(activity.ID, 10)}, callbackSeparator),
+		}})
+	}
+	text := l.T(msgActivityTrashTitle, activityTrashRetentionDays, strings.Join(lines, "\n\n"))
+	if len(activities) > len(shown) {
+		text += l.T(msgActivityTrashLimited, len(shown))
+	}
 	b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:          chatID,
 		MessageThreadID: msgThreadID,
-		Text:            "Activity deleted successfully!",
+		Text:            text,
+		ParseMode:       "HTML",
+		ReplyMarkup:     &models.InlineKeyboardMarkup{InlineKeyboard: rows},
 	})
-	// Clean up user state
-	delete(userStates, userStateKey)
 }
 
-func (h *ActivityHandler) handleUpdateActivitySteps(ctx context.Context, b *bot.Bot, update *models.Update, userStateKey string, userState *UserState) {
-	chatID := update.Message.Chat.ID
-	msgThreadID := update.Message.MessageThreadID
-	// Create inline keyboard for update options
-	keyboard := &models.InlineKeyboardMarkup{
-		InlineKeyboard: [][]models.InlineKeyboardButton{
-			{
-				{Text: "Name", CallbackData: strings.Join([]string{workplanUpdateEventCallbackPrefix, workplanUpdateEventCallbackOptionName}, callbackSeparator)},
-				{Text: "Start Time", CallbackData: strings.Join([]string{workplanUpdateEventCallbackPrefix, workplanUpdateEventCallbackOptionStartedAt}, callbackSeparator)},
-				{Text: "Committee", CallbackData: strings.Join([]string{workplanUpdateEventCallbackPrefix, workplanUpdateEventCallbackOptionCommittee}, callbackSeparator)},
-			},
-			{
-				{Text: "Lead", CallbackData: strings.Join([]string{workplanUpdateEventCallbackPrefix, workplanUpdateEventCallbackOptionLead}, callbackSeparator)},
-				{Text: "Co-lead", CallbackData: strings.Join([]string{workplanUpdateEventCallbackPrefix, workplanUpdateEventCallbackOptionCoLead}, callbackSeparator)},
-			},
-		},
+// handleRestoreActivity takes the activity selected in the trash out of it,
+// if the user could have deleted it
+func (h *ActivityHandler) handleRestoreActivity(ctx context.Context, b Messenger, update *models.Update) {
+	chatID := update.CallbackQuery.Message.Message.Chat.ID
+	msgThreadID := update.CallbackQuery.Message.Message.MessageThreadID
+	l := h.chatSettings.GetLocalizer(ctx, chatID)
+	alert := func(key MessageKey) {
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			Text:            l.T(key),
+			ShowAlert:       true,
+		})
 	}
 
-	switch userState.Step {
-	case 1:
-		activityIDStr := strings.TrimSpace(update.Message.Text)
-		activityID, err := strconv.ParseInt(activityIDStr, 10, 64)
-		if err != nil {
-			log.Println("invalid activity ID", activityIDStr, err)
-			b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:          chatID,
-				MessageThreadID: msgThreadID,
-				Text:            "Invalid activity ID! Please enter a valid number.",
-			})
-			return
-		}
+	idStr := strings.TrimPrefix(update.CallbackQuery.Data, workplanRestoreCallbackPrefix+callbackSeparator)
+	activityID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		slog.WarnContext(ctx, "invalid callback data", "data", update.CallbackQuery.Data)
+		alert(msgInvalidCallbackData)
+		return
+	}
+	since := trashSince(time.Now())
+	activity, err := h.activityDAO.GetDeletedByID(activityID, since)
+	if errors.Is(err, sql.ErrNoRows) {
+		alert(msgActivityNotInTrash)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "error retrieving deleted activity", "activity_id", activityID, "err", err)
+		alert(msgActivityRestoreFailed)
+		return
+	}
+	if !h.canDeleteActivity(ctx, &update.CallbackQuery.From, activity) {
+		alert(msgActivityRestoreNotAuthorized)
+		return
+	}
+	affectedRows, err := h.activityDAO.Restore(activityID, since)
+	if err != nil || affectedRows == 0 {
+		slog.ErrorContext(ctx, "failed to restore activity", "activity_id", activityID, "affected_rows", affectedRows, "err", err)
+		alert(msgActivityRestoreFailed)
+		return
+	}
+	slog.InfoContext(ctx, "activity restored", "activity_id", activityID, "user_id", update.CallbackQuery.From.ID)
+	b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: update.CallbackQuery.ID})
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          chatID,
+		MessageThreadID: msgThreadID,
+		Text:            l.T(msgActivityRestored, activity.string(l)),
+		ParseMode:       "HTML",
+	})
+}
 
-		activity, err := h.activityDAO.GetByID(activityID)
-		if err != nil {
-			log.Println("failed to get activity", activityID, err)
-			b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:          chatID,
-				MessageThreadID: msgThreadID,
-				Text:            "Failed to retrieve activity! Please try again.",
-			})
+// runTrashPurge removes the activities which have been in the trash longer than the retention period
+func (h *ActivityHandler) runTrashPurge(ctx context.Context) {
+	ticker := time.NewTicker(activityTrashPurgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
 			return
+		case now := <-ticker.C:
+			purged, err := h.activityDAO.Purge(trashSince(now))
+			if err != nil {
+				slog.ErrorContext(ctx, "error purging deleted activities", "err", err)
+				continue
+			}
+			if purged > 0 {
+				slog.InfoContext(ctx, "deleted activities purged", "count", purged)
+			}
 		}
+	}
+}
 
-		if !isSameUser(update.Message.From, activity.CreatedBy, activity.CreatedByID) {
-			b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:          chatID,
-				MessageThreadID: msgThreadID,
-				Text:            "You are not authorized to update this activity!",
-			})
-			return
-		}
+func (h *ActivityHandler) parseActivityToPoll(ctx context.Context, input WizardInput, state *UserState) error {
+	activity, err := h.getActivityByInput(ctx, input, state.localizer())
+	if err != nil {
+		return err
+	}
+	event, err := h.events.GetEventByActivityID(activity.ID)
+	if err == nil {
+		return errors.New(state.localizer().T(msgActivityPollExists, event.ID))
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		slog.ErrorContext(ctx, "failed to get activity poll", "activity_id", activity.ID, "err", err)
+		return errors.New(state.localizer().T(msgActivityGetFailed))
+	}
+	state.Activity = *activity
+	return nil
+}
 
+// completeCreateActivityPoll creates a poll pre-filled from the activity and sends it to the chat
+func (h *ActivityHandler) completeCreateActivityPoll(ctx context.Context, b Messenger, userStateKey string, userState *UserState) {
+	chatID := userState.ChatID
+	msgThreadID := userState.MsgThreadID
+	l := userState.localizer()
+	activity := userState.Activity
+
+	startedAt := activity.StartedAt
+	event := Event{
+		Description: activity.Name,
+		Options:     []string{l.T(msgDefaultPollOption)},
+		StartedAt:   &startedAt,
+		ActivityID:  activity.ID,
+	}
+	event.updateDetails(chatID, 0, userState.Event.CreatedBy, userState.Event.CreatedByID)
+	eventID, err := h.events.SaveEvent(&event)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to save activity poll", "activity_id", activity.ID, "err", err)
 		b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:          chatID,
 			MessageThreadID: msgThreadID,
-			Text:            fmt.Sprintf("Select what you want to update:\n\n%s", activity.string()),
-			ParseMode:       "HTML",
-			ReplyMarkup:     keyboard,
+			Text:            l.T(msgActivityPollFailed),
+			ReplyMarkup:     getCancelKeyboard(l),
 		})
+		return
+	}
+	event.ID = eventID
+	slog.InfoContext(ctx, "activity poll created", "activity_id", activity.ID, "event_id", eventID)
 
-		// Update user state to wait for callback query
-		userState.Step = 2
-		userState.Activity = *activity
+	event.MessageID = sendEventPoll(ctx, b, chatID, msgThreadID, event, nil, l)
+	if err := h.events.UpdateEvent(&event); err != nil {
+		slog.ErrorContext(ctx, "error updating event", "event_id", eventID, "err", err)
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          chatID,
+		MessageThreadID: msgThreadID,
+		Text:            l.T(msgActivityPollCreated, eventID, eventID),
+	})
+	h.deleteUserState(ctx, userStateKey)
+}
 
-	case 2:
-		// ignore any message at step 2 as user expeted to send request via callback (inline keyboard)
-		return
-	default:
-		ok := h.handleAddOrUpdateActivitySteps(ctx, b, update, userState, 2)
-		if !ok {
-			return
-		}
-		if err := h.activityDAO.Update(&userState.Activity); err != nil {
-			log.Println("failed to update activity", userState.Activity, err)
-			b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:          chatID,
-				MessageThreadID: msgThreadID,
-				Text:            "Failed to update activity! Please try send the value again!",
-			})
-			return
-		}
+func (h *ActivityHandler) parseActivityToUpdate(ctx context.Context, input WizardInput, state *UserState) error {
+	activity, err := h.getActivityByInput(ctx, input, state.localizer())
+	if err != nil {
+		return err
+	}
+	if !isSameUser(input.User, activity.CreatedBy, activity.CreatedByID) {
+		return errors.New(state.localizer().T(msgActivityNotAuthorized))
+	}
+	state.Activity = *activity
+	return nil
+}
+
+// completeSelectActivityToUpdate waits for the user to select the field to update via callback (inline keyboard)
+func (h *ActivityHandler) completeSelectActivityToUpdate(ctx context.Context, b Messenger, userStateKey string, userState *UserState) {
+	h.updateWizard.ShowCurrentMenu(ctx, b, userStateKey, userState)
+}
+
+func (h *ActivityHandler) completeUpdateActivity(ctx context.Context, b Messenger, userStateKey string, userState *UserState) {
+	chatID := userState.ChatID
+	msgThreadID := userState.MsgThreadID
+	l := userState.localizer()
+
+	prev, err := h.activityDAO.GetByID(userState.Activity.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to get activity", "activity_id", userState.Activity.ID, "err", err)
+	}
+	if err := h.activityDAO.Update(&userState.Activity); err != nil {
+		slog.ErrorContext(ctx, "failed to update activity", "activity_id", userState.Activity.ID, "err", err)
 		b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:          chatID,
 			MessageThreadID: msgThreadID,
-			Text:            "Activity updated successfully!\n" + userState.Activity.string(),
-			ParseMode:       "HTML",
-			ReplyMarkup:     keyboard,
+			Text:            l.T(msgActivityUpdateFailed),
+			ReplyMarkup:     getUpdateActivityKeyboard(l),
 		})
-		userState.Step = 2 // reset to step 2 to allow user to select other option to update
+		return
+	}
+	// allow user to select other option to update
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          chatID,
+		MessageThreadID: msgThreadID,
+		Text:            l.T(msgActivityUpdated, userState.Activity.string(l)),
+		ParseMode:       "HTML",
+		ReplyMarkup:     getUpdateActivityKeyboard(l),
+	})
+	if prev != nil {
+		h.notifier.notifyChange(ctx, b, prev, userState.Activity, userState.Activity.CreatedByID)
 	}
-
 }
 
-func (h *ActivityHandler) handleUpdateActivityCallback(ctx context.Context, b *bot.Bot, update *models.Update) {
+func (h *ActivityHandler) handleUpdateActivityCallback(ctx context.Context, b Messenger, update *models.Update) {
 	messageID := update.CallbackQuery.Message.Message.ID
-	log.Println("update activity callback for message", messageID, "from", getUserFullName(&update.CallbackQuery.From), "Data", update.CallbackQuery.Data)
+	slog.InfoContext(ctx, "update activity callback", "message_id", messageID, "data", update.CallbackQuery.Data)
 
 	options := strings.Split(update.CallbackQuery.Data, callbackSeparator)
 	if len(options) < 2 {
-		log.Println("invalid option callback", update.CallbackQuery.Data)
+		slog.WarnContext(ctx, "invalid option callback", "data", update.CallbackQuery.Data)
 		return
 	}
 
@@ -486,12 +1088,12 @@ func (h *ActivityHandler) handleUpdateActivityCallback(ctx context.Context, b *b
 	msgThreadID := update.CallbackQuery.Message.Message.MessageThreadID
 	userStateKey := getUserStateKey(chatID, msgThreadID, &update.CallbackQuery.From)
 
-	userState, exists := userStates[userStateKey]
-	if !exists || userState.StateType != UPDATE_ACTIVITY {
-		log.Println("invalid user state for update activity callback")
+	userState, exists := h.userStates.Get(userStateKey)
+	if !exists || userState.StateType != UPDATE_ACTIVITY || userState.Activity.ID == 0 {
+		slog.WarnContext(ctx, "invalid user state for update activity callback")
 		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 			CallbackQueryID: update.CallbackQuery.ID,
-			Text:            "request too old. not in update mode",
+			Text:            h.chatSettings.GetLocalizer(ctx, chatID).T(msgActivityNotUpdating),
 			ShowAlert:       true,
 		})
 		return
@@ -502,87 +1104,276 @@ func (h *ActivityHandler) handleUpdateActivityCallback(ctx context.Context, b *b
 		ShowAlert:       false,
 	})
 
-	switch options[1] {
-	case workplanUpdateEventCallbackOptionName:
-		userState.Step = 3
-
-	case workplanUpdateEventCallbackOptionStartedAt:
-		userState.Step = 4
-
-	case workplanUpdateEventCallbackOptionCommittee:
-		userState.Step = 5
-
-	case workplanUpdateEventCallbackOptionLead:
-		userState.Step = 6
-
-	case workplanUpdateEventCallbackOptionCoLead:
-		userState.Step = 7
-	default:
-		return
-	}
-
-	h.sendAddOrUpdateActivityPrompt(ctx, b, chatID, msgThreadID, userState.Step-2)
+	h.updateWizard.Edit(ctx, b, userStateKey, userState, options[1])
 }
 
-func (h *ActivityHandler) sendAddOrUpdateActivityPrompt(ctx context.Context, b *bot.Bot, chatID int64, msgThreadID int, step int) {
-	prompt, ok := activityStepPrompts[step]
-	if !ok {
-		log.Println("failed to get activity step prompt. step", step)
-		return
+func (h *ActivityHandler) deleteUserState(ctx context.Context, userStateKey string) {
+	if err := h.userStates.Delete(userStateKey); err != nil {
+		slog.ErrorContext(ctx, "error deleting user state", "key", userStateKey, "err", err)
 	}
+}
 
+func (h *ActivityHandler) sendUpdateActivityMenu(ctx context.Context, b Messenger, userStateKey string, userState *UserState) {
 	b.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:          chatID,
-		MessageThreadID: msgThreadID,
-		Text:            prompt,
+		ChatID:          userState.ChatID,
+		MessageThreadID: userState.MsgThreadID,
+		Text:            userState.localizer().T(msgActivitySelectField, userState.Activity.string(userState.localizer())),
+		ParseMode:       "HTML",
+		ReplyMarkup:     getUpdateActivityKeyboard(userState.localizer()),
 	})
 }
 
+// getUpdateActivityKeyboard creates inline keyboard for update options
+func getUpdateActivityKeyboard(l *Localizer) *models.InlineKeyboardMarkup {
+	return &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
+				{Text: l.T(msgButtonName), CallbackData: strings.Join([]string{workplanUpdateEventCallbackPrefix, workplanUpdateEventCallbackOptionName}, callbackSeparator)},
+				{Text: l.T(msgButtonStartTime), CallbackData: strings.Join([]string{workplanUpdateEventCallbackPrefix, workplanUpdateEventCallbackOptionStartedAt}, callbackSeparator)},
+				{Text: l.T(msgButtonCommittee), CallbackData: strings.Join([]string{workplanUpdateEventCallbackPrefix, workplanUpdateEventCallbackOptionCommittee}, callbackSeparator)},
+			},
+			{
+				{Text: l.T(msgButtonLead), CallbackData: strings.Join([]string{workplanUpdateEventCallbackPrefix, workplanUpdateEventCallbackOptionLead}, callbackSeparator)},
+				{Text: l.T(msgButtonCoLead), CallbackData: strings.Join([]string{workplanUpdateEventCallbackPrefix, workplanUpdateEventCallbackOptionCoLead}, callbackSeparator)},
+				{Text: l.T(msgButtonStatus), CallbackData: strings.Join([]string{workplanUpdateEventCallbackPrefix, workplanUpdateEventCallbackOptionStatus}, callbackSeparator)},
+			},
+			{
+				{Text: l.T(msgButtonEndTime), CallbackData: strings.Join([]string{workplanUpdateEventCallbackPrefix, workplanUpdateEventCallbackOptionEndsAt}, callbackSeparator)},
+				{Text: l.T(msgButtonLocation), CallbackData: strings.Join([]string{workplanUpdateEventCallbackPrefix, workplanUpdateEventCallbackOptionLocation}, callbackSeparator)},
+				{Text: l.T(msgButtonDescription), CallbackData: strings.Join([]string{workplanUpdateEventCallbackPrefix, workplanUpdateEventCallbackOptionDescription}, callbackSeparator)},
+			},
+			getCancelButtonRow(l),
+		},
+	}
+}
+
 // sendAllActivities from past 2 months, total 18 months
-func (h *ActivityHandler) sendAllActivities(ctx context.Context, b *bot.Bot, chatID int64, msgThreadID int) {
+func (h *ActivityHandler) sendAllActivities(ctx context.Context, b Messenger, chatID int64, msgThreadID int, l *Localizer) {
 	// Logic to view calendar of activities from past 2 months, total 18 months
-	startTime := getCurrentMonthInUTC().AddDate(0, -2, 0)
+	startTime := getCurrentMonth(l.Location).AddDate(0, -2, 0)
 	endTime := startTime.AddDate(0, 18, 0).Add(-time.Nanosecond)
-	h.sendActivitiesForPeriod(ctx, b, chatID, msgThreadID, startTime, endTime)
+	h.sendActivitiesForPeriod(ctx, b, chatID, msgThreadID, startTime, endTime, l)
 }
 
-func (h *ActivityHandler) sendActivitiesForPeriod(ctx context.Context, b *bot.Bot, chatID int64, msgThreadID int, start, end time.Time) {
+// sendActivitiesForPeriod sends the activities between start and end, both in the timezone of the chat
+func (h *ActivityHandler) sendActivitiesForPeriod(ctx context.Context, b Messenger, chatID int64, msgThreadID int, start, end time.Time, l *Localizer) {
 	activities, err := h.activityDAO.GetByDuration(start, end)
 	if err != nil {
-		log.Println("error retrieving activities", start, end, err)
+		slog.ErrorContext(ctx, "error retrieving activities", "start", start, "end", end, "err", err)
 		return
 	}
-	startMonth := start.Format(monthFormat)
-	endMonth := end.Format(monthFormat)
+	startMonth := l.FormatMonth(start)
+	endMonth := l.FormatMonth(end)
 	periodStr := startMonth
 	if startMonth != endMonth {
 		periodStr += " - " + endMonth
 	}
+	h.sendActivities(ctx, b, chatID, msgThreadID, l.T(msgActivitiesTitle, periodStr, h.getActivitiesMessage(ctx, activities, l)))
+}
 
-	messageText := fmt.Sprintf("Activities (%s):\n%s", periodStr, getActivitiesMessage(activities))
+// sendFilteredActivities sends the latest activities matching the filter, described by the label
+func (h *ActivityHandler) sendFilteredActivities(ctx context.Context, b Messenger, chatID int64, msgThreadID int, label string, filter ActivityFilter, l *Localizer) {
+	activities, err := h.activityDAO.Search(filter, activitySearchLimit)
+	if err != nil {
+		slog.ErrorContext(ctx, "error searching activities", "filter", filter, "err", err)
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: msgThreadID,
+			Text:            l.T(msgActivityGetFailed),
+		})
+		return
+	}
+	text := l.T(msgActivitiesTitle, html.EscapeString(label), h.getActivitiesMessage(ctx, activities, l))
+	if len(activities) == activitySearchLimit {
+		text += l.T(msgActivitySearchLimited, activitySearchLimit)
+	}
+	h.sendActivities(ctx, b, chatID, msgThreadID, text)
+}
+
+// getActivitiesMessage lists the activities with the headcounts of their polls
+func (h *ActivityHandler) getActivitiesMessage(ctx context.Context, activities []Activity, l *Localizer) string {
+	ids := make([]int64, 0, len(activities))
+	for _, activity := range activities {
+		ids = append(ids, activity.ID)
+	}
+	headcounts, err := h.events.GetHeadcounts(ids)
+	if err != nil {
+		// the activities are still worth showing
+		slog.ErrorContext(ctx, "error retrieving headcounts", "err", err)
+	}
+	return getActivitiesMessage(activities, headcounts, l)
+}
+
+func (h *ActivityHandler) sendActivities(ctx context.Context, b Messenger, chatID int64, msgThreadID int, text string) {
 	b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:          chatID,
 		MessageThreadID: msgThreadID,
-		Text:            messageText,
+		Text:            text,
 		ParseMode:       "HTML",
 	})
 }
 
-func getActivitiesMessage(activities []Activity) string {
+// sendOrgFilterMenu replaces the workplan menu with the organizations to filter by, archived ones included
+func (h *ActivityHandler) sendOrgFilterMenu(ctx context.Context, b Messenger, chatID int64, messageID int, l *Localizer) {
+	orgs, err := h.orgs.ListOrgs()
+	if err != nil {
+		slog.ErrorContext(ctx, "error listing orgs", "err", err)
+		return
+	}
+	var rows [][]models.InlineKeyboardButton
+	for i, org := range orgs {
+		if i%orgButtonsPerRow == 0 {
+			rows = append(rows, nil)
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], models.InlineKeyboardButton{
+			Text:         org.Label(),
+			CallbackData: strings.Join([]string{workplanFilterOrgCallbackPrefix, string(org.Name)}, callbackSeparator),
+		})
+	}
+	rows = append(rows, []models.InlineKeyboardButton{{
+		Text:         l.T(msgButtonBack),
+		CallbackData: strings.Join([]string{workplanFilterOrgCallbackPrefix, callbackNavBack}, callbackSeparator),
+	}})
+	_, err = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      chatID,
+		MessageID:   messageID,
+		Text:        l.T(msgActivityFilterOrgPrompt),
+		ReplyMarkup: &models.InlineKeyboardMarkup{InlineKeyboard: rows},
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "error editing message", "err", err)
+	}
+}
+
+// handleFilterByOrg sends the activities of the selected organization
+func (h *ActivityHandler) handleFilterByOrg(ctx context.Context, b Messenger, update *models.Update) {
+	messageID := update.CallbackQuery.Message.Message.ID
+	chatID := update.CallbackQuery.Message.Message.Chat.ID
+	msgThreadID := update.CallbackQuery.Message.Message.MessageThreadID
+	l := h.chatSettings.GetLocalizer(ctx, chatID)
+	b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
+		ShowAlert:       false,
+	})
+
+	option := strings.TrimPrefix(update.CallbackQuery.Data, workplanFilterOrgCallbackPrefix+callbackSeparator)
+	if option == callbackNavBack {
+		kb, msg := h.getWorkplanMenu(l)
+		_, err := b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:      chatID,
+			MessageID:   messageID,
+			Text:        msg,
+			ReplyMarkup: kb,
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "error editing message", "err", err)
+		}
+		return
+	}
+	org, err := h.orgs.GetOrg(Org(option))
+	if err != nil {
+		slog.WarnContext(ctx, "error getting org", "org", option, "err", err)
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: msgThreadID,
+			Text:            l.T(msgOrgGetFailed),
+		})
+		return
+	}
+	h.sendFilteredActivities(ctx, b, chatID, msgThreadID, org.Label(), ActivityFilter{Org: org.Name}, l)
+}
+
+// parseActivityLeadFilter collects the lead or co-lead to filter by, who can be mentioned
+func (h *ActivityHandler) parseActivityLeadFilter(ctx context.Context, input WizardInput, state *UserState) error {
+	if strings.TrimSpace(input.Text) == "" {
+		return errors.New(state.localizer().T(msgActivityFilterLeadPrompt))
+	}
+	return h.parseActivityLead(ctx, input, state)
+}
+
+// completeFilterByLead sends the activities of the lead, matched by name and by user ID if it is known
+func (h *ActivityHandler) completeFilterByLead(ctx context.Context, b Messenger, userStateKey string, userState *UserState) {
+	h.deleteUserState(ctx, userStateKey)
+	lead := userState.Activity
+	h.sendFilteredActivities(ctx, b, userState.ChatID, userState.MsgThreadID, lead.Lead,
+		ActivityFilter{LeadName: lead.Lead, LeadID: lead.LeadID}, userState.localizer())
+}
+
+// sendConflicts sends the clashes between the activities of the months given as
+// YYYY-MM, from the first to the last one, or of the current month
+func (h *ActivityHandler) sendConflicts(ctx context.Context, b Messenger, chatID int64, msgThreadID int, months []string, l *Localizer) {
+	reply := func(text string) {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: msgThreadID,
+			Text:            text,
+			ParseMode:       "HTML",
+		})
+	}
+	if len(months) > 2 {
+		reply(html.EscapeString(l.T(msgActivityConflictsUsage)))
+		return
+	}
+	start := getCurrentMonth(l.Location)
+	end := start
+	for i, month := range months {
+		t, err := time.ParseInLocation(monthInputFormat, month, l.Location)
+		if err != nil {
+			reply(html.EscapeString(l.T(msgActivityConflictsUsage)))
+			return
+		}
+		if i == 0 {
+			start = t
+		}
+		end = t
+	}
+	if end.Before(start) {
+		start, end = end, start
+	}
+	end = end.AddDate(0, 1, 0)
+
+	activities, err := h.activityDAO.GetOverlapping(start, end)
+	if err != nil {
+		slog.ErrorContext(ctx, "error retrieving activities", "start", start, "end", end, "err", err)
+		reply(l.T(msgActivityGetFailed))
+		return
+	}
+	periodStr := l.FormatMonth(start)
+	if lastMonth := l.FormatMonth(end.AddDate(0, -1, 0)); lastMonth != periodStr {
+		periodStr += " - " + lastMonth
+	}
+	text := l.T(msgNoActivityConflicts)
+	if clashes := findActivityClashes(activities); len(clashes) > 0 {
+		text = getActivityClashesMessage(clashes, true, l)
+	}
+	reply(l.T(msgActivityConflictsTitle, periodStr, text))
+}
+
+// getActivitiesMessage lists the activities by month, with the headcount of the poll of those which have one
+func getActivitiesMessage(activities []Activity, headcounts map[int64]int, l *Localizer) string {
 	if len(activities) == 0 {
-		return "no activities found."
+		return l.T(msgNoActivities)
 	}
 	str := ""
 	var year int
 	var month time.Month
 	for _, activity := range activities {
-		y, m, _ := activity.StartedAt.Date()
+		startedAt := activity.StartedAt.In(l.Location)
+		y, m, _ := startedAt.Date()
 		if y != year || m != month {
 			year = y
 			month = m
-			str += fmt.Sprintf("<b><u>%v %d</u></b>\n\n", month, year)
+			str += fmt.Sprintf("<b><u>%s</u></b>\n\n", l.FormatMonth(startedAt))
+		}
+		line := activity.string(l)
+		if headcount, ok := headcounts[activity.ID]; ok {
+			line += "\n" + l.T(msgActivityHeadcount, headcount)
+		}
+		if activity.Status == ActivityCancelled {
+			str += "<s>" + line + "</s>\n\n"
+		} else {
+			str += line + "\n\n"
 		}
-		str += activity.string() + "\n\n"
 	}
 	return str
 }
@@ -0,0 +1,646 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+)
+
+func TestActivityHandler(t *testing.T) {
+	en := NewLocalizer(langEnglish, time.UTC)
+	workplan := func(tb *testBot) HandlerFunc { return tb.activities.handleWorkplan }
+	menu := func(tb *testBot) HandlerFunc { return tb.activities.handleWorkplanCallback }
+	updateField := func(tb *testBot) HandlerFunc { return tb.activities.handleUpdateActivityCallback }
+	input := func(tb *testBot) HandlerFunc { return tb.defaults.handle }
+	button := func(tb *testBot) HandlerFunc { return tb.defaults.handleWizardInputCallback }
+	// remember stands in for the middleware which remembers the senders of messages
+	remember := func(tb *testBot) HandlerFunc {
+		return func(ctx context.Context, b Messenger, update *models.Update) {
+			tb.knownUsers.Remember(update.Message.From)
+		}
+	}
+	filterOrg := func(tb *testBot) HandlerFunc { return tb.activities.handleFilterByOrg }
+	// leadBob makes Bob a co-lead of the hike by user ID
+	leadBob := func(tb *testBot) HandlerFunc {
+		return func(ctx context.Context, b Messenger, update *models.Update) {
+			activity, err := tb.activityDAO.GetByID(1)
+			if err != nil {
+				t.Fatalf("GetByID failed: %v", err)
+			}
+			activity.CoLeads, activity.CoLeadIDs = []string{"Bob"}, []int64{testBob.ID}
+			if err := tb.activityDAO.Update(activity); err != nil {
+				t.Fatalf("Update failed: %v", err)
+			}
+		}
+	}
+	testCarol := &models.User{ID: 3, FirstName: "Carol"}
+	restore := func(tb *testBot) HandlerFunc { return tb.activities.handleRestoreActivity }
+	// orgAdmin makes the user an admin of the organization
+	orgAdmin := func(name Org, user *models.User) func(tb *testBot) HandlerFunc {
+		return func(tb *testBot) HandlerFunc {
+			return func(ctx context.Context, b Messenger, update *models.Update) {
+				if err := tb.orgDAO.AddOrgAdmin(name, user.ID); err != nil {
+					t.Fatalf("AddOrgAdmin failed: %v", err)
+				}
+			}
+		}
+	}
+	// legacyCreator forgets the user ID of the creator of the hike, as before user IDs were stored
+	legacyCreator := func(tb *testBot) HandlerFunc {
+		return func(ctx context.Context, b Messenger, update *models.Update) {
+			if _, err := tb.db.Exec(`UPDATE activities SET created_by_id = 0 WHERE id = 1`); err != nil {
+				t.Fatalf("Failed to forget the creator: %v", err)
+			}
+		}
+	}
+	// admin lets the user run admin commands
+	admin := func(user *models.User) func(tb *testBot) HandlerFunc {
+		return func(tb *testBot) HandlerFunc {
+			return func(ctx context.Context, b Messenger, update *models.Update) {
+				AppConfig.Admins = append(AppConfig.Admins, user.ID)
+			}
+		}
+	}
+	// upload sends the data to the wizard as a document
+	upload := func(data string) func(tb *testBot) HandlerFunc {
+		return func(tb *testBot) HandlerFunc {
+			tb.messenger.addFile(t, "plan", []byte(data))
+			return tb.defaults.handle
+		}
+	}
+	document := &models.Update{Message: &models.Message{
+		Chat:     models.Chat{ID: testChatID},
+		From:     testAlice,
+		Document: &models.Document{FileID: "plan", FileName: "plan.csv"},
+	}}
+	hikeStart := getCurrentMonth(time.UTC).AddDate(0, 0, 14).Add(10 * time.Hour)
+
+	tests := []struct {
+		name       string
+		steps      []testStep
+		wantTexts  []string
+		wantAlerts []string
+		check      func(t *testing.T, tb *testBot)
+	}{
+		{
+			name:      "show menu",
+			steps:     []testStep{{workplan, textUpdate(testAlice, "/workplan")}},
+			wantTexts: []string{en.T(msgWorkplanChooseOption)},
+		},
+		{
+			name:      "view this month",
+			steps:     []testStep{{menu, callbackUpdate(testBob, "workplan_viewCurrentMonth")}},
+			wantTexts: []string{"Hike"},
+		},
+		{
+			name: "add activity",
+			steps: []testStep{
+				{menu, callbackUpdate(testAlice, "workplan_addEvent")},
+				{input, textUpdate(testAlice, "Quiz night")},
+				{input, textUpdate(testAlice, "2030-05-01 19:00")},
+				{input, textUpdate(testAlice, "xx")},
+				{input, textUpdate(testAlice, "cc")},
+				{input, textUpdate(testAlice, "Alice")},
+				{input, textUpdate(testAlice, "Bob; Carol")},
+			},
+			wantTexts: []string{
+				en.T(msgActivityNamePrompt),
+				en.T(msgActivityStartedPrompt, startTimeExamples),
+				en.T(msgStartTimeSet, "Wed, 2030-05-01 19:00"),
+				en.T(msgActivityOrgPrompt),
+				en.T(msgActivityInvalidOrg, []Org{OrgCC, OrgPEAK}),
+				en.T(msgActivityLeadPrompt),
+				en.T(msgActivityCoLeadPrompt),
+				"Quiz night",
+			},
+			check: func(t *testing.T, tb *testBot) {
+				activity, err := tb.activityDAO.GetByID(2)
+				if err != nil {
+					t.Fatalf("GetByID failed: %v", err)
+				}
+				want := time.Date(2030, 5, 1, 19, 0, 0, 0, time.UTC)
+				if activity.Name != "Quiz night" || activity.Org != OrgCC || activity.Lead != "Alice" ||
+					len(activity.CoLeads) != 2 || !activity.StartedAt.Equal(want) || activity.CreatedByID != testAlice.ID {
+					t.Errorf("Unexpected activity %+v", activity)
+				}
+			},
+		},
+		{
+			name: "add activity with mentioned leads",
+			steps: []testStep{
+				{remember, textUpdate(&models.User{ID: 2, FirstName: "Bob", Username: "bob"}, "hi")},
+				{menu, callbackUpdate(testAlice, "workplan_addEvent")},
+				{input, textUpdate(testAlice, "Quiz night")},
+				{input, textUpdate(testAlice, "2030-05-01 19:00")},
+				{input, textUpdate(testAlice, "cc")},
+				{input, textUpdate(testAlice, "@Bob")},
+				{input, &models.Update{Message: &models.Message{
+					Chat:     models.Chat{ID: testChatID},
+					From:     testAlice,
+					Text:     "Carol; @dave",
+					Entities: []models.MessageEntity{{Type: models.MessageEntityTypeTextMention, Offset: 0, Length: 5, User: testCarol}},
+				}}},
+			},
+			wantTexts: []string{
+				en.T(msgActivityNamePrompt),
+				en.T(msgActivityStartedPrompt, startTimeExamples),
+				en.T(msgStartTimeSet, "Wed, 2030-05-01 19:00"),
+				en.T(msgActivityOrgPrompt),
+				en.T(msgActivityLeadPrompt),
+				en.T(msgActivityCoLeadPrompt),
+				en.T(msgLeadUnknownUsers, "@dave"),
+				"Quiz night",
+				"You are now the lead",
+				"You are now the co-lead",
+			},
+			check: func(t *testing.T, tb *testBot) {
+				activity, err := tb.activityDAO.GetByID(2)
+				if err != nil {
+					t.Fatalf("GetByID failed: %v", err)
+				}
+				if activity.Lead != "@Bob" || activity.LeadID != 2 || !slices.Equal(activity.CoLeads, []string{"Carol", "@dave"}) ||
+					!slices.Equal(activity.CoLeadIDs, []int64{3, 0}) {
+					t.Errorf("Unexpected activity %+v", activity)
+				}
+			},
+		},
+		{
+			name: "add activity with conflicts",
+			steps: []testStep{
+				{menu, callbackUpdate(testAlice, "workplan_addEvent")},
+				{input, textUpdate(testAlice, "Clean-up")},
+				{input, textUpdate(testAlice, hikeStart.Add(time.Hour).Format(timeFormat))},
+				{button, callbackUpdate(testAlice, "wizIn_confirm")},
+				{input, textUpdate(testAlice, "cc")},
+				{input, textUpdate(testAlice, "alice")},
+				{button, callbackUpdate(testAlice, "wizIn_back")},
+				{input, textUpdate(testAlice, "Bob")},
+				{input, textUpdate(testAlice, "Carol")},
+				{workplan, textUpdate(testAlice, "/workplan conflicts")},
+			},
+			wantTexts: []string{
+				en.T(msgActivityNamePrompt),
+				en.T(msgActivityStartedPrompt, startTimeExamples),
+				en.T(msgStartTimeSet, en.FormatTime(hikeStart.Add(time.Hour))),
+				"Same time slot</b>\n<b>" + en.FormatTime(hikeStart) + " Hike",
+				en.T(msgActivityOrgPrompt),
+				en.T(msgActivityLeadPrompt),
+				"Double-booked: alice",
+				en.T(msgActivityLeadPrompt),
+				en.T(msgActivityCoLeadPrompt),
+				"Clean-up",
+				"Same time slot</b>\n<b>" + en.FormatTime(hikeStart) + " Hike",
+			},
+			check: func(t *testing.T, tb *testBot) {
+				activity, err := tb.activityDAO.GetByID(2)
+				if err != nil {
+					t.Fatalf("GetByID failed: %v", err)
+				}
+				if activity.Name != "Clean-up" || activity.Lead != "Bob" {
+					t.Errorf("Unexpected activity %+v", activity)
+				}
+			},
+		},
+		{
+			name: "import activities",
+			steps: []testStep{
+				{workplan, textUpdate(testAlice, "/workplan import")},
+				{upload("name,start,org,lead,co-leads\nQuiz,2030-05-01 19:00,cc,Bob,Carol\nFair,2030-05-02 10:00,PEAK,Alice\n"), document},
+				{button, callbackUpdate(testAlice, "wizIn_confirm")},
+			},
+			wantTexts: []string{
+				en.T(msgActivityImportPrompt, startTimeExamples),
+				"<b>2 activities will be imported:</b>\n\nWed, 2030-05-01 19:00 <b>Quiz</b> (CC) Bob\nThu, 2030-05-02 10:00 <b>Fair</b> (PEAK) Alice",
+				en.T(msgActivitiesImported, 2),
+			},
+			check: func(t *testing.T, tb *testBot) {
+				all, err := tb.activityDAO.GetAll()
+				if err != nil {
+					t.Fatalf("GetAll failed: %v", err)
+				}
+				if names := activityNames(all); !slices.Equal(names, []string{"Hike", "Quiz", "Fair"}) {
+					t.Errorf("Unexpected activities %v", names)
+				}
+				if quiz := all[1]; quiz.Org != OrgCC || quiz.Lead != "Bob" || !slices.Equal(quiz.CoLeads, []string{"Carol"}) || quiz.CreatedByID != testAlice.ID {
+					t.Errorf("Unexpected imported activity %+v", quiz)
+				}
+			},
+		},
+		{
+			name: "import invalid activities",
+			steps: []testStep{
+				{workplan, textUpdate(testAlice, "/workplan import")},
+				{input, textUpdate(testAlice, "Quiz")},
+				{upload("Quiz,2030-05-01 19:00,cc,Bob\nFair,2030-05-02 10:00,XX,Alice\nTalk,2030-05-03 10:00,CC, \n"), document},
+			},
+			wantTexts: []string{
+				en.T(msgActivityImportPrompt, startTimeExamples),
+				en.T(msgActivityImportNoFile),
+				"2 of 3 activities are invalid, nothing has been imported. Fix them and send the file again:\n\nLine 2: " +
+					en.T(msgActivityInvalidOrg, []Org{OrgCC, OrgPEAK}) + "\nLine 3: lead is missing",
+			},
+			check: func(t *testing.T, tb *testBot) {
+				if all, _ := tb.activityDAO.GetAll(); len(all) != 1 {
+					t.Errorf("Expected nothing to be imported, got %v", activityNames(all))
+				}
+			},
+		},
+		{
+			name:      "search activities",
+			steps:     []testStep{{workplan, textUpdate(testBob, "/workplan search hik")}},
+			wantTexts: []string{"Activities (“hik”):\n<b><u>"},
+			check: func(t *testing.T, tb *testBot) {
+				if !strings.Contains(tb.messenger.texts()[0], "Hike") {
+					t.Errorf("Expected the hike to be found, got %q", tb.messenger.texts())
+				}
+			},
+		},
+		{
+			name: "search without text",
+			steps: []testStep{
+				{workplan, textUpdate(testBob, "/workplan search")},
+				{workplan, textUpdate(testBob, "/workplan search party")},
+			},
+			wantTexts: []string{en.T(msgActivitySearchUsage), en.T(msgNoActivities)},
+		},
+		{
+			name: "filter by org",
+			steps: []testStep{
+				{menu, callbackUpdate(testBob, "workplan_byOrg")},
+				{filterOrg, callbackUpdate(testBob, "wpFilterOrg_OLD")},
+				{filterOrg, callbackUpdate(testBob, "wpFilterOrg_PEAK")},
+				{filterOrg, callbackUpdate(testBob, "wpFilterOrg_back")},
+			},
+			wantTexts: []string{en.T(msgActivityFilterOrgPrompt), en.T(msgNoActivities), "Hike", en.T(msgWorkplanChooseOption)},
+		},
+		{
+			name: "filter by lead",
+			steps: []testStep{
+				{menu, callbackUpdate(testBob, "workplan_byLead")},
+				{input, textUpdate(testBob, "ALICE")},
+			},
+			wantTexts: []string{en.T(msgActivityFilterLeadPrompt), "Hike"},
+		},
+		{
+			name: "filter mine",
+			steps: []testStep{
+				{menu, callbackUpdate(testBob, "workplan_mine")},
+				{leadBob, nil},
+				{menu, callbackUpdate(testBob, "workplan_mine")},
+			},
+			wantTexts: []string{en.T(msgNoActivities), "Hike"},
+		},
+		{
+			name:      "conflicts of an invalid month",
+			steps:     []testStep{{workplan, textUpdate(testAlice, "/workplan conflicts 2030-13")}},
+			wantTexts: []string{"Usage: /workplan conflicts"},
+		},
+		{
+			name:      "no conflicts",
+			steps:     []testStep{{workplan, textUpdate(testAlice, "/workplan conflicts "+hikeStart.Format(monthInputFormat))}},
+			wantTexts: []string{en.T(msgNoActivityConflicts)},
+		},
+		{
+			name: "create poll from activity",
+			steps: []testStep{
+				{menu, callbackUpdate(testBob, "workplan_createPoll")},
+				{input, textUpdate(testBob, "1")},
+				{menu, callbackUpdate(testBob, "workplan_createPoll")},
+				{input, textUpdate(testBob, "1")},
+				{menu, callbackUpdate(testBob, "workplan_viewCurrentMonth")},
+			},
+			wantTexts: []string{
+				en.T(msgActivityPollPrompt),
+				"Hike\n" + en.T(msgPollStartTime, en.FormatTime(hikeStart)),
+				en.T(msgActivityPollCreated, 1, 1),
+				en.T(msgActivityPollPrompt),
+				en.T(msgActivityPollExists, 1),
+				"Alice(L), (CoL)\n" + en.T(msgActivityHeadcount, 0),
+			},
+			check: func(t *testing.T, tb *testBot) {
+				event, err := tb.eventDAO.GetEventByActivityID(1)
+				if err != nil {
+					t.Fatalf("GetEventByActivityID failed: %v", err)
+				}
+				if event.Description != "Hike" || event.StartedAt == nil || !event.StartedAt.Equal(hikeStart) ||
+					event.MessageID == 0 || event.CreatedByID != testBob.ID {
+					t.Errorf("Unexpected poll %+v", event)
+				}
+			},
+		},
+		{
+			name: "delete activity",
+			steps: []testStep{
+				{menu, callbackUpdate(testAlice, "workplan_deleteEvent")},
+				{input, textUpdate(testAlice, "1")},
+				{button, callbackUpdate(testAlice, "wizIn_confirm")},
+			},
+			wantTexts: []string{
+				en.T(msgActivityDeletePrompt),
+				"Delete this activity?\n<b>",
+				en.T(msgActivityDeleted, activityTrashRetentionDays),
+			},
+			check: func(t *testing.T, tb *testBot) {
+				if _, err := tb.activityDAO.GetByID(1); err == nil {
+					t.Error("Expected the activity to be deleted")
+				}
+				if deleted, _ := tb.activityDAO.GetDeleted(trashSince(time.Now())); len(deleted) != 1 {
+					t.Errorf("Expected the activity to be in the trash, got %v", activityNames(deleted))
+				}
+			},
+		},
+		{
+			name: "go back from deleting an activity",
+			steps: []testStep{
+				{menu, callbackUpdate(testAlice, "workplan_deleteEvent")},
+				{input, textUpdate(testAlice, "1")},
+				{button, callbackUpdate(testAlice, "wizIn_back")},
+			},
+			wantTexts: []string{en.T(msgActivityDeletePrompt), "Delete this activity?", en.T(msgActivityDeletePrompt)},
+			check: func(t *testing.T, tb *testBot) {
+				if _, err := tb.activityDAO.GetByID(1); err != nil {
+					t.Errorf("Expected the activity to be kept, got %v", err)
+				}
+			},
+		},
+		{
+			name: "delete activity of another user",
+			steps: []testStep{
+				{menu, callbackUpdate(testBob, "workplan_deleteEvent")},
+				{input, textUpdate(testBob, "1")},
+			},
+			wantTexts: []string{en.T(msgActivityDeletePrompt), en.T(msgActivityDeleteNotAuthorized)},
+		},
+		{
+			name: "delete activity as co-lead and admin",
+			steps: []testStep{
+				{leadBob, nil},
+				{menu, callbackUpdate(testBob, "workplan_deleteEvent")},
+				{input, textUpdate(testBob, "1")},
+				{button, callbackUpdate(testBob, "wizIn_back")},
+				{admin(testCarol), nil},
+				{menu, callbackUpdate(testCarol, "workplan_deleteEvent")},
+				{input, textUpdate(testCarol, "1")},
+				{button, callbackUpdate(testCarol, "wizIn_confirm")},
+			},
+			wantTexts: []string{
+				en.T(msgActivityDeletePrompt),
+				"Delete this activity?",
+				en.T(msgActivityDeletePrompt),
+				en.T(msgActivityDeletePrompt),
+				"Delete this activity?",
+				en.T(msgActivityDeleted, activityTrashRetentionDays),
+				"An activity you are the co-lead of has been deleted",
+			},
+			check: func(t *testing.T, tb *testBot) {
+				if got := tb.messenger.sentTo(); got[len(got)-1] != testBob.ID {
+					t.Errorf("Expected the co-lead to be told about the deletion, got messages to %v", got)
+				}
+			},
+		},
+		{
+			name: "delete activity as co-lead",
+			steps: []testStep{
+				{leadBob, nil},
+				{menu, callbackUpdate(testBob, "workplan_deleteEvent")},
+				{input, textUpdate(testBob, "1")},
+				{button, callbackUpdate(testBob, "wizIn_confirm")},
+			},
+			// the co-lead who deleted it is not told
+			wantTexts: []string{
+				en.T(msgActivityDeletePrompt),
+				"Delete this activity?",
+				en.T(msgActivityDeleted, activityTrashRetentionDays),
+			},
+		},
+		{
+			name: "delete activity as org admin",
+			steps: []testStep{
+				{orgAdmin(OrgCC, testBob), nil},
+				{menu, callbackUpdate(testBob, "workplan_deleteEvent")},
+				{input, textUpdate(testBob, "1")},
+				{orgAdmin(OrgPEAK, testBob), nil},
+				{input, textUpdate(testBob, "1")},
+				{button, callbackUpdate(testBob, "wizIn_confirm")},
+			},
+			wantTexts: []string{
+				en.T(msgActivityDeletePrompt),
+				en.T(msgActivityDeleteNotAuthorized),
+				"Delete this activity?",
+				en.T(msgActivityDeleted, activityTrashRetentionDays),
+			},
+		},
+		{
+			name: "delete activity created by a namesake",
+			steps: []testStep{
+				{legacyCreator, nil},
+				{menu, callbackUpdate(testAlice, "workplan_deleteEvent")},
+				{input, textUpdate(testAlice, "1")},
+			},
+			wantTexts: []string{en.T(msgActivityDeletePrompt), en.T(msgActivityDeleteNotAuthorized)},
+		},
+		{
+			name: "restore activity from the trash",
+			steps: []testStep{
+				{workplan, textUpdate(testAlice, "/workplan trash")},
+				{menu, callbackUpdate(testAlice, "workplan_deleteEvent")},
+				{input, textUpdate(testAlice, "1")},
+				{button, callbackUpdate(testAlice, "wizIn_confirm")},
+				{workplan, textUpdate(testAlice, "/workplan trash")},
+				{restore, callbackUpdate(testBob, "wpRestore_1")},
+				{restore, callbackUpdate(testAlice, "wpRestore_1")},
+				{restore, callbackUpdate(testAlice, "wpRestore_1")},
+			},
+			wantTexts: []string{
+				en.T(msgActivityTrashEmpty, activityTrashRetentionDays),
+				en.T(msgActivityDeletePrompt),
+				"Delete this activity?",
+				en.T(msgActivityDeleted, activityTrashRetentionDays),
+				"Hike",
+				"Activity restored:\n<b>",
+			},
+			wantAlerts: []string{en.T(msgActivityRestoreNotAuthorized), en.T(msgActivityNotInTrash)},
+			check: func(t *testing.T, tb *testBot) {
+				if _, err := tb.activityDAO.GetByID(1); err != nil {
+					t.Errorf("Expected the activity to be restored, got %v", err)
+				}
+				texts := tb.messenger.texts()
+				if trash := texts[4]; !strings.Contains(trash, "Deleted in the last 30 days") || !strings.Contains(trash, "Deleted "+time.Now().UTC().Format("Mon, 2006-01-02")) {
+					t.Errorf("Unexpected trash %q", trash)
+				}
+			},
+		},
+		{
+			name: "delete unknown activity",
+			steps: []testStep{
+				{menu, callbackUpdate(testAlice, "workplan_deleteEvent")},
+				{input, textUpdate(testAlice, "99")},
+			},
+			wantTexts: []string{en.T(msgActivityDeletePrompt), en.T(msgActivityNotFound)},
+		},
+		{
+			name: "update lead",
+			steps: []testStep{
+				{menu, callbackUpdate(testAlice, "workplan_updateEvent")},
+				{input, textUpdate(testAlice, "1")},
+				{updateField, callbackUpdate(testAlice, "wpUpdateevent_lead")},
+				{input, textUpdate(testAlice, "Dave")},
+			},
+			wantTexts: []string{en.T(msgActivityUpdatePrompt), "Hike", en.T(msgActivityLeadPrompt), "Dave"},
+			check: func(t *testing.T, tb *testBot) {
+				activity, err := tb.activityDAO.GetByID(1)
+				if err != nil {
+					t.Fatalf("GetByID failed: %v", err)
+				}
+				if activity.Lead != "Dave" {
+					t.Errorf("Expected lead Dave, got %q", activity.Lead)
+				}
+			},
+		},
+		{
+			name: "update committee with a button",
+			steps: []testStep{
+				{menu, callbackUpdate(testAlice, "workplan_updateEvent")},
+				{input, textUpdate(testAlice, "1")},
+				{updateField, callbackUpdate(testAlice, "wpUpdateevent_committee")},
+				{input, textUpdate(testAlice, "Old committee")},
+				{button, callbackUpdate(testAlice, "wizIn_CC")},
+			},
+			wantTexts: []string{
+				en.T(msgActivityUpdatePrompt),
+				"Hike",
+				en.T(msgActivityOrgPrompt),
+				en.T(msgActivityInvalidOrg, []Org{OrgCC, OrgPEAK}),
+				"CC",
+			},
+			check: func(t *testing.T, tb *testBot) {
+				var buttons []string
+				for _, row := range tb.messenger.calls[len(tb.messenger.calls)-4].ReplyMarkup.(*models.InlineKeyboardMarkup).InlineKeyboard {
+					for _, button := range row {
+						buttons = append(buttons, button.CallbackData)
+					}
+				}
+				if want := []string{"wizIn_CC", "wizIn_PEAK", cancelStateCallbackPrefix}; !slices.Equal(buttons, want) {
+					t.Errorf("Expected the active orgs as buttons %v, got %v", want, buttons)
+				}
+				activity, err := tb.activityDAO.GetByID(1)
+				if err != nil {
+					t.Fatalf("GetByID failed: %v", err)
+				}
+				if activity.Org != OrgCC {
+					t.Errorf("Expected org CC, got %q", activity.Org)
+				}
+			},
+		},
+		{
+			name: "update status, location and end time",
+			steps: []testStep{
+				{menu, callbackUpdate(testAlice, "workplan_updateEvent")},
+				{input, textUpdate(testAlice, "1")},
+				{updateField, callbackUpdate(testAlice, "wpUpdateevent_status")},
+				{input, textUpdate(testAlice, "done")},
+				{button, callbackUpdate(testAlice, "wizIn_confirmed")},
+				{updateField, callbackUpdate(testAlice, "wpUpdateevent_location")},
+				{input, textUpdate(testAlice, "Lion Rock")},
+				{updateField, callbackUpdate(testAlice, "wpUpdateevent_endsAt")},
+				{input, textUpdate(testAlice, "2000-01-01 10:00")},
+				{input, textUpdate(testAlice, "-")},
+			},
+			wantTexts: []string{
+				en.T(msgActivityUpdatePrompt),
+				"Hike",
+				en.T(msgActivityStatusPrompt),
+				en.T(msgActivityInvalidStatus, "Planned", "done"),
+				"[Confirmed]",
+				en.T(msgActivityLocationPrompt),
+				"📍 Lion Rock",
+				en.T(msgActivityEndsAtPrompt, startTimeExamples),
+				"Invalid input. Please enter an end time after the start time",
+				en.T(msgEndTimeCleared),
+				"Hike",
+			},
+			check: func(t *testing.T, tb *testBot) {
+				activity, err := tb.activityDAO.GetByID(1)
+				if err != nil {
+					t.Fatalf("GetByID failed: %v", err)
+				}
+				if activity.Status != ActivityConfirmed || activity.Location != "Lion Rock" || activity.EndsAt != nil {
+					t.Errorf("Unexpected activity %+v", activity)
+				}
+			},
+		},
+		{
+			name:       "org button without wizard",
+			steps:      []testStep{{button, callbackUpdate(testAlice, "wizIn_CC")}},
+			wantAlerts: []string{en.T(msgStateExpired)},
+		},
+		{
+			name: "update activity of another user",
+			steps: []testStep{
+				{menu, callbackUpdate(testBob, "workplan_updateEvent")},
+				{input, textUpdate(testBob, "1")},
+			},
+			wantTexts: []string{en.T(msgActivityUpdatePrompt), en.T(msgActivityNotAuthorized)},
+		},
+		{
+			name:       "select field without update in progress",
+			steps:      []testStep{{updateField, callbackUpdate(testAlice, "wpUpdateevent_lead")}},
+			wantAlerts: []string{en.T(msgActivityNotUpdating)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := newTestBot(t)
+			if err := tb.orgDAO.SaveOrg(&Organization{Name: "OLD", DisplayName: "Old committee"}); err != nil {
+				t.Fatalf("SaveOrg failed: %v", err)
+			}
+			_, err := tb.activityDAO.Save(&Activity{
+				Name:        "Hike",
+				Org:         OrgPEAK,
+				Lead:        "Alice",
+				CreatedBy:   getUserFullName(testAlice),
+				CreatedByID: testAlice.ID,
+				StartedAt:   hikeStart,
+			})
+			if err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+
+			assertTexts(t, tb.run(t, tt.steps), tt.wantTexts)
+			assertTexts(t, tb.messenger.alerts(), tt.wantAlerts)
+			if tt.check != nil {
+				tt.check(t, tb)
+			}
+		})
+	}
+}
+
+func TestGetActivitiesMessage(t *testing.T) {
+	l := NewLocalizer(langEnglish, time.UTC)
+	startedAt := time.Date(2025, 3, 8, 9, 0, 0, 0, time.UTC)
+	endsAt := startedAt.Add(3 * time.Hour)
+	nextDay := startedAt.AddDate(0, 0, 1)
+	activities := []Activity{
+		{ID: 1, Name: "Hike", Org: OrgPEAK, Lead: "Alice", StartedAt: startedAt, EndsAt: &endsAt, Location: "Lion Rock", Status: ActivityConfirmed},
+		{ID: 2, Name: "Camp <2 nights>", Org: OrgPEAK, Lead: "Bob", StartedAt: startedAt, EndsAt: &nextDay, Status: ActivityCancelled},
+		{ID: 3, Name: "Swim", Org: "R&D <Club>", Lead: "Carol", StartedAt: nextDay, Status: ActivityPlanned},
+	}
+	got := getActivitiesMessage(activities, map[int64]int{1: 3}, l)
+	for _, want := range []string{
+		"Sat, 2025-03-08 09:00-12:00 Hike",
+		"[Confirmed]\n📍 Lion Rock",
+		"<s><b>Sat, 2025-03-08 09:00 - Sun, 2025-03-09 09:00 Camp &lt;2 nights&gt;",
+		"[Confirmed]\n📍 Lion Rock\n👥 3 attending",
+		"[Cancelled]</s>",
+		"(Org: R&amp;D &lt;Club&gt;)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected the message to contain %q, got %q", want, got)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"html"
+	"strings"
+)
+
+// ActivityClash is a pair of activities taking place at the same time
+type ActivityClash struct {
+	First  Activity
+	Second Activity
+	// Leads are the people leading both activities
+	Leads []string
+}
+
+// overlaps reports whether both activities take place at the same time
+func (a Activity) overlaps(other Activity) bool {
+	return a.StartedAt.Before(other.endTime()) && other.StartedAt.Before(a.endTime())
+}
+
+// sharedLeads returns the lead and co-leads of the activity who also lead or
+// co-lead the other one, compared by user ID when both are known and by
+// case-insensitive name otherwise
+func (a Activity) sharedLeads(other Activity) []string {
+	otherLeads := other.leadNames()
+	var shared []string
+	for _, person := range a.leadNames() {
+		if person.Name == "" || containsFold(shared, person.Name) {
+			continue
+		}
+		for _, otherLead := range otherLeads {
+			if (person.ID != 0 && person.ID == otherLead.ID) || strings.EqualFold(person.Name, otherLead.Name) {
+				shared = append(shared, person.Name)
+				break
+			}
+		}
+	}
+	return shared
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// findActivityConflicts returns the clashes of the activity with the others,
+// leaving out the activity itself and cancelled activities
+func findActivityConflicts(activity Activity, others []Activity) []ActivityClash {
+	var clashes []ActivityClash
+	for _, other := range others {
+		if (activity.ID != 0 && other.ID == activity.ID) || other.status() == ActivityCancelled || !activity.overlaps(other) {
+			continue
+		}
+		clashes = append(clashes, ActivityClash{First: activity, Second: other, Leads: activity.sharedLeads(other)})
+	}
+	return clashes
+}
+
+// findActivityClashes returns every pair of overlapping activities which are not
+// cancelled. The activities must be ordered by start time
+func findActivityClashes(activities []Activity) []ActivityClash {
+	var clashes []ActivityClash
+	for i, activity := range activities {
+		if activity.status() == ActivityCancelled {
+			continue
+		}
+		for _, other := range activities[i+1:] {
+			if !other.StartedAt.Before(activity.endTime()) {
+				// the following activities start even later
+				break
+			}
+			clashes = append(clashes, findActivityConflicts(activity, []Activity{other})...)
+		}
+	}
+	return clashes
+}
+
+// leadClashes returns the clashes in which someone leads both activities
+func leadClashes(clashes []ActivityClash) []ActivityClash {
+	var leads []ActivityClash
+	for _, clash := range clashes {
+		if len(clash.Leads) > 0 {
+			leads = append(leads, clash)
+		}
+	}
+	return leads
+}
+
+// getActivityClashesMessage lists the clashes as HTML, each with the reason it is one.
+// With includeFirst, both activities are listed, otherwise only the second one
+func getActivityClashesMessage(clashes []ActivityClash, includeFirst bool, l *Localizer) string {
+	var blocks []string
+	for _, clash := range clashes {
+		reason := l.T(msgActivityClashSlot)
+		if len(clash.Leads) > 0 {
+			leads := make([]string, 0, len(clash.Leads))
+			for _, lead := range clash.Leads {
+				leads = append(leads, html.EscapeString(lead))
+			}
+			reason = l.T(msgActivityClashLeads, strings.Join(leads, ", "))
+		}
+		block := reason + "\n"
+		if includeFirst {
+			block += clash.First.string(l) + "\n"
+		}
+		blocks = append(blocks, block+clash.Second.string(l))
+	}
+	return strings.Join(blocks, "\n\n")
+}
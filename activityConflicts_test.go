@@ -0,0 +1,47 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFindActivityClashes(t *testing.T) {
+	start := time.Date(2025, 3, 8, 18, 0, 0, 0, time.UTC)
+	lateEnd := start.Add(6 * time.Hour)
+	activities := []Activity{
+		{ID: 1, Name: "Dinner", Lead: "Alice", StartedAt: start, EndsAt: &lateEnd},
+		{ID: 2, Name: "Quiz", Lead: "Bob", CoLeads: []string{"alice"}, StartedAt: start.Add(time.Hour)},
+		{ID: 3, Name: "Cancelled talk", Lead: "Alice", StartedAt: start.Add(2 * time.Hour), Status: ActivityCancelled},
+		{ID: 4, Name: "Late show", Lead: "Carol", StartedAt: start.Add(5 * time.Hour)},
+		{ID: 5, Name: "Breakfast", Lead: "Alice", StartedAt: start.Add(14 * time.Hour)},
+	}
+
+	var got []string
+	for _, clash := range findActivityClashes(activities) {
+		got = append(got, clash.First.Name+"/"+clash.Second.Name)
+		if clash.First.ID == 1 && clash.Second.ID == 2 && !reflect.DeepEqual(clash.Leads, []string{"Alice"}) {
+			t.Errorf("Expected Alice to be double-booked, got %v", clash.Leads)
+		}
+		if clash.Second.ID == 4 && len(clash.Leads) != 0 {
+			t.Errorf("Expected no double-booked lead, got %v", clash.Leads)
+		}
+	}
+	if want := []string{"Dinner/Quiz", "Dinner/Late show"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected clashes %v, got %v", want, got)
+	}
+
+	// the same user is double-booked however their name is written
+	mentioned := []Activity{
+		{ID: 6, Name: "Hike", Lead: "@alice", LeadID: 1, StartedAt: start},
+		{ID: 7, Name: "Talk", Lead: "Bob", CoLeads: []string{"Alice Chan", "Dave"}, CoLeadIDs: []int64{1, 0}, StartedAt: start},
+	}
+	if clashes := findActivityClashes(mentioned); len(clashes) != 1 || !reflect.DeepEqual(clashes[0].Leads, []string{"@alice"}) {
+		t.Errorf("Expected @alice to be double-booked by user ID, got %+v", clashes)
+	}
+
+	// an activity being updated does not clash with itself
+	if clashes := findActivityConflicts(activities[0], activities[:1]); len(clashes) != 0 {
+		t.Errorf("Expected no clash of an activity with itself, got %+v", clashes)
+	}
+}
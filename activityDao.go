@@ -3,39 +3,214 @@ package main
 import (
 	"database/sql"
 	"fmt"
+	"html"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	_ "modernc.org/sqlite"
 )
 
-type Org string
+// ActivityStatus is where an activity is in its lifecycle:
+// planned, then confirmed, then done, or cancelled before it is done
+type ActivityStatus string
 
 const (
-	OrgCC   = "CC"
-	OrgPEAK = "PEAK"
+	ActivityPlanned   ActivityStatus = "planned"
+	ActivityConfirmed ActivityStatus = "confirmed"
+	ActivityDone      ActivityStatus = "done"
+	ActivityCancelled ActivityStatus = "cancelled"
 )
 
-var AllOrgs = []Org{OrgCC, OrgPEAK}
+// activityStatusTransitions are the statuses each status can change to.
+// A cancelled activity can be planned again, a done one is final
+var activityStatusTransitions = map[ActivityStatus][]ActivityStatus{
+	ActivityPlanned:   {ActivityConfirmed, ActivityCancelled},
+	ActivityConfirmed: {ActivityDone, ActivityCancelled, ActivityPlanned},
+	ActivityCancelled: {ActivityPlanned},
+	ActivityDone:      {},
+}
+
+var activityStatusMessages = map[ActivityStatus]MessageKey{
+	ActivityPlanned:   msgActivityStatusPlanned,
+	ActivityConfirmed: msgActivityStatusConfirmed,
+	ActivityDone:      msgActivityStatusDone,
+	ActivityCancelled: msgActivityStatusCancelled,
+}
+
+// parseActivityStatus returns the status matching the case-insensitive input
+func parseActivityStatus(input string) (ActivityStatus, bool) {
+	status := ActivityStatus(strings.ToLower(strings.TrimSpace(input)))
+	_, ok := activityStatusTransitions[status]
+	return status, ok
+}
+
+// canChangeTo reports whether the status may change to next
+func (s ActivityStatus) canChangeTo(next ActivityStatus) bool {
+	for _, allowed := range activityStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+func (s ActivityStatus) label(l *Localizer) string {
+	if key, ok := activityStatusMessages[s]; ok {
+		return l.T(key)
+	}
+	return string(s)
+}
 
 type Activity struct {
 	ID          int64
 	Name        string
 	Org         Org
 	Lead        string
+	LeadID      int64 // Telegram user ID of the lead, 0 if only the name is known
 	CoLeads     []string
+	CoLeadIDs   []int64 // Telegram user IDs of the CoLeads in the same order, 0 if unknown
 	CreatedBy   string
 	CreatedByID int64
 	StartedAt   time.Time
+	EndsAt      *time.Time
+	Location    string
+	Description string
+	Status      ActivityStatus
+	RemindedAt  *time.Time // when the leads were reminded of the activity, nil if not yet
+	DeletedAt   *time.Time // when the activity was moved to the trash, nil if it was not
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 }
 
-func (a Activity) string() string {
-	return fmt.Sprintf("<b>%s %s - (Org: %s) - (ID:%d):</b> %s(L), %s(CoL)", a.StartedAt.Format(displayTimeFormat), a.Name, a.Org, a.ID, a.Lead, strings.Join(a.CoLeads, "(CoL), "))
+func (a Activity) string(l *Localizer) string {
+	coLeads := make([]string, 0, len(a.CoLeads))
+	for i, coLead := range a.CoLeads {
+		coLeads = append(coLeads, userLink(coLead, a.coLeadID(i)))
+	}
+	str := l.T(msgActivityLine, a.timeRange(l), html.EscapeString(a.Name), html.EscapeString(string(a.Org)), a.ID, userLink(a.Lead, a.LeadID), strings.Join(coLeads, l.T(msgActivityCoLeadSep)))
+	if a.Status != "" && a.Status != ActivityPlanned {
+		str += " " + l.T(msgActivityStatusTag, a.Status.label(l))
+	}
+	if a.Location != "" {
+		str += "\n" + l.T(msgActivityLocation, html.EscapeString(a.Location))
+	}
+	if a.Description != "" {
+		str += "\n" + html.EscapeString(a.Description)
+	}
+	return str
+}
+
+// userLink returns the escaped name, linked to the Telegram user if the ID is known
+func userLink(name string, id int64) string {
+	if id == 0 {
+		return html.EscapeString(name)
+	}
+	return fmt.Sprintf(`<a href="tg://user?id=%d">%s</a>`, id, html.EscapeString(name))
+}
+
+// coLeadID returns the Telegram user ID of the i-th co-lead, 0 if unknown
+func (a Activity) coLeadID(i int) int64 {
+	if i < len(a.CoLeadIDs) {
+		return a.CoLeadIDs[i]
+	}
+	return 0
+}
+
+// leadNames returns the lead and the co-leads with their user IDs, 0 if unknown
+func (a Activity) leadNames() []mentionedUser {
+	leads := []mentionedUser{{Name: a.Lead, ID: a.LeadID}}
+	for i, coLead := range a.CoLeads {
+		leads = append(leads, mentionedUser{Name: coLead, ID: a.coLeadID(i)})
+	}
+	return leads
+}
+
+// knownLeadID returns the user ID of the lead or co-lead with the case-insensitive name, 0 if there is none
+func (a Activity) knownLeadID(name string) int64 {
+	if a.LeadID != 0 && strings.EqualFold(a.Lead, name) {
+		return a.LeadID
+	}
+	for i, coLead := range a.CoLeads {
+		if id := a.coLeadID(i); id != 0 && strings.EqualFold(coLead, name) {
+			return id
+		}
+	}
+	return 0
+}
+
+// keepLeadIDs sets the user IDs of the lead and co-leads whose names are unchanged from prev
+func (a *Activity) keepLeadIDs(prev Activity) {
+	a.LeadID = prev.knownLeadID(a.Lead)
+	a.CoLeadIDs = make([]int64, len(a.CoLeads))
+	for i, coLead := range a.CoLeads {
+		a.CoLeadIDs[i] = prev.knownLeadID(coLead)
+	}
+}
+
+// timeRange returns the start time, followed by the end time if it is set.
+// The date of the end time is left out if the activity ends on the same day
+func (a Activity) timeRange(l *Localizer) string {
+	start := l.FormatTime(a.StartedAt)
+	if a.EndsAt == nil {
+		return start
+	}
+	startedAt, endsAt := a.StartedAt.In(l.Location), a.EndsAt.In(l.Location)
+	if startedAt.YearDay() == endsAt.YearDay() && startedAt.Year() == endsAt.Year() {
+		return start + "-" + endsAt.Format("15:04")
+	}
+	return start + " - " + l.FormatTime(endsAt)
+}
+
+// activityDefaultDuration is how long an activity without an end time is assumed to take
+const activityDefaultDuration = 3 * time.Hour
+
+// status returns the status of the activity, planned if it is not set yet
+func (a Activity) status() ActivityStatus {
+	if a.Status == "" {
+		return ActivityPlanned
+	}
+	return a.Status
+}
+
+// endTime returns when the activity ends, activityDefaultDuration after it starts if no end time is set
+func (a Activity) endTime() time.Time {
+	if a.EndsAt != nil {
+		return *a.EndsAt
+	}
+	return a.StartedAt.Add(activityDefaultDuration)
 }
 
-// ActivityDAO provides data access operations for activities
+// joinIDs stores the user IDs as a comma-separated list
+func joinIDs(ids []int64) string {
+	strs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		strs = append(strs, strconv.FormatInt(id, 10))
+	}
+	return strings.Join(strs, ",")
+}
+
+// splitIDs reads n user IDs stored by joinIDs, missing or invalid ones are 0
+func splitIDs(s string, n int) []int64 {
+	ids := make([]int64, n)
+	for i, str := range strings.Split(s, ",") {
+		if i < n {
+			ids[i], _ = strconv.ParseInt(str, 10, 64)
+		}
+	}
+	return ids
+}
+
+// nullTime returns t as a database value, NULL if it is nil
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+// ActivityDAO is the ActivityRepository kept in SQLite
 type ActivityDAO struct {
 	db *sql.DB
 }
@@ -45,48 +220,63 @@ func NewActivityDAO(db *sql.DB) *ActivityDAO {
 	return &ActivityDAO{db: db}
 }
 
-// Initialize creates the necessary tables if they don't exist
-func (dao *ActivityDAO) Initialize() error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS activities (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            name TEXT NOT NULL,
-            org TEXT NOT NULL,
-            lead TEXT NOT NULL,
-            co_leads TEXT,
-            started_at DATETIME NOT NULL,
-            created_by TEXT,
-			created_by_id INTEGER,
-            created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-            updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-        )`,
-	}
-
-	for _, q := range queries {
-		_, err := dao.db.Exec(q)
-		if err != nil {
-			return err
+// activityColumns are the columns read by scanActivities
+const activityColumns = `id, name, org, lead, co_leads, started_at, ends_at, location, description, status,
+	lead_id, co_lead_ids, reminded_at, deleted_at, created_by, created_by_id, created_at, updated_at`
+
+// Create inserts a new activity into the database
+func (dao *ActivityDAO) Save(activity *Activity) (int64, error) {
+	tx, err := dao.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := saveActivity(tx, activity); err != nil {
+		return 0, err
+	}
+	return activity.ID, tx.Commit()
+}
+
+// SaveAll creates all of the activities or, if any of them fails, none
+func (dao *ActivityDAO) SaveAll(activities []Activity) error {
+	tx, err := dao.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i := range activities {
+		if _, err := saveActivity(tx, &activities[i]); err != nil {
+			return fmt.Errorf("activity %q: %w", activities[i].Name, err)
 		}
 	}
-	return nil
+	return tx.Commit()
 }
 
-// Create inserts a new activity into the database
-func (dao *ActivityDAO) Save(activity *Activity) (int64, error) {
+func saveActivity(q dbQuerier, activity *Activity) (int64, error) {
 	query := `
-		INSERT INTO activities (name, org, lead, co_leads, started_at, created_by, created_by_id, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		INSERT INTO activities (name, org, lead, co_leads, started_at, ends_at, location, description, status,
+			lead_id, co_lead_ids, created_by, created_by_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 	`
 
 	coLeadsStr := strings.Join(activity.CoLeads, ",")
+	activity.Status = activity.status()
 
-	result, err := dao.db.Exec(
+	result, err := q.Exec(
 		query,
 		activity.Name,
 		activity.Org,
 		activity.Lead,
 		coLeadsStr,
-		activity.StartedAt,
+		activity.StartedAt.UTC(),
+		nullTime(activity.EndsAt),
+		activity.Location,
+		activity.Description,
+		activity.Status,
+		activity.LeadID,
+		joinIDs(activity.CoLeadIDs),
 		activity.CreatedBy,
 		activity.CreatedByID,
 	)
@@ -100,68 +290,159 @@ func (dao *ActivityDAO) Save(activity *Activity) (int64, error) {
 		return id, err
 	}
 	activity.ID = id
-	return id, nil
+	return id, saveActivityLeads(q, activity)
+}
+
+// saveActivityLeads replaces the rows of activity_leads, which index the lead at
+// position 0 and the co-leads after it by name and user ID
+func saveActivityLeads(q dbQuerier, activity *Activity) error {
+	if _, err := q.Exec(`DELETE FROM activity_leads WHERE activity_id = ?`, activity.ID); err != nil {
+		return err
+	}
+	for i, lead := range activity.leadNames() {
+		_, err := q.Exec(`INSERT INTO activity_leads (activity_id, position, name, user_id) VALUES (?, ?, ?, ?)`,
+			activity.ID, i, lead.Name, lead.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // GetByID retrieves an activity by its ID
 func (dao *ActivityDAO) GetByID(id int64) (*Activity, error) {
+	rows, err := dao.db.Query(`SELECT `+activityColumns+` FROM activities WHERE id = ? AND deleted_at IS NULL`, id)
+	if err != nil {
+		return nil, err
+	}
+	activities, err := scanActivities(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(activities) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &activities[0], nil
+}
+
+// GetByDuration retrieves activities within a specific time range
+func (dao *ActivityDAO) GetByDuration(startTime, endTime time.Time) ([]Activity, error) {
 	query := `
-		SELECT id, name, org, lead, co_leads, started_at, created_by, created_by_id, created_at, updated_at
+		SELECT ` + activityColumns + `
 		FROM activities
-		WHERE id = ?
+		WHERE started_at BETWEEN ? AND ? AND deleted_at IS NULL
+		ORDER BY started_at ASC
 	`
 
-	row := dao.db.QueryRow(query, id)
-
-	var a Activity
-	var coLeadsStr string
-
-	err := row.Scan(
-		&a.ID,
-		&a.Name,
-		&a.Org,
-		&a.Lead,
-		&coLeadsStr,
-		&a.StartedAt,
-		&a.CreatedBy,
-		&a.CreatedByID,
-		&a.CreatedAt,
-		&a.UpdatedAt,
-	)
+	rows, err := dao.db.Query(query, startTime.UTC(), endTime.UTC())
+	if err != nil {
+		return nil, err
+	}
+	return scanActivities(rows)
+}
 
+// GetOverlapping retrieves the activities which are not cancelled and take place
+// between startTime and endTime, ordered by start time
+func (dao *ActivityDAO) GetOverlapping(startTime, endTime time.Time) ([]Activity, error) {
+	query := `
+		SELECT ` + activityColumns + `
+		FROM activities
+		WHERE deleted_at IS NULL AND status <> ? AND started_at < ? AND (ends_at > ? OR (ends_at IS NULL AND started_at > ?))
+		ORDER BY started_at ASC
+	`
+
+	rows, err := dao.db.Query(query, ActivityCancelled, endTime.UTC(), startTime.UTC(), startTime.Add(-activityDefaultDuration).UTC())
 	if err != nil {
 		return nil, err
 	}
+	return scanActivities(rows)
+}
 
-	if coLeadsStr != "" {
-		a.CoLeads = strings.Split(coLeadsStr, ",")
-	} else {
-		a.CoLeads = []string{}
+// ActivityFilter selects the activities matching all of its non-empty fields
+type ActivityFilter struct {
+	// Text is part of the name, location or description, case-insensitive
+	Text string
+	Org  Org
+	// LeadName and LeadID match the lead or a co-lead by case-insensitive name or by user ID, either of them
+	LeadName string
+	LeadID   int64
+}
+
+// searchActivitiesQuery returns the query of the latest limit activities matching the filter, with ? placeholders.
+// Leads are looked up through the indexes of activity_leads
+func searchActivitiesQuery(filter ActivityFilter, limit int, postgres bool) (string, []any) {
+	nameEquals, like := `name = ? COLLATE NOCASE`, `LIKE ? ESCAPE '\'`
+	if postgres {
+		nameEquals, like = `lower(name) = lower(?)`, `ILIKE ? ESCAPE '\'`
+	}
+	conditions := []string{`deleted_at IS NULL`}
+	var args []any
+	if filter.Org != "" {
+		conditions = append(conditions, `org = ?`)
+		args = append(args, filter.Org)
 	}
+	var leadConditions []string
+	if filter.LeadName != "" {
+		leadConditions = append(leadConditions, nameEquals)
+		args = append(args, filter.LeadName)
+	}
+	if filter.LeadID != 0 {
+		leadConditions = append(leadConditions, `user_id = ?`)
+		args = append(args, filter.LeadID)
+	}
+	if len(leadConditions) > 0 {
+		conditions = append(conditions, `id IN (SELECT activity_id FROM activity_leads WHERE `+strings.Join(leadConditions, " OR ")+`)`)
+	}
+	if filter.Text != "" {
+		conditions = append(conditions, `(name `+like+` OR location `+like+` OR description `+like+`)`)
+		pattern := likePattern(filter.Text)
+		args = append(args, pattern, pattern, pattern)
+	}
+	where := ` WHERE ` + strings.Join(conditions, " AND ")
+	return `SELECT ` + activityColumns + ` FROM activities` + where + ` ORDER BY started_at DESC LIMIT ?`, append(args, limit)
+}
 
-	return &a, nil
+// likePattern matches the text anywhere, with the wildcards in it escaped
+func likePattern(text string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(text) + "%"
 }
 
-// GetByDuration retrieves activities within a specific time range
-func (dao *ActivityDAO) GetByDuration(startTime, endTime time.Time) ([]Activity, error) {
+// Search returns the latest limit activities matching the filter, earliest first
+func (dao *ActivityDAO) Search(filter ActivityFilter, limit int) ([]Activity, error) {
+	query, args := searchActivitiesQuery(filter, limit, false)
+	rows, err := dao.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	activities, err := scanActivities(rows)
+	slices.Reverse(activities)
+	return activities, err
+}
+
+// GetAll retrieves all activities ordered by start time
+func (dao *ActivityDAO) GetAll() ([]Activity, error) {
 	query := `
-		SELECT id, name, org, lead, co_leads, started_at, created_by, created_by_id, created_at, updated_at
+		SELECT ` + activityColumns + `
 		FROM activities
-		WHERE started_at BETWEEN ? AND ?
+		WHERE deleted_at IS NULL
 		ORDER BY started_at ASC
 	`
 
-	rows, err := dao.db.Query(query, startTime, endTime)
+	rows, err := dao.db.Query(query)
 	if err != nil {
 		return nil, err
 	}
+	return scanActivities(rows)
+}
+
+func scanActivities(rows *sql.Rows) ([]Activity, error) {
 	defer rows.Close()
 
 	var activities []Activity
 
 	for rows.Next() {
 		var a Activity
-		var coLeadsStr string
+		var coLeadsStr, coLeadIDsStr string
 
 		err := rows.Scan(
 			&a.ID,
@@ -170,6 +451,14 @@ func (dao *ActivityDAO) GetByDuration(startTime, endTime time.Time) ([]Activity,
 			&a.Lead,
 			&coLeadsStr,
 			&a.StartedAt,
+			&a.EndsAt,
+			&a.Location,
+			&a.Description,
+			&a.Status,
+			&a.LeadID,
+			&coLeadIDsStr,
+			&a.RemindedAt,
+			&a.DeletedAt,
 			&a.CreatedBy,
 			&a.CreatedByID,
 			&a.CreatedAt,
@@ -185,49 +474,144 @@ func (dao *ActivityDAO) GetByDuration(startTime, endTime time.Time) ([]Activity,
 		} else {
 			a.CoLeads = []string{}
 		}
+		a.CoLeadIDs = splitIDs(coLeadIDsStr, len(a.CoLeads))
 
 		activities = append(activities, a)
 	}
 
-	return activities, nil
+	return activities, rows.Err()
 }
 
-// Update updates an existing activity
+// Update updates an existing activity. The leads are reminded again if the start time changes
 func (dao *ActivityDAO) Update(activity *Activity) error {
 	query := `
 		UPDATE activities
-		SET name = ?, org = ?, lead = ?, co_leads = ?, started_at = ?, created_by = ?, created_by_id = ?, updated_at = CURRENT_TIMESTAMP
-		WHERE id = ?
+		SET name = ?, org = ?, lead = ?, co_leads = ?, started_at = ?, ends_at = ?, location = ?, description = ?, status = ?,
+			lead_id = ?, co_lead_ids = ?, created_by = ?, created_by_id = ?, updated_at = CURRENT_TIMESTAMP,
+			reminded_at = CASE WHEN started_at = ? THEN reminded_at ELSE NULL END
+		WHERE id = ? AND deleted_at IS NULL
 	`
 
 	coLeadsStr := strings.Join(activity.CoLeads, ",")
+	activity.Status = activity.status()
 
-	_, err := dao.db.Exec(
+	tx, err := dao.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
 		query,
 		activity.Name,
 		activity.Org,
 		activity.Lead,
 		coLeadsStr,
-		activity.StartedAt,
+		activity.StartedAt.UTC(),
+		nullTime(activity.EndsAt),
+		activity.Location,
+		activity.Description,
+		activity.Status,
+		activity.LeadID,
+		joinIDs(activity.CoLeadIDs),
 		activity.CreatedBy,
 		activity.CreatedByID,
+		activity.StartedAt.UTC(),
 		activity.ID,
 	)
 
 	if err != nil {
 		return err
 	}
+	if err := saveActivityLeads(tx, activity); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
-	return nil
+// MarkReminded records when the leads of the activity were reminded of it, unless they were already.
+// It returns 0 if another caller claimed the reminder first
+func (dao *ActivityDAO) MarkReminded(id int64, at time.Time) (int64, error) {
+	res, err := dao.db.Exec(`UPDATE activities SET reminded_at = ? WHERE id = ? AND reminded_at IS NULL`, at.UTC(), id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
 }
 
-// Delete removes an activity by its ID
+// Delete moves an activity to the trash. It is hidden until it is restored or purged
 func (dao *ActivityDAO) Delete(id int64) (int64, error) {
-	query := `DELETE FROM activities WHERE id = ?`
+	res, err := dao.db.Exec(`UPDATE activities SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL`, time.Now().UTC(), id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GetDeleted retrieves the activities moved to the trash since the given time, most recently deleted first
+func (dao *ActivityDAO) GetDeleted(since time.Time) ([]Activity, error) {
+	query := `
+		SELECT ` + activityColumns + `
+		FROM activities
+		WHERE deleted_at >= ?
+		ORDER BY deleted_at DESC
+	`
 
-	res, err := dao.db.Exec(query, id)
+	rows, err := dao.db.Query(query, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	return scanActivities(rows)
+}
+
+// GetDeletedByID retrieves an activity moved to the trash since the given time
+func (dao *ActivityDAO) GetDeletedByID(id int64, since time.Time) (*Activity, error) {
+	rows, err := dao.db.Query(`SELECT `+activityColumns+` FROM activities WHERE id = ? AND deleted_at >= ?`, id, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	activities, err := scanActivities(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(activities) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &activities[0], nil
+}
+
+// Restore takes an activity deleted since the given time out of the trash
+func (dao *ActivityDAO) Restore(id int64, since time.Time) (int64, error) {
+	res, err := dao.db.Exec(`UPDATE activities SET deleted_at = NULL WHERE id = ? AND deleted_at >= ?`, id, since.UTC())
 	if err != nil {
 		return 0, err
 	}
 	return res.RowsAffected()
 }
+
+// Purge removes the activities deleted before the given time for good. Their polls are kept
+// but no longer linked to them, SQLite does not enforce the foreign key
+func (dao *ActivityDAO) Purge(before time.Time) (int64, error) {
+	tx, err := dao.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	purged := `SELECT id FROM activities WHERE deleted_at < ?`
+	if _, err := tx.Exec(`UPDATE events SET activity_id = NULL WHERE activity_id IN (`+purged+`)`, before.UTC()); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`DELETE FROM activity_leads WHERE activity_id IN (`+purged+`)`, before.UTC()); err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec(`DELETE FROM activities WHERE deleted_at < ?`, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return affectedRows, tx.Commit()
+}
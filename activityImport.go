@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"html"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+)
+
+const (
+	// activityImportMaxSize is the largest file /workplan import downloads
+	activityImportMaxSize = 1 << 20
+	// activityImportListLimit is the number of activities or errors listed in a reply
+	activityImportListLimit = 15
+
+	activityImportFileField = "file"
+)
+
+// activityImportColumns are the wizard fields filled from the CSV columns, in order.
+// The first four are required
+var activityImportColumns = []string{
+	workplanUpdateEventCallbackOptionName,
+	workplanUpdateEventCallbackOptionStartedAt,
+	workplanUpdateEventCallbackOptionCommittee,
+	workplanUpdateEventCallbackOptionLead,
+	workplanUpdateEventCallbackOptionCoLead,
+	workplanUpdateEventCallbackOptionEndsAt,
+	workplanUpdateEventCallbackOptionLocation,
+	workplanUpdateEventCallbackOptionDescription,
+}
+
+const activityImportRequiredColumns = 4
+
+// activityImportColumnNames name the columns in error messages
+var activityImportColumnNames = map[string]string{
+	workplanUpdateEventCallbackOptionName:        "name",
+	workplanUpdateEventCallbackOptionStartedAt:   "start",
+	workplanUpdateEventCallbackOptionCommittee:   "org",
+	workplanUpdateEventCallbackOptionLead:        "lead",
+	workplanUpdateEventCallbackOptionCoLead:      "co-leads",
+	workplanUpdateEventCallbackOptionEndsAt:      "end",
+	workplanUpdateEventCallbackOptionLocation:    "location",
+	workplanUpdateEventCallbackOptionDescription: "description",
+}
+
+// activityImportRow is an activity read from an import file, before it is validated
+type activityImportRow struct {
+	Line int
+	// Values are the texts entered for the wizard fields
+	Values map[string]string
+	// StartedAt and EndsAt are set by calendar files instead of the texts
+	StartedAt time.Time
+	EndsAt    *time.Time
+	// Err is why the row could not be read
+	Err error
+}
+
+// parseActivityImport reads and validates all activities of the file, nothing is kept if any of them is invalid
+func (h *ActivityHandler) parseActivityImport(ctx context.Context, input WizardInput, state *UserState) error {
+	l := state.localizer()
+	if input.Document == nil {
+		return errors.New(l.T(msgActivityImportNoFile))
+	}
+	data, err := input.ReadDocument(ctx, activityImportMaxSize)
+	if err != nil {
+		slog.WarnContext(ctx, "error downloading import file", "file_name", input.Document.FileName, "err", err)
+		return errors.New(l.T(msgActivityImportReadFailed, activityImportMaxSize>>10))
+	}
+	rows, err := readActivityImport(input.Document.FileName, data, l)
+	if err != nil {
+		return errors.New(l.T(msgActivityImportInvalidFile, err.Error()))
+	}
+	if len(rows) == 0 {
+		return errors.New(l.T(msgActivityImportEmpty))
+	}
+
+	var activities []Activity
+	var errs []string
+	for _, row := range rows {
+		activity, err := h.validateImportRow(ctx, row, state)
+		if err != nil {
+			errs = append(errs, l.T(msgActivityImportRowError, row.Line, err.Error()))
+			continue
+		}
+		activities = append(activities, activity)
+	}
+	if len(errs) > 0 {
+		return errors.New(l.T(msgActivityImportErrors, len(errs), len(rows), strings.Join(limitImportList(errs, l), "\n")))
+	}
+	state.Imports = activities
+	return nil
+}
+
+// validateImportRow applies the rules of the wizard fields to the texts of the row
+func (h *ActivityHandler) validateImportRow(ctx context.Context, row activityImportRow, state *UserState) (Activity, error) {
+	if row.Err != nil {
+		return Activity{}, row.Err
+	}
+	l := state.localizer()
+	rowState := &UserState{
+		ChatID:   state.ChatID,
+		Timezone: state.Timezone,
+		Language: state.Language,
+		Activity: Activity{CreatedBy: state.Activity.CreatedBy, CreatedByID: state.Activity.CreatedByID},
+	}
+	fields := slices.Concat(h.activityFields(), h.activityDetailFields())
+	for i, name := range activityImportColumns {
+		switch {
+		case name == workplanUpdateEventCallbackOptionStartedAt && !row.StartedAt.IsZero():
+			rowState.Activity.StartedAt = row.StartedAt
+			continue
+		case name == workplanUpdateEventCallbackOptionEndsAt && row.EndsAt != nil:
+			if !row.EndsAt.After(rowState.Activity.StartedAt) {
+				return Activity{}, errors.New(l.T(msgActivityInvalidEndsAt, l.FormatTime(rowState.Activity.StartedAt)))
+			}
+			rowState.Activity.EndsAt = row.EndsAt
+			continue
+		}
+		text := strings.TrimSpace(row.Values[name])
+		if text == "" {
+			if i < activityImportRequiredColumns {
+				return Activity{}, errors.New(l.T(msgActivityImportMissing, activityImportColumnNames[name]))
+			}
+			continue
+		}
+		for _, field := range fields {
+			if field.Name != name {
+				continue
+			}
+			if err := field.Parse(ctx, WizardInput{Text: text}, rowState); err != nil {
+				return Activity{}, err
+			}
+		}
+	}
+	return rowState.Activity, nil
+}
+
+// confirmActivityImport previews the activities to be imported
+func confirmActivityImport(ctx context.Context, state *UserState) string {
+	l := state.localizer()
+	lines := make([]string, 0, len(state.Imports))
+	for _, a := range state.Imports {
+		lines = append(lines, l.T(msgActivityImportLine, a.timeRange(l), html.EscapeString(a.Name), a.Org, userLink(a.Lead, a.LeadID)))
+	}
+	return l.T(msgActivityImportPreview, len(state.Imports), strings.Join(limitImportList(lines, l), "\n"))
+}
+
+// completeImportActivities saves the confirmed activities in one transaction
+func (h *ActivityHandler) completeImportActivities(ctx context.Context, b Messenger, userStateKey string, userState *UserState) {
+	l := userState.localizer()
+	if err := h.activityDAO.SaveAll(userState.Imports); err != nil {
+		slog.ErrorContext(ctx, "failed to import activities", "count", len(userState.Imports), "err", err)
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          userState.ChatID,
+			MessageThreadID: userState.MsgThreadID,
+			Text:            l.T(msgActivityImportFailed),
+			ReplyMarkup:     getCancelKeyboard(l),
+		})
+		return
+	}
+	slog.InfoContext(ctx, "activities imported", "count", len(userState.Imports), "user_id", userState.Activity.CreatedByID)
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          userState.ChatID,
+		MessageThreadID: userState.MsgThreadID,
+		Text:            l.T(msgActivitiesImported, len(userState.Imports)),
+	})
+	for _, activity := range userState.Imports {
+		h.notifier.notifyChange(ctx, b, nil, activity, activity.CreatedByID)
+	}
+	h.deleteUserState(ctx, userStateKey)
+}
+
+// limitImportList shortens a long list of activities or errors to fit in a message
+func limitImportList(lines []string, l *Localizer) []string {
+	if len(lines) <= activityImportListLimit {
+		return lines
+	}
+	return append(lines[:activityImportListLimit:activityImportListLimit], l.T(msgActivityImportMore, len(lines)-activityImportListLimit))
+}
+
+// readActivityImport reads the rows of a calendar file, recognized by its extension
+// or content, or of a CSV file otherwise
+func readActivityImport(fileName string, data []byte, l *Localizer) ([]activityImportRow, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	if strings.EqualFold(filepath.Ext(fileName), ".ics") ||
+		bytes.HasPrefix(bytes.ToUpper(bytes.TrimSpace(data)), []byte("BEGIN:VCALENDAR")) {
+		return readActivityImportICS(string(data), l), nil
+	}
+	return readActivityImportCSV(bytes.NewReader(data), l)
+}
+
+// readActivityImportCSV reads the columns of activityImportColumns. A first row starting with "name" is a header
+func readActivityImportCSV(r io.Reader, l *Localizer) ([]activityImportRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	var rows []activityImportRow
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		if len(rows) == 0 && line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), activityImportColumnNames[activityImportColumns[0]]) {
+			continue
+		}
+		row := activityImportRow{Line: line, Values: make(map[string]string)}
+		if len(record) < activityImportRequiredColumns || len(record) > len(activityImportColumns) {
+			row.Err = errors.New(l.T(msgActivityImportColumns, activityImportRequiredColumns, len(activityImportColumns), len(record)))
+		}
+		for i, value := range record {
+			if i < len(activityImportColumns) {
+				row.Values[activityImportColumns[i]] = value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// icsLine is an unfolded content line of a calendar file
+type icsLine struct {
+	number int
+	name   string
+	params map[string]string
+	value  string
+}
+
+// readActivityImportICS reads the events of a calendar file. The summary is the name,
+// the first category the org and the organizer the lead. Cancelled events are skipped
+func readActivityImportICS(text string, l *Localizer) []activityImportRow {
+	var rows []activityImportRow
+	var row *activityImportRow
+	cancelled := false
+	// depth counts the components nested in an event, such as alarms
+	depth := 0
+	for _, line := range unfoldICSLines(text) {
+		switch {
+		case line.name == "BEGIN" && row == nil && strings.EqualFold(line.value, "VEVENT"):
+			row = &activityImportRow{Line: line.number, Values: make(map[string]string)}
+			cancelled, depth = false, 0
+			continue
+		case row == nil:
+			continue
+		case line.name == "BEGIN":
+			depth++
+			continue
+		case line.name == "END" && depth > 0:
+			depth--
+			continue
+		case line.name == "END":
+			if !cancelled {
+				rows = append(rows, *row)
+			}
+			row = nil
+			continue
+		case depth > 0:
+			continue
+		}
+
+		switch line.name {
+		case "SUMMARY":
+			row.Values[workplanUpdateEventCallbackOptionName] = unescapeICSText(line.value)
+		case "CATEGORIES":
+			categories := strings.Split(line.value, ",")
+			row.Values[workplanUpdateEventCallbackOptionCommittee] = unescapeICSText(categories[0])
+		case "ORGANIZER":
+			lead := line.params["CN"]
+			if lead == "" {
+				lead = line.value
+				if len(lead) > len("mailto:") && strings.EqualFold(lead[:len("mailto:")], "mailto:") {
+					lead = lead[len("mailto:"):]
+				}
+			}
+			row.Values[workplanUpdateEventCallbackOptionLead] = lead
+		case "LOCATION":
+			row.Values[workplanUpdateEventCallbackOptionLocation] = unescapeICSText(line.value)
+		case "DESCRIPTION":
+			row.Values[workplanUpdateEventCallbackOptionDescription] = unescapeICSText(line.value)
+		case "STATUS":
+			cancelled = strings.EqualFold(line.value, "CANCELLED")
+		case "DTSTART", "DTEND":
+			t, err := parseICSTime(line.value, line.params, l.Location)
+			switch {
+			case err != nil:
+				row.Err = errors.New(l.T(msgActivityImportInvalidTime, line.value))
+			case line.name == "DTSTART":
+				row.StartedAt = t
+			default:
+				row.EndsAt = &t
+			}
+		}
+	}
+	return rows
+}
+
+// unfoldICSLines joins the content lines continued on the next line and splits them into name, parameters and value
+func unfoldICSLines(text string) []icsLine {
+	var lines []icsLine
+	var unfolded []string
+	var numbers []int
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(unfolded) > 0 {
+			unfolded[len(unfolded)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			unfolded = append(unfolded, line)
+			numbers = append(numbers, i+1)
+		}
+	}
+	for i, line := range unfolded {
+		// the value starts at the first colon outside of quoted parameter values
+		quoted := false
+		sep := -1
+		for j, r := range line {
+			if r == '"' {
+				quoted = !quoted
+			} else if r == ':' && !quoted {
+				sep = j
+				break
+			}
+		}
+		if sep < 0 {
+			continue
+		}
+		parts := strings.Split(line[:sep], ";")
+		params := make(map[string]string)
+		for _, param := range parts[1:] {
+			if key, value, ok := strings.Cut(param, "="); ok {
+				params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+			}
+		}
+		lines = append(lines, icsLine{number: numbers[i], name: strings.ToUpper(parts[0]), params: params, value: line[sep+1:]})
+	}
+	return lines
+}
+
+var icsTextUnescaper = strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+
+func unescapeICSText(s string) string {
+	return strings.TrimSpace(icsTextUnescaper.Replace(s))
+}
+
+// parseICSTime parses a UTC, local or floating date-time, or a date which starts at midnight.
+// Floating times are in loc
+func parseICSTime(value string, params map[string]string, loc *time.Location) (time.Time, error) {
+	if tz, err := time.LoadLocation(params["TZID"]); err == nil && params["TZID"] != "" {
+		loc = tz
+	}
+	switch {
+	case strings.HasSuffix(value, "Z"):
+		return time.Parse("20060102T150405Z", value)
+	case params["VALUE"] == "DATE" || len(value) == len("20060102"):
+		return time.ParseInLocation("20060102", value, loc)
+	default:
+		return time.ParseInLocation("20060102T150405", value, loc)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadActivityImportCSV(t *testing.T) {
+	l := NewLocalizer(langEnglish, time.UTC)
+	data := "\ufeffName,Start,Org,Lead,Co-leads\n" +
+		"Hike,2030-05-01 10:00,PEAK,Alice,Bob; Carol\n" +
+		"\n" +
+		"\"Quiz, night\",2030-05-02 19:00,CC,Bob,,2030-05-02 22:00,Hall,Bring pens\n" +
+		"Short,2030-05-03 10:00,CC\n"
+	rows, err := readActivityImport("plan.csv", []byte(data), l)
+	if err != nil {
+		t.Fatalf("readActivityImport failed: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("Expected 3 rows, got %+v", rows)
+	}
+	if rows[0].Line != 2 || rows[0].Values[workplanUpdateEventCallbackOptionCoLead] != "Bob; Carol" || rows[0].Err != nil {
+		t.Errorf("Unexpected first row %+v", rows[0])
+	}
+	if rows[1].Line != 4 || rows[1].Values[workplanUpdateEventCallbackOptionName] != "Quiz, night" ||
+		rows[1].Values[workplanUpdateEventCallbackOptionDescription] != "Bring pens" {
+		t.Errorf("Unexpected second row %+v", rows[1])
+	}
+	if rows[2].Err == nil || !strings.Contains(rows[2].Err.Error(), "expected 4 to 8 columns, got 3") {
+		t.Errorf("Expected a column count error, got %v", rows[2].Err)
+	}
+}
+
+func TestReadActivityImportICS(t *testing.T) {
+	hk, err := time.LoadLocation("Asia/Hong_Kong")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	l := NewLocalizer(langEnglish, hk)
+	data := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"SUMMARY:Hike\\, long",
+		"DTSTART;TZID=Europe/London:20300501T100000",
+		"DTEND:20300501T130000Z",
+		"CATEGORIES:PEAK,Outdoor",
+		"ORGANIZER;CN=\"Alice Wong\":mailto:alice@example.com",
+		"DESCRIPTION:Bring water\\nand ",
+		" snacks",
+		"BEGIN:VALARM",
+		"DESCRIPTION:Reminder",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Cancelled",
+		"DTSTART:20300502T100000Z",
+		"STATUS:CANCELLED",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Fair",
+		"DTSTART;VALUE=DATE:20300503",
+		"ORGANIZER:mailto:bob@example.com",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Broken",
+		"DTSTART:tomorrow",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+	rows, err := readActivityImport("calendar.txt", []byte(data), l)
+	if err != nil {
+		t.Fatalf("readActivityImport failed: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("Expected 3 rows, got %+v", rows)
+	}
+
+	hike := rows[0]
+	london, _ := time.LoadLocation("Europe/London")
+	if hike.Line != 3 || hike.Values[workplanUpdateEventCallbackOptionName] != "Hike, long" ||
+		hike.Values[workplanUpdateEventCallbackOptionCommittee] != "PEAK" ||
+		hike.Values[workplanUpdateEventCallbackOptionLead] != "Alice Wong" ||
+		hike.Values[workplanUpdateEventCallbackOptionDescription] != "Bring water\nand snacks" ||
+		!hike.StartedAt.Equal(time.Date(2030, 5, 1, 10, 0, 0, 0, london)) ||
+		hike.EndsAt == nil || !hike.EndsAt.Equal(time.Date(2030, 5, 1, 13, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected first row %+v", hike)
+	}
+	fair := rows[1]
+	if fair.Values[workplanUpdateEventCallbackOptionLead] != "bob@example.com" || !fair.StartedAt.Equal(time.Date(2030, 5, 3, 0, 0, 0, 0, hk)) {
+		t.Errorf("Unexpected second row %+v", fair)
+	}
+	if rows[2].Err == nil || !strings.Contains(rows[2].Err.Error(), `invalid time "tomorrow"`) {
+		t.Errorf("Expected an invalid time error, got %v", rows[2].Err)
+	}
+}
@@ -0,0 +1,680 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	apiPathPrefix    = "/api/v1/"
+	apiCreatedBy     = "api"
+	apiMaxBodyBytes  = 1 << 20
+	apiDateLayout    = time.DateOnly
+	defaultAPILimit  = 100
+	optionsSeparator = ";"
+)
+
+// API serves the events, votes and activities as JSON to authenticated clients.
+// Changes to events re-render their poll messages in Telegram
+type API struct {
+	events       EventRepository
+	activities   ActivityRepository
+	orgs         OrgRepository
+	chatSettings ChatSettingsRepository
+	notifier     *LeadNotifier
+	messenger    Messenger
+	tokens       []string
+	// location interprets dates without a time in query parameters
+	location *time.Location
+}
+
+func NewAPI(events EventRepository, activities ActivityRepository, orgs OrgRepository, chatSettings ChatSettingsRepository, notifier *LeadNotifier,
+	messenger Messenger, tokens []string, location *time.Location) *API {
+	return &API{events: events, activities: activities, orgs: orgs, chatSettings: chatSettings, notifier: notifier, messenger: messenger, tokens: tokens, location: location}
+}
+
+// EventJSON is an event as sent and received by the API
+type EventJSON struct {
+	ID          int64      `json:"id"`
+	Description string     `json:"description"`
+	Options     []string   `json:"options"`
+	ChatID      int64      `json:"chat_id"`
+	MessageID   int        `json:"message_id"`
+	CreatedBy   string     `json:"created_by"`
+	CreatedByID int64      `json:"created_by_id"`
+	StartedAt   *time.Time `json:"started_at"`
+	ActivityID  int64      `json:"activity_id,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// VoteJSON is a vote of a user for an option
+type VoteJSON struct {
+	User   string `json:"user"`
+	UserID int64  `json:"user_id"`
+	Option string `json:"option"`
+}
+
+// ActivityJSON is an activity as sent and received by the API.
+// The user IDs of the leads are set by the bot from mentions and ignored in requests
+type ActivityJSON struct {
+	ID          int64          `json:"id"`
+	Name        string         `json:"name"`
+	Org         Org            `json:"org"`
+	Lead        string         `json:"lead"`
+	CoLeads     []string       `json:"co_leads"`
+	LeadID      int64          `json:"lead_id"`
+	CoLeadIDs   []int64        `json:"co_lead_ids"`
+	StartedAt   time.Time      `json:"started_at"`
+	EndsAt      *time.Time     `json:"ends_at"`
+	Location    string         `json:"location"`
+	Description string         `json:"description"`
+	Status      ActivityStatus `json:"status"`
+	CreatedBy   string         `json:"created_by"`
+	CreatedByID int64          `json:"created_by_id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+// eventPatch changes the fields which are set
+type eventPatch struct {
+	Description *string      `json:"description"`
+	Options     *[]string    `json:"options"`
+	StartedAt   optionalTime `json:"started_at"`
+}
+
+// optionalTime tells a missing field from null, which clears the time
+type optionalTime struct {
+	Set  bool
+	Time *time.Time
+}
+
+func (t *optionalTime) UnmarshalJSON(data []byte) error {
+	t.Set = true
+	return json.Unmarshal(data, &t.Time)
+}
+
+type optionJSON struct {
+	Option string `json:"option"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// Handler returns the routes below /api/v1/, which require a bearer token
+func (a *API) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/events", a.handleListEvents)
+	mux.HandleFunc("POST /api/v1/events", a.handleCreateEvent)
+	mux.HandleFunc("GET /api/v1/events/{id}", a.handleGetEvent)
+	mux.HandleFunc("PATCH /api/v1/events/{id}", a.handleUpdateEvent)
+	mux.HandleFunc("DELETE /api/v1/events/{id}", a.handleDeleteEvent)
+	mux.HandleFunc("POST /api/v1/events/{id}/options", a.handleAddOption)
+	mux.HandleFunc("DELETE /api/v1/events/{id}/options/{option}", a.handleDeleteOption)
+	mux.HandleFunc("GET /api/v1/events/{id}/votes", a.handleListVotes)
+	mux.HandleFunc("GET /api/v1/activities", a.handleListActivities)
+	mux.HandleFunc("POST /api/v1/activities", a.handleCreateActivity)
+	mux.HandleFunc("GET /api/v1/activities/{id}", a.handleGetActivity)
+	mux.HandleFunc("PUT /api/v1/activities/{id}", a.handleUpdateActivity)
+	mux.HandleFunc("DELETE /api/v1/activities/{id}", a.handleDeleteActivity)
+	return a.authenticate(mux)
+}
+
+func (a *API) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !ok || !a.validToken(token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeAPIError(w, req, http.StatusUnauthorized, "missing or invalid token")
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+func (a *API) validToken(token string) bool {
+	valid := false
+	for _, t := range a.tokens {
+		// compare all tokens to not leak which one matched through timing
+		if t != "" && subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+func (a *API) handleListEvents(w http.ResponseWriter, req *http.Request) {
+	limit := defaultAPILimit
+	if s := req.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			writeAPIError(w, req, http.StatusBadRequest, "limit must be a positive number")
+			return
+		}
+		limit = n
+	}
+	events, err := a.events.ListEvents(limit)
+	if err != nil {
+		writeAPIServerError(w, req, "error listing events", err)
+		return
+	}
+	result := make([]EventJSON, 0, len(events))
+	for _, event := range events {
+		result = append(result, newEventJSON(event))
+	}
+	writeJSON(w, req, http.StatusOK, result)
+}
+
+func (a *API) handleCreateEvent(w http.ResponseWriter, req *http.Request) {
+	var patch eventPatch
+	if !decodeJSON(w, req, &patch) {
+		return
+	}
+	if patch.Description == nil || patch.Options == nil {
+		writeAPIError(w, req, http.StatusBadRequest, "description and options are required")
+		return
+	}
+	event := &Event{CreatedBy: apiCreatedBy}
+	if err := patch.apply(event); err != nil {
+		writeAPIError(w, req, http.StatusBadRequest, err.Error())
+		return
+	}
+	id, err := a.events.SaveEvent(event)
+	if err != nil {
+		writeAPIServerError(w, req, "error saving event", err)
+		return
+	}
+	event, err = a.events.GetEventByID(id)
+	if err != nil {
+		writeAPIServerError(w, req, "error getting event", err)
+		return
+	}
+	slog.InfoContext(req.Context(), "event created through api", "event_id", id)
+	writeJSON(w, req, http.StatusCreated, newEventJSON(event))
+}
+
+func (a *API) handleGetEvent(w http.ResponseWriter, req *http.Request) {
+	event, ok := a.getEvent(w, req)
+	if !ok {
+		return
+	}
+	writeJSON(w, req, http.StatusOK, newEventJSON(event))
+}
+
+func (a *API) handleUpdateEvent(w http.ResponseWriter, req *http.Request) {
+	event, ok := a.getEvent(w, req)
+	if !ok {
+		return
+	}
+	var patch eventPatch
+	if !decodeJSON(w, req, &patch) {
+		return
+	}
+	if err := patch.apply(event); err != nil {
+		writeAPIError(w, req, http.StatusBadRequest, err.Error())
+		return
+	}
+	a.saveEvent(w, req, event)
+}
+
+func (a *API) handleDeleteEvent(w http.ResponseWriter, req *http.Request) {
+	event, ok := a.getEvent(w, req)
+	if !ok {
+		return
+	}
+	if _, err := a.events.DeleteEvent(event.ID); err != nil {
+		writeAPIServerError(w, req, "error deleting event", err)
+		return
+	}
+	slog.InfoContext(req.Context(), "event deleted through api", "event_id", event.ID)
+	l := a.chatSettings.GetLocalizer(req.Context(), event.ChatID)
+	if err := closePollMessage(req.Context(), a.messenger, l, event); err != nil {
+		slog.ErrorContext(req.Context(), "error closing poll message", "event_id", event.ID, "err", err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (a *API) handleAddOption(w http.ResponseWriter, req *http.Request) {
+	event, ok := a.getEvent(w, req)
+	if !ok {
+		return
+	}
+	var input optionJSON
+	if !decodeJSON(w, req, &input) {
+		return
+	}
+	options := append(slices.Clone(event.Options), input.Option)
+	if err := (&eventPatch{Options: &options}).apply(event); err != nil {
+		writeAPIError(w, req, http.StatusBadRequest, err.Error())
+		return
+	}
+	a.saveEvent(w, req, event)
+}
+
+func (a *API) handleDeleteOption(w http.ResponseWriter, req *http.Request) {
+	event, ok := a.getEvent(w, req)
+	if !ok {
+		return
+	}
+	option := req.PathValue("option")
+	if !slices.Contains(event.Options, option) {
+		writeAPIError(w, req, http.StatusNotFound, fmt.Sprintf("event has no option %q", option))
+		return
+	}
+	if len(event.Options) == 1 {
+		writeAPIError(w, req, http.StatusBadRequest, "the last option cannot be deleted")
+		return
+	}
+	event.Options = deleteElementFromStrSlice(event.Options, option)
+	a.saveEvent(w, req, event)
+}
+
+func (a *API) handleListVotes(w http.ResponseWriter, req *http.Request) {
+	event, ok := a.getEvent(w, req)
+	if !ok {
+		return
+	}
+	users, err := a.events.GetEventUsers(event.ID)
+	if err != nil {
+		writeAPIServerError(w, req, "error getting votes", err)
+		return
+	}
+	votes := make([]VoteJSON, 0, len(users))
+	for _, user := range users {
+		votes = append(votes, VoteJSON{User: user.User, UserID: user.UserID, Option: user.Option})
+	}
+	writeJSON(w, req, http.StatusOK, votes)
+}
+
+// getEvent loads the event in the path or writes the error response
+func (a *API) getEvent(w http.ResponseWriter, req *http.Request) (*Event, bool) {
+	id, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil {
+		writeAPIError(w, req, http.StatusBadRequest, "invalid event id")
+		return nil, false
+	}
+	event, err := a.events.GetEventByID(id)
+	if err == sql.ErrNoRows {
+		writeAPIError(w, req, http.StatusNotFound, "event not found")
+		return nil, false
+	}
+	if err != nil {
+		writeAPIServerError(w, req, "error getting event", err)
+		return nil, false
+	}
+	return event, true
+}
+
+// saveEvent updates the event, re-renders its poll message and responds with it
+func (a *API) saveEvent(w http.ResponseWriter, req *http.Request, event *Event) {
+	ctx := req.Context()
+	if err := a.events.UpdateEvent(event); err != nil {
+		writeAPIServerError(w, req, "error updating event", err)
+		return
+	}
+	event, err := a.events.GetEventByID(event.ID)
+	if err != nil {
+		writeAPIServerError(w, req, "error getting event", err)
+		return
+	}
+	slog.InfoContext(ctx, "event updated through api", "event_id", event.ID)
+	l := a.chatSettings.GetLocalizer(ctx, event.ChatID)
+	if err := refreshPollMessage(ctx, a.messenger, a.events, l, event); err != nil {
+		slog.ErrorContext(ctx, "error refreshing poll message", "event_id", event.ID, "err", err)
+	}
+	writeJSON(w, req, http.StatusOK, newEventJSON(event))
+}
+
+// apply validates the patch and sets its fields on the event
+func (p *eventPatch) apply(event *Event) error {
+	if p.Description != nil {
+		description := strings.TrimSpace(*p.Description)
+		if description == "" {
+			return errors.New("description must not be empty")
+		}
+		event.Description = description
+	}
+	if p.Options != nil {
+		var options []string
+		for _, option := range *p.Options {
+			option = strings.TrimSpace(option)
+			switch {
+			case option == "":
+				return errors.New("options must not be empty")
+			case strings.Contains(option, optionsSeparator):
+				return fmt.Errorf("options must not contain %q", optionsSeparator)
+			case slices.Contains(options, option):
+				return fmt.Errorf("duplicate option %q", option)
+			}
+			options = append(options, option)
+		}
+		if len(options) == 0 {
+			return errors.New("at least one option is required")
+		}
+		event.Options = options
+	}
+	if p.StartedAt.Set {
+		event.StartedAt = p.StartedAt.Time
+	}
+	return nil
+}
+
+func newEventJSON(event *Event) EventJSON {
+	options := event.Options
+	if options == nil {
+		options = []string{}
+	}
+	return EventJSON{
+		ID:          event.ID,
+		Description: event.Description,
+		Options:     options,
+		ChatID:      event.ChatID,
+		MessageID:   event.MessageID,
+		CreatedBy:   event.CreatedBy,
+		CreatedByID: event.CreatedByID,
+		StartedAt:   event.StartedAt,
+		ActivityID:  event.ActivityID,
+		CreatedAt:   event.CreatedAt,
+		UpdatedAt:   event.UpdatedAt,
+	}
+}
+
+// handleListActivities returns the activities, optionally starting within
+// from and to (RFC 3339 or YYYY-MM-DD) and of one org
+func (a *API) handleListActivities(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	from, err := a.parseQueryTime(query.Get("from"), false)
+	if err != nil {
+		writeAPIError(w, req, http.StatusBadRequest, "invalid from: "+err.Error())
+		return
+	}
+	to, err := a.parseQueryTime(query.Get("to"), true)
+	if err != nil {
+		writeAPIError(w, req, http.StatusBadRequest, "invalid to: "+err.Error())
+		return
+	}
+	var org Org
+	if s := query.Get("org"); s != "" {
+		orgs, ok := a.listOrgs(w, req)
+		if !ok {
+			return
+		}
+		found, ok := findOrg(orgs, s)
+		if !ok {
+			writeAPIError(w, req, http.StatusBadRequest, fmt.Sprintf("unknown org %q", s))
+			return
+		}
+		org = found.Name
+	}
+
+	var activities []Activity
+	if from.IsZero() && to.IsZero() {
+		activities, err = a.activities.GetAll()
+	} else {
+		if to.IsZero() {
+			to = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+		}
+		activities, err = a.activities.GetByDuration(from, to)
+	}
+	if err != nil {
+		writeAPIServerError(w, req, "error getting activities", err)
+		return
+	}
+	result := make([]ActivityJSON, 0, len(activities))
+	for _, activity := range activities {
+		if org != "" && activity.Org != org {
+			continue
+		}
+		result = append(result, newActivityJSON(&activity))
+	}
+	writeJSON(w, req, http.StatusOK, result)
+}
+
+// parseQueryTime parses a time or a date, which stands for the start of the day
+// or the end of the day if endOfDay is set
+func (a *API) parseQueryTime(s string, endOfDay bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	day, err := time.ParseInLocation(apiDateLayout, s, a.location)
+	if err != nil {
+		return time.Time{}, errors.New("expected RFC 3339 or YYYY-MM-DD")
+	}
+	if endOfDay {
+		return day.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	return day, nil
+}
+
+func (a *API) handleCreateActivity(w http.ResponseWriter, req *http.Request) {
+	var input ActivityJSON
+	if !decodeJSON(w, req, &input) {
+		return
+	}
+	orgs, ok := a.listOrgs(w, req)
+	if !ok {
+		return
+	}
+	activity := &Activity{CreatedBy: apiCreatedBy}
+	if err := input.apply(activity, orgs); err != nil {
+		writeAPIError(w, req, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err := a.activities.Save(activity); err != nil {
+		writeAPIServerError(w, req, "error saving activity", err)
+		return
+	}
+	slog.InfoContext(req.Context(), "activity created through api", "activity_id", activity.ID)
+	a.notifier.notifyChange(req.Context(), a.messenger, nil, *activity, 0)
+	a.writeActivity(w, req, http.StatusCreated, activity.ID)
+}
+
+func (a *API) handleGetActivity(w http.ResponseWriter, req *http.Request) {
+	activity, ok := a.getActivity(w, req)
+	if !ok {
+		return
+	}
+	writeJSON(w, req, http.StatusOK, newActivityJSON(activity))
+}
+
+func (a *API) handleUpdateActivity(w http.ResponseWriter, req *http.Request) {
+	activity, ok := a.getActivity(w, req)
+	if !ok {
+		return
+	}
+	var input ActivityJSON
+	if !decodeJSON(w, req, &input) {
+		return
+	}
+	orgs, ok := a.listOrgs(w, req)
+	if !ok {
+		return
+	}
+	prev := *activity
+	if err := input.apply(activity, orgs); err != nil {
+		writeAPIError(w, req, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := a.activities.Update(activity); err != nil {
+		writeAPIServerError(w, req, "error updating activity", err)
+		return
+	}
+	slog.InfoContext(req.Context(), "activity updated through api", "activity_id", activity.ID)
+	a.notifier.notifyChange(req.Context(), a.messenger, &prev, *activity, 0)
+	a.writeActivity(w, req, http.StatusOK, activity.ID)
+}
+
+func (a *API) handleDeleteActivity(w http.ResponseWriter, req *http.Request) {
+	activity, ok := a.getActivity(w, req)
+	if !ok {
+		return
+	}
+	if _, err := a.activities.Delete(activity.ID); err != nil {
+		writeAPIServerError(w, req, "error deleting activity", err)
+		return
+	}
+	slog.InfoContext(req.Context(), "activity deleted through api", "activity_id", activity.ID)
+	a.notifier.notifyDeleted(req.Context(), a.messenger, *activity, 0)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getActivity loads the activity in the path or writes the error response
+func (a *API) getActivity(w http.ResponseWriter, req *http.Request) (*Activity, bool) {
+	id, err := strconv.ParseInt(req.PathValue("id"), 10, 64)
+	if err != nil {
+		writeAPIError(w, req, http.StatusBadRequest, "invalid activity id")
+		return nil, false
+	}
+	activity, err := a.activities.GetByID(id)
+	if err == sql.ErrNoRows {
+		writeAPIError(w, req, http.StatusNotFound, "activity not found")
+		return nil, false
+	}
+	if err != nil {
+		writeAPIServerError(w, req, "error getting activity", err)
+		return nil, false
+	}
+	return activity, true
+}
+
+// writeActivity responds with the activity as stored
+func (a *API) writeActivity(w http.ResponseWriter, req *http.Request, status int, id int64) {
+	activity, err := a.activities.GetByID(id)
+	if err != nil {
+		writeAPIServerError(w, req, "error getting activity", err)
+		return
+	}
+	writeJSON(w, req, status, newActivityJSON(activity))
+}
+
+func (a *API) listOrgs(w http.ResponseWriter, req *http.Request) ([]Organization, bool) {
+	orgs, err := a.orgs.ListOrgs()
+	if err != nil {
+		writeAPIServerError(w, req, "error listing orgs", err)
+		return nil, false
+	}
+	return orgs, true
+}
+
+// apply validates the input and sets the editable fields on the activity.
+// An archived org is only accepted if the activity already belongs to it.
+// The status of an existing activity can only follow its lifecycle, an empty one is left unchanged.
+// Leads keep their user IDs as long as their names are unchanged
+func (input *ActivityJSON) apply(activity *Activity, orgs []Organization) error {
+	name := strings.TrimSpace(input.Name)
+	lead := strings.TrimSpace(input.Lead)
+	org, ok := findOrg(orgs, string(input.Org))
+	status := activity.status()
+	if input.Status != "" {
+		var known bool
+		if status, known = parseActivityStatus(string(input.Status)); !known {
+			return fmt.Errorf("unknown status %q", input.Status)
+		}
+		if activity.ID != 0 && status != activity.status() && !activity.status().canChangeTo(status) {
+			return fmt.Errorf("status cannot change from %s to %s", activity.status(), status)
+		}
+	}
+	switch {
+	case name == "":
+		return errors.New("name is required")
+	case !ok:
+		return fmt.Errorf("unknown org %q", input.Org)
+	case !org.Active && org.Name != activity.Org:
+		return fmt.Errorf("org %q is archived", org.Name)
+	case lead == "":
+		return errors.New("lead is required")
+	case input.StartedAt.IsZero():
+		return errors.New("started_at is required")
+	case input.EndsAt != nil && !input.EndsAt.After(input.StartedAt):
+		return errors.New("ends_at must be after started_at")
+	}
+	coLeads := []string{}
+	for _, coLead := range input.CoLeads {
+		if coLead = strings.TrimSpace(coLead); coLead != "" {
+			coLeads = append(coLeads, coLead)
+		}
+	}
+	prev := *activity
+	activity.Name = name
+	activity.Org = org.Name
+	activity.Lead = lead
+	activity.CoLeads = coLeads
+	activity.keepLeadIDs(prev)
+	activity.StartedAt = input.StartedAt
+	activity.EndsAt = input.EndsAt
+	activity.Location = strings.TrimSpace(input.Location)
+	activity.Description = strings.TrimSpace(input.Description)
+	activity.Status = status
+	return nil
+}
+
+func newActivityJSON(activity *Activity) ActivityJSON {
+	coLeads := activity.CoLeads
+	if coLeads == nil {
+		coLeads = []string{}
+	}
+	coLeadIDs := make([]int64, len(coLeads))
+	for i := range coLeads {
+		coLeadIDs[i] = activity.coLeadID(i)
+	}
+	return ActivityJSON{
+		ID:          activity.ID,
+		Name:        activity.Name,
+		Org:         activity.Org,
+		Lead:        activity.Lead,
+		CoLeads:     coLeads,
+		LeadID:      activity.LeadID,
+		CoLeadIDs:   coLeadIDs,
+		StartedAt:   activity.StartedAt,
+		EndsAt:      activity.EndsAt,
+		Location:    activity.Location,
+		Description: activity.Description,
+		Status:      activity.status(),
+		CreatedBy:   activity.CreatedBy,
+		CreatedByID: activity.CreatedByID,
+		CreatedAt:   activity.CreatedAt,
+		UpdatedAt:   activity.UpdatedAt,
+	}
+}
+
+// decodeJSON reads the request body into v or writes the error response
+func decodeJSON(w http.ResponseWriter, req *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, req.Body, apiMaxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeAPIError(w, req, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, req *http.Request, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.ErrorContext(req.Context(), "error writing api response", "path", req.URL.Path, "err", err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, req *http.Request, status int, message string) {
+	writeJSON(w, req, status, apiError{Error: message})
+}
+
+// writeAPIServerError logs the error and hides its details from the client
+func writeAPIServerError(w http.ResponseWriter, req *http.Request, message string, err error) {
+	slog.ErrorContext(req.Context(), message, "method", req.Method, "path", req.URL.Path, "err", err)
+	writeAPIError(w, req, http.StatusInternalServerError, "internal error")
+}
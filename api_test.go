@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testAPIToken = "secret"
+
+// apiRequest sends a request to the API and decodes the JSON response into out, if not nil
+func apiRequest(t *testing.T, handler http.Handler, method, path, body string, out any) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if out != nil && rec.Code < 300 {
+		if err := json.NewDecoder(rec.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: failed to decode response: %v", method, path, err)
+		}
+	}
+	return rec.Code
+}
+
+func newTestAPI(tb *testBot) http.Handler {
+	return NewAPI(tb.eventDAO, tb.activityDAO, tb.orgDAO, tb.chatSettings, NewLeadNotifier(tb.activityDAO, tb.chatSettings, 2), tb.messenger, []string{testAPIToken}, time.UTC).Handler()
+}
+
+func TestAPIAuthentication(t *testing.T) {
+	handler := newTestAPI(newTestBot(t))
+	for _, header := range []string{"", "Bearer wrong", testAPIToken} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/events", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected %d for Authorization %q, got %d", http.StatusUnauthorized, header, rec.Code)
+		}
+	}
+	if code := apiRequest(t, handler, http.MethodGet, "/api/v1/events", "", nil); code != http.StatusOK {
+		t.Errorf("Expected a valid token to be accepted, got %d", code)
+	}
+}
+
+func TestAPIEvents(t *testing.T) {
+	tb := newTestBot(t)
+	handler := newTestAPI(tb)
+
+	var event EventJSON
+	code := apiRequest(t, handler, http.MethodPost, "/api/v1/events",
+		`{"description": "Board games", "options": ["Yes", "No"], "started_at": "2025-03-08T18:30:00Z"}`, &event)
+	if code != http.StatusCreated || event.ID == 0 || event.Description != "Board games" || event.CreatedBy != apiCreatedBy {
+		t.Fatalf("Expected the event to be created, got %d %+v", code, event)
+	}
+	if code := apiRequest(t, handler, http.MethodPost, "/api/v1/events", `{"description": "No options"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected an event without options to be rejected, got %d", code)
+	}
+	if code := apiRequest(t, handler, http.MethodPost, "/api/v1/events", `{"description": "x", "options": ["a;b"]}`, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected an option with the separator to be rejected, got %d", code)
+	}
+
+	// the poll was sent to a chat, so changes are shown there
+	stored, err := tb.eventDAO.GetEventByID(event.ID)
+	if err != nil {
+		t.Fatalf("GetEventByID failed: %v", err)
+	}
+	stored.updateDetails(testChatID, 100, stored.CreatedBy, stored.CreatedByID)
+	if err := tb.eventDAO.UpdateEvent(stored); err != nil {
+		t.Fatalf("UpdateEvent failed: %v", err)
+	}
+	if err := tb.eventDAO.ToggleEventUser(&EventUser{EventID: event.ID, User: "Bob", UserID: testBob.ID, Option: "Yes"}); err != nil {
+		t.Fatalf("ToggleEventUser failed: %v", err)
+	}
+	path := "/api/v1/events/" + strconv.FormatInt(event.ID, 10)
+
+	code = apiRequest(t, handler, http.MethodPatch, path, `{"description": "Chess", "started_at": null}`, &event)
+	if code != http.StatusOK || event.Description != "Chess" || event.StartedAt != nil || len(event.Options) != 2 {
+		t.Fatalf("Expected the event to be updated, got %d %+v", code, event)
+	}
+	code = apiRequest(t, handler, http.MethodPost, path+"/options", `{"option": "Maybe"}`, &event)
+	if code != http.StatusOK || strings.Join(event.Options, ",") != "Yes,No,Maybe" {
+		t.Fatalf("Expected the option to be added, got %d %+v", code, event)
+	}
+	if code := apiRequest(t, handler, http.MethodPost, path+"/options", `{"option": "Maybe"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected a duplicate option to be rejected, got %d", code)
+	}
+	code = apiRequest(t, handler, http.MethodDelete, path+"/options/No", "", &event)
+	if code != http.StatusOK || strings.Join(event.Options, ",") != "Yes,Maybe" {
+		t.Fatalf("Expected the option to be deleted, got %d %+v", code, event)
+	}
+	if code := apiRequest(t, handler, http.MethodDelete, path+"/options/No", "", nil); code != http.StatusNotFound {
+		t.Errorf("Expected a missing option to be reported, got %d", code)
+	}
+
+	var votes []VoteJSON
+	code = apiRequest(t, handler, http.MethodGet, path+"/votes", "", &votes)
+	if code != http.StatusOK || len(votes) != 1 || votes[0].User != "Bob" || votes[0].Option != "Yes" {
+		t.Errorf("Expected Bob's vote, got %d %+v", code, votes)
+	}
+
+	edits := tb.messenger.texts()
+	if len(edits) != 3 {
+		t.Fatalf("Expected the poll message to be edited after each change, got %q", edits)
+	}
+	assertTexts(t, edits[2:], []string{"Chess"})
+	if !strings.Contains(edits[2], "Maybe") || strings.Contains(edits[2], "*No*") || !strings.Contains(edits[2], "Bob") {
+		t.Errorf("Expected the poll to show the current options and votes, got %q", edits[2])
+	}
+
+	if code := apiRequest(t, handler, http.MethodDelete, path, "", nil); code != http.StatusNoContent {
+		t.Fatalf("Expected the event to be deleted, got %d", code)
+	}
+	if code := apiRequest(t, handler, http.MethodGet, path, "", nil); code != http.StatusNotFound {
+		t.Errorf("Expected a deleted event to be missing, got %d", code)
+	}
+	assertTexts(t, tb.messenger.texts()[3:], []string{"This poll has been deleted."})
+}
+
+func TestAPIActivities(t *testing.T) {
+	tb := newTestBot(t)
+	handler := newTestAPI(tb)
+
+	bodies := []string{
+		`{"name": "Hike", "org": "peak", "lead": "Alice", "co_leads": ["Bob"], "started_at": "2025-03-08T09:00:00Z"}`,
+		`{"name": "Quiz night", "org": "CC", "lead": "Carol", "started_at": "2025-03-20T19:00:00Z"}`,
+		`{"name": "Climbing", "org": "PEAK", "lead": "Alice", "started_at": "2025-04-02T18:00:00Z"}`,
+	}
+	var ids []int64
+	for _, body := range bodies {
+		var activity ActivityJSON
+		if code := apiRequest(t, handler, http.MethodPost, "/api/v1/activities", body, &activity); code != http.StatusCreated {
+			t.Fatalf("Expected the activity to be created, got %d", code)
+		}
+		ids = append(ids, activity.ID)
+	}
+	if code := apiRequest(t, handler, http.MethodPost, "/api/v1/activities", `{"name": "x", "org": "NOPE", "lead": "a", "started_at": "2025-03-08T09:00:00Z"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown org to be rejected, got %d", code)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"Hike", "Quiz night", "Climbing"}},
+		{"?from=2025-03-01&to=2025-03-31", []string{"Hike", "Quiz night"}},
+		{"?from=2025-03-10", []string{"Quiz night", "Climbing"}},
+		{"?org=peak", []string{"Hike", "Climbing"}},
+		{"?org=PEAK&to=2025-03-31T23:59:59Z", []string{"Hike"}},
+	}
+	for _, tt := range tests {
+		var activities []ActivityJSON
+		if code := apiRequest(t, handler, http.MethodGet, "/api/v1/activities"+tt.query, "", &activities); code != http.StatusOK {
+			t.Fatalf("GET %s: got %d", tt.query, code)
+		}
+		var names []string
+		for _, a := range activities {
+			names = append(names, a.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("GET %s: expected %v, got %v", tt.query, tt.want, names)
+		}
+	}
+	if code := apiRequest(t, handler, http.MethodGet, "/api/v1/activities?from=tomorrow", "", nil); code != http.StatusBadRequest {
+		t.Errorf("Expected an invalid date to be rejected, got %d", code)
+	}
+
+	path := "/api/v1/activities/" + strconv.FormatInt(ids[0], 10)
+	var activity ActivityJSON
+	code := apiRequest(t, handler, http.MethodPut, path, `{"name": "Long hike", "org": "PEAK", "lead": "Bob", "started_at": "2025-03-09T09:00:00Z"}`, &activity)
+	if code != http.StatusOK || activity.Name != "Long hike" || activity.Lead != "Bob" || len(activity.CoLeads) != 0 {
+		t.Fatalf("Expected the activity to be updated, got %d %+v", code, activity)
+	}
+	stored, err := tb.activityDAO.GetByID(ids[0])
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	stored.LeadID = testBob.ID
+	if err := tb.activityDAO.Update(stored); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	sent := len(tb.messenger.texts())
+	if code := apiRequest(t, handler, http.MethodDelete, path, "", nil); code != http.StatusNoContent {
+		t.Fatalf("Expected the activity to be deleted, got %d", code)
+	}
+	if texts, to := tb.messenger.texts()[sent:], tb.messenger.sentTo()[sent:]; len(texts) != 1 || to[0] != testBob.ID ||
+		!strings.HasPrefix(texts[0], "An activity you are the lead of has been deleted") {
+		t.Errorf("Expected the lead to be told about the deletion, got %q to %v", texts, to)
+	}
+	if code := apiRequest(t, handler, http.MethodGet, path, "", nil); code != http.StatusNotFound {
+		t.Errorf("Expected a deleted activity to be missing, got %d", code)
+	}
+}
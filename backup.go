@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+const (
+	backupFilePrefix   = "events-"
+	backupFileExt      = ".db"
+	backupTimeLayout   = "20060102-150405"
+	defaultBackupHours = 24
+)
+
+var errBackupsShared = errors.New("backups of the shared postgres database are not supported, use pg_dump")
+
+// Backups takes consistent snapshots of the database and applies the retention policy
+type Backups struct {
+	db     *sql.DB
+	config BackupConfig
+	// shared is set when the data is kept in PostgreSQL, leaving nothing in db worth a snapshot
+	shared bool
+	// location decides which day a snapshot belongs to for KeepDaily
+	location *time.Location
+	// mu serializes scheduled and on-demand snapshots
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+func NewBackups(repos *Repositories, config BackupConfig, location *time.Location) *Backups {
+	return &Backups{db: repos.sqlite, config: config, shared: repos.postgres != nil, location: location, now: time.Now}
+}
+
+func (s *Backups) enabled() bool {
+	return s.config.Dir != "" && !s.shared
+}
+
+// Snapshot writes a timestamped copy of the database to the backup directory,
+// deletes the snapshots no longer kept and returns the path of the new one
+func (s *Backups) Snapshot(ctx context.Context) (string, error) {
+	if s.shared {
+		return "", errBackupsShared
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.config.Dir, 0o750); err != nil {
+		return "", err
+	}
+	path := filepath.Join(s.config.Dir, backupFilePrefix+s.now().UTC().Format(backupTimeLayout)+backupFileExt)
+	if err := BackupDB(ctx, s.db, path); err != nil {
+		return "", err
+	}
+	lastBackupTimestamp.SetToCurrentTime()
+	if err := s.prune(); err != nil {
+		slog.ErrorContext(ctx, "error deleting old backups", "dir", s.config.Dir, "err", err)
+	}
+	return path, nil
+}
+
+type snapshotFile struct {
+	name    string
+	takenAt time.Time
+}
+
+// prune deletes the snapshots which are neither among the KeepLast most recent
+// nor the last of one of the KeepDaily most recent days
+func (s *Backups) prune() error {
+	if s.config.KeepLast <= 0 && s.config.KeepDaily <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(s.config.Dir)
+	if err != nil {
+		return err
+	}
+	var snapshots []snapshotFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, backupFilePrefix) || !strings.HasSuffix(name, backupFileExt) {
+			continue
+		}
+		takenAt, err := time.Parse(backupTimeLayout, strings.TrimSuffix(strings.TrimPrefix(name, backupFilePrefix), backupFileExt))
+		if err != nil {
+			// not ours
+			continue
+		}
+		snapshots = append(snapshots, snapshotFile{name: name, takenAt: takenAt})
+	}
+	// newest first
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].takenAt.After(snapshots[j].takenAt) })
+
+	keep := make(map[string]bool)
+	for i := 0; i < len(snapshots) && i < s.config.KeepLast; i++ {
+		keep[snapshots[i].name] = true
+	}
+	days := make(map[string]bool)
+	for _, snapshot := range snapshots {
+		day := snapshot.takenAt.In(s.location).Format(time.DateOnly)
+		if days[day] {
+			continue
+		}
+		if len(days) == s.config.KeepDaily {
+			break
+		}
+		days[day] = true
+		keep[snapshot.name] = true
+	}
+
+	for _, snapshot := range snapshots {
+		if keep[snapshot.name] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.config.Dir, snapshot.name)); err != nil {
+			return err
+		}
+		slog.Info("deleted old backup", "file", snapshot.name)
+	}
+	return nil
+}
+
+// run takes a snapshot every IntervalHours until ctx is done
+func (s *Backups) run(ctx context.Context) {
+	hours := s.config.IntervalHours
+	if hours <= 0 {
+		hours = defaultBackupHours
+	}
+	ticker := time.NewTicker(time.Duration(hours) * time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			path, err := s.Snapshot(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "error backing up database", "err", err)
+				continue
+			}
+			slog.InfoContext(ctx, "database backed up", "file", path)
+		}
+	}
+}
+
+// BackupHandler handles the /backup admin command
+type BackupHandler struct {
+	backups      *Backups
+	config       *Config
+	chatSettings ChatSettingsRepository
+}
+
+func NewBackupHandler(backups *Backups, config *Config, chatSettings ChatSettingsRepository) *BackupHandler {
+	return &BackupHandler{backups: backups, config: config, chatSettings: chatSettings}
+}
+
+// handleBackup takes a snapshot and sends it to the admin chat
+func (h *BackupHandler) handleBackup(ctx context.Context, b Messenger, update *models.Update) {
+	chatID := update.Message.Chat.ID
+	msgThreadID := update.Message.MessageThreadID
+	l := h.chatSettings.GetLocalizer(ctx, chatID)
+	reply := func(key MessageKey) {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: msgThreadID,
+			Text:            l.T(key),
+		})
+	}
+
+	if !h.config.isAdmin(update.Message.From) {
+		slog.WarnContext(ctx, "backup requested by non-admin")
+		reply(msgNotAdmin)
+		return
+	}
+	if h.backups.shared {
+		reply(msgBackupShared)
+		return
+	}
+	if !h.backups.enabled() {
+		reply(msgBackupDisabled)
+		return
+	}
+
+	path, err := h.backups.Snapshot(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "error backing up database", "err", err)
+		reply(msgBackupFailed)
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		slog.ErrorContext(ctx, "error opening backup", "file", path, "err", err)
+		reply(msgBackupFailed)
+		return
+	}
+	defer f.Close()
+
+	targetChatID, targetThreadID := chatID, msgThreadID
+	if h.config.AdminChatID != 0 {
+		targetChatID, targetThreadID = h.config.AdminChatID, 0
+	}
+	_, err = b.SendDocument(ctx, &bot.SendDocumentParams{
+		ChatID:          targetChatID,
+		MessageThreadID: targetThreadID,
+		Document:        &models.InputFileUpload{Filename: filepath.Base(path), Data: f},
+		Caption:         l.T(msgBackupCaption, l.FormatTime(h.backups.now())),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "error sending backup", "file", path, "err", err)
+		reply(msgBackupFailed)
+		return
+	}
+	slog.InfoContext(ctx, "backup sent", "file", path, "target_chat_id", targetChatID)
+	if targetChatID != chatID {
+		reply(msgBackupSent)
+	}
+}
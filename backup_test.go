@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+)
+
+func TestBackupsPrune(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{
+		"events-20250301-020000.db",
+		"events-20250302-020000.db",
+		"events-20250302-140000.db",
+		"events-20250303-020000.db",
+		"events-20250303-140000.db",
+		"events-20250304-020000.db",
+		"events-20250304-140000.db",
+		"events-20250304-200000.db",
+		"events-manual.db", // not a snapshot name, left alone
+		"notes.txt",
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups := NewBackups(&Repositories{}, BackupConfig{Dir: dir, KeepLast: 2, KeepDaily: 3}, time.UTC)
+	if err := backups.prune(); err != nil {
+		t.Fatalf("prune failed: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+	want := []string{
+		"events-20250302-140000.db", // last of the 3rd most recent day
+		"events-20250303-140000.db", // last of the 2nd most recent day
+		"events-20250304-140000.db", // 2nd most recent
+		"events-20250304-200000.db", // most recent
+		"events-manual.db",
+		"notes.txt",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %v to be kept, got %v", want, got)
+	}
+}
+
+func TestBackupsSnapshot(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "events.db")
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE items (name TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO items VALUES ('backed up')`); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "backups")
+	backups := NewBackups(&Repositories{sqlite: db}, BackupConfig{Dir: dir, KeepLast: 1}, time.UTC)
+	now := time.Date(2025, 3, 4, 20, 0, 0, 0, time.UTC)
+	backups.now = func() time.Time { return now }
+
+	first, err := backups.Snapshot(context.Background())
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if filepath.Base(first) != "events-20250304-200000.db" {
+		t.Errorf("Unexpected snapshot name %s", first)
+	}
+	now = now.Add(time.Hour)
+	second, err := backups.Snapshot(context.Background())
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if _, err := os.Stat(first); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be deleted by KeepLast, got %v", first, err)
+	}
+
+	snapshot, err := sql.Open("sqlite", second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snapshot.Close()
+	var name string
+	if err := snapshot.QueryRow(`SELECT name FROM items`).Scan(&name); err != nil || name != "backed up" {
+		t.Errorf("Expected the snapshot to contain the data, got %q (%v)", name, err)
+	}
+}
+
+func TestBackupHandler(t *testing.T) {
+	en := NewLocalizer(langEnglish, time.UTC)
+	tests := []struct {
+		name         string
+		dir          bool
+		shared       bool
+		adminChatID  int64
+		from         *models.User
+		wantTexts    []string
+		wantDocument any
+	}{
+		{name: "not an admin", dir: true, from: testBob, wantTexts: []string{en.T(msgNotAdmin)}},
+		{name: "backups not configured", from: testAlice, wantTexts: []string{en.T(msgBackupDisabled)}},
+		{name: "data kept in postgres", dir: true, shared: true, from: testAlice, wantTexts: []string{en.T(msgBackupShared)}},
+		{name: "send to the same chat", dir: true, from: testAlice, wantDocument: testChatID},
+		{name: "send to the admin chat", dir: true, adminChatID: 42, from: testAlice, wantTexts: []string{en.T(msgBackupSent)}, wantDocument: int64(42)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := newTestBot(t)
+			AppConfig.Admins = []int64{testAlice.ID}
+			AppConfig.AdminChatID = tt.adminChatID
+			config := BackupConfig{}
+			if tt.dir {
+				config.Dir = t.TempDir()
+			}
+			repos := &Repositories{sqlite: tb.db}
+			if tt.shared {
+				// any handle marks the data as kept in postgres
+				repos.postgres = tb.db
+			}
+			backups := NewBackups(repos, config, time.UTC)
+			if tt.shared && backups.enabled() {
+				t.Error("Expected backups to be disabled while the data is kept in postgres")
+			}
+			h := NewBackupHandler(backups, AppConfig, tb.chatSettings)
+
+			step := testStep{func(*testBot) HandlerFunc { return h.handleBackup }, textUpdate(tt.from, "/backup")}
+			assertTexts(t, tb.run(t, []testStep{step}), tt.wantTexts)
+
+			var documents []recordedCall
+			for _, call := range tb.messenger.calls {
+				if call.Method == "sendDocument" {
+					documents = append(documents, call)
+				}
+			}
+			if tt.wantDocument == nil {
+				if len(documents) != 0 {
+					t.Errorf("Expected no document, got %+v", documents)
+				}
+				return
+			}
+			if len(documents) != 1 || documents[0].ChatID != tt.wantDocument || len(documents[0].Document) == 0 {
+				t.Fatalf("Expected a backup sent to %v, got %+v", tt.wantDocument, documents)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+// ChatSettingsDAO provides data access operations for per chat settings
+type ChatSettingsDAO struct {
+	db *sql.DB
+}
+
+// NewChatSettingsDAO creates a new ChatSettingsDAO instance
+func NewChatSettingsDAO(db *sql.DB) *ChatSettingsDAO {
+	return &ChatSettingsDAO{db: db}
+}
+
+// GetLocalizer returns the language and timezone of the chat, falling back to the app defaults
+func (dao *ChatSettingsDAO) GetLocalizer(ctx context.Context, chatID int64) *Localizer {
+	var tzName, language sql.NullString
+	err := dao.db.QueryRowContext(ctx, `SELECT timezone, language FROM chat_settings WHERE chat_id = ?`, chatID).Scan(&tzName, &language)
+	if err != nil && err != sql.ErrNoRows {
+		slog.ErrorContext(ctx, "error getting chat settings", "settings_chat_id", chatID, "err", err)
+	}
+	return chatLocalizer(ctx, chatID, tzName, language)
+}
+
+// chatLocalizer returns the localizer of the stored chat settings, the app defaults replacing missing ones
+func chatLocalizer(ctx context.Context, chatID int64, tzName, language sql.NullString) *Localizer {
+	tz := AppConfig.Timezone
+	if tzName.Valid && tzName.String != "" {
+		if loc, err := time.LoadLocation(tzName.String); err != nil {
+			slog.ErrorContext(ctx, "error loading chat timezone", "settings_chat_id", chatID, "timezone", tzName.String, "err", err)
+		} else {
+			tz = loc
+		}
+	}
+	lang := AppConfig.Language
+	if language.Valid && language.String != "" {
+		lang = language.String
+	}
+	return NewLocalizer(lang, tz)
+}
+
+// SetTimezone stores the timezone of the chat
+func (dao *ChatSettingsDAO) SetTimezone(chatID int64, tz *time.Location) error {
+	query := `INSERT INTO chat_settings (chat_id, timezone, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(chat_id) DO UPDATE SET timezone = excluded.timezone, updated_at = CURRENT_TIMESTAMP`
+	_, err := dao.db.Exec(query, chatID, tz.String())
+	return err
+}
+
+// SetLanguage stores the language of the chat
+func (dao *ChatSettingsDAO) SetLanguage(chatID int64, language string) error {
+	query := `INSERT INTO chat_settings (chat_id, language, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(chat_id) DO UPDATE SET language = excluded.language, updated_at = CURRENT_TIMESTAMP`
+	_, err := dao.db.Exec(query, chatID, language)
+	return err
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// ChatSettingsHandler handles commands changing the settings of a chat
+type ChatSettingsHandler struct {
+	chatSettings ChatSettingsRepository
+}
+
+// NewChatSettingsHandler creates a new ChatSettingsHandler instance
+func NewChatSettingsHandler(chatSettings ChatSettingsRepository) *ChatSettingsHandler {
+	return &ChatSettingsHandler{chatSettings: chatSettings}
+}
+
+// handleSetTimezone sets the timezone of the chat, e.g. /settimezone Europe/Berlin
+func (h *ChatSettingsHandler) handleSetTimezone(ctx context.Context, b Messenger, update *models.Update) {
+	chatID := update.Message.Chat.ID
+	msgThreadID := update.Message.MessageThreadID
+	l := h.chatSettings.GetLocalizer(ctx, chatID)
+
+	tzName := getCommandArgument(update)
+	if tzName == "" {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: msgThreadID,
+			Text:            l.T(msgCurrentTimezone, l.Location.String()),
+		})
+		return
+	}
+
+	// "Local" would be the timezone of the server
+	tz, err := time.LoadLocation(tzName)
+	if err != nil || tzName == "Local" {
+		slog.WarnContext(ctx, "invalid timezone", "timezone", tzName, "err", err)
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: msgThreadID,
+			Text:            l.T(msgUnknownTimezone, tzName),
+		})
+		return
+	}
+
+	if err := h.chatSettings.SetTimezone(chatID, tz); err != nil {
+		slog.ErrorContext(ctx, "error setting chat timezone", "timezone", tzName, "err", err)
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: msgThreadID,
+			Text:            l.T(msgSetTimezoneFailed),
+		})
+		return
+	}
+	l.Location = tz
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          chatID,
+		MessageThreadID: msgThreadID,
+		Text:            l.T(msgTimezoneSet, tz.String(), l.FormatTime(time.Now())),
+	})
+}
+
+// handleSetLanguage sets the language of the chat, e.g. /setlanguage zh
+func (h *ChatSettingsHandler) handleSetLanguage(ctx context.Context, b Messenger, update *models.Update) {
+	chatID := update.Message.Chat.ID
+	msgThreadID := update.Message.MessageThreadID
+	l := h.chatSettings.GetLocalizer(ctx, chatID)
+	available := strings.Join(languageCodes(), ", ")
+
+	language := strings.ToLower(getCommandArgument(update))
+	if language == "" {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: msgThreadID,
+			Text:            l.T(msgCurrentLanguage, l.language().Name, available),
+		})
+		return
+	}
+
+	if _, ok := languages[language]; !ok {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: msgThreadID,
+			Text:            l.T(msgUnknownLanguage, language, available),
+		})
+		return
+	}
+
+	if err := h.chatSettings.SetLanguage(chatID, language); err != nil {
+		slog.ErrorContext(ctx, "error setting chat language", "language", language, "err", err)
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: msgThreadID,
+			Text:            l.T(msgSetLanguageFailed),
+		})
+		return
+	}
+	l = NewLocalizer(language, l.Location)
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          chatID,
+		MessageThreadID: msgThreadID,
+		Text:            l.T(msgLanguageSet, l.language().Name),
+	})
+}
@@ -0,0 +1,567 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const defaultEventListLimit = 20
+
+var (
+	errUsage = errors.New("usage")
+
+	activityCSVHeader = []string{"id", "name", "org", "lead", "co_leads", "started_at", "created_by", "created_by_id",
+		"ends_at", "location", "description", "status"}
+	// activityCSVLegacyColumns is the number of columns exported before activities had an end time,
+	// location, description and status
+	activityCSVLegacyColumns = 8
+)
+
+// cliEnv is what the administrative subcommands work with
+type cliEnv struct {
+	config      *Config
+	db          *sql.DB
+	repos       *Repositories
+	eventDAO    EventRepository
+	activityDAO ActivityRepository
+	orgDAO      OrgRepository
+	stdout      io.Writer
+	stderr      io.Writer
+}
+
+type cliCommand struct {
+	usage       string
+	description string
+	run         func(ctx context.Context, env *cliEnv, args []string) error
+}
+
+var cliCommands = map[string]cliCommand{
+	"migrate":           {"migrate [-dry-run]", "migrate the database to the current schema", runMigrate},
+	"events list":       {"events list [-limit N]", "list the most recent events", runEventsList},
+	"events show":       {"events show <id>", "show an event and its votes", runEventsShow},
+	"events delete":     {"events delete <id>", "delete an event and its votes", runEventsDelete},
+	"activities export": {"activities export [-o file]", "export all activities as CSV", runActivitiesExport},
+	"activities import": {"activities import [-dry-run] <file>", "create or update activities from CSV", runActivitiesImport},
+	"db backup":         {"db backup <file>", "write a consistent copy of the database", runDBBackup},
+	"db check":          {"db check", "check the integrity and schema version of the database", runDBCheck},
+}
+
+// runCLI runs an administrative subcommand against the database at dbPath
+// and returns the process exit code
+func runCLI(ctx context.Context, config *Config, dbPath string, args []string, stdout, stderr io.Writer) int {
+	name, cmd, ok := findCLICommand(args)
+	if !ok {
+		printCLIUsage(stderr)
+		return 2
+	}
+
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		fmt.Fprintln(stderr, "error opening database:", err)
+		return 1
+	}
+	defer db.Close()
+	repos, err := OpenRepositories(config.Database, db)
+	if err != nil {
+		fmt.Fprintln(stderr, "error opening database:", err)
+		return 1
+	}
+	defer repos.Close()
+
+	env := &cliEnv{
+		config:      config,
+		db:          db,
+		repos:       repos,
+		eventDAO:    repos.Events,
+		activityDAO: repos.Activities,
+		orgDAO:      repos.Orgs,
+		stdout:      stdout,
+		stderr:      stderr,
+	}
+	err = cmd.run(ctx, env, args[len(strings.Fields(name)):])
+	if errors.Is(err, errUsage) {
+		fmt.Fprintln(stderr, "usage: eventpoll", cmd.usage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return 1
+	}
+	return 0
+}
+
+func findCLICommand(args []string) (string, cliCommand, bool) {
+	if len(args) >= 2 {
+		if cmd, ok := cliCommands[args[0]+" "+args[1]]; ok {
+			return args[0] + " " + args[1], cmd, true
+		}
+	}
+	if len(args) >= 1 {
+		if cmd, ok := cliCommands[args[0]]; ok {
+			return args[0], cmd, true
+		}
+	}
+	return "", cliCommand{}, false
+}
+
+func printCLIUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: eventpoll [command]")
+	fmt.Fprintln(w, "Without a command the bot is started. Commands:")
+	names := make([]string, 0, len(cliCommands))
+	for name := range cliCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(tw, "  %s\t%s\n", cliCommands[name].usage, cliCommands[name].description)
+	}
+	tw.Flush()
+}
+
+// newFlagSet returns a flag set which reports errors instead of exiting
+func (env *cliEnv) newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(env.stderr)
+	return fs
+}
+
+// checkSchema refuses to work on a database which has not been migrated yet
+func (env *cliEnv) checkSchema() error {
+	plan, err := PlanMigrations(env.db)
+	if err != nil {
+		return err
+	}
+	if pending := len(plan.Baseline) + len(plan.Pending); pending > 0 {
+		return fmt.Errorf("database has %d pending migrations: run eventpoll migrate first", pending)
+	}
+	pending, err := env.repos.PlanMigrations()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%s database has %d pending migrations: run eventpoll migrate first", env.config.Database.Type, len(pending))
+	}
+	return nil
+}
+
+func (env *cliEnv) localizer() *Localizer {
+	return NewLocalizer(env.config.Language, env.config.Timezone)
+}
+
+func parseIDArg(args []string) (int64, error) {
+	if len(args) != 1 {
+		return 0, errUsage
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid id %q", args[0])
+	}
+	return id, nil
+}
+
+func runMigrate(ctx context.Context, env *cliEnv, args []string) error {
+	fs := env.newFlagSet("migrate")
+	dryRun := fs.Bool("dry-run", false, "print the pending migrations without applying them")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return errUsage
+	}
+	if *dryRun {
+		plan, err := PlanMigrations(env.db)
+		if err != nil {
+			return err
+		}
+		printMigrationPlan(env.stdout, plan)
+		pending, err := env.repos.PlanMigrations()
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			fmt.Fprintf(env.stdout, "-- %s\n", env.config.Database.Type)
+			printMigrationPlan(env.stdout, &MigrationPlan{Pending: pending})
+		}
+		return nil
+	}
+	if err := MigrateDB(env.db, env.config.Timezone); err != nil {
+		return err
+	}
+	fmt.Fprintln(env.stdout, "database is at schema version", latestSchemaVersion())
+	if err := env.repos.Migrate(); err != nil {
+		return fmt.Errorf("%s: %w", env.config.Database.Type, err)
+	}
+	return nil
+}
+
+func printMigrationPlan(w io.Writer, plan *MigrationPlan) {
+	if len(plan.Baseline) == 0 && len(plan.Pending) == 0 {
+		fmt.Fprintln(w, "database is up to date")
+		return
+	}
+	for _, m := range plan.Baseline {
+		fmt.Fprintf(w, "-- migration %d: %s (already applied, will be recorded)\n", m.Version, m.Description)
+	}
+	for _, m := range plan.Pending {
+		fmt.Fprintf(w, "-- migration %d: %s\n", m.Version, m.Description)
+		for _, q := range m.Queries {
+			fmt.Fprintf(w, "%s;\n", q)
+		}
+		if m.Func != nil {
+			fmt.Fprintln(w, "-- followed by a data migration in Go")
+		}
+	}
+}
+
+func runEventsList(ctx context.Context, env *cliEnv, args []string) error {
+	fs := env.newFlagSet("events list")
+	limit := fs.Int("limit", defaultEventListLimit, "maximum number of events")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return errUsage
+	}
+	if err := env.checkSchema(); err != nil {
+		return err
+	}
+	events, err := env.eventDAO.ListEvents(*limit)
+	if err != nil {
+		return err
+	}
+	l := env.localizer()
+	tw := tabwriter.NewWriter(env.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTARTS AT\tCHAT\tCREATED BY\tDESCRIPTION")
+	for _, event := range events {
+		startedAt := "-"
+		if event.StartedAt != nil {
+			startedAt = l.FormatTime(*event.StartedAt)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%s\n", event.ID, startedAt, event.ChatID, event.CreatedBy, firstLine(event.Description))
+	}
+	return tw.Flush()
+}
+
+func runEventsShow(ctx context.Context, env *cliEnv, args []string) error {
+	eventID, err := parseIDArg(args)
+	if err != nil {
+		return err
+	}
+	if err := env.checkSchema(); err != nil {
+		return err
+	}
+	event, err := env.eventDAO.GetEventByID(eventID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("event %d not found", eventID)
+	}
+	if err != nil {
+		return err
+	}
+	users, err := env.eventDAO.GetEventUsers(eventID)
+	if err != nil {
+		return err
+	}
+
+	l := env.localizer()
+	startedAt := "-"
+	if event.StartedAt != nil {
+		startedAt = l.FormatTime(*event.StartedAt)
+	}
+	tw := tabwriter.NewWriter(env.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%d\n", event.ID)
+	fmt.Fprintf(tw, "Description:\t%s\n", event.Description)
+	fmt.Fprintf(tw, "Starts at:\t%s\n", startedAt)
+	fmt.Fprintf(tw, "Chat:\t%d (message %d)\n", event.ChatID, event.MessageID)
+	fmt.Fprintf(tw, "Created by:\t%s (%d)\n", event.CreatedBy, event.CreatedByID)
+	fmt.Fprintf(tw, "Created at:\t%s\n", l.FormatTime(event.CreatedAt))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	eventAndUsers := getEventAndUsers(*event, users)
+	for _, option := range event.Options {
+		voters := eventAndUsers.OptionUsers[option]
+		fmt.Fprintf(env.stdout, "\n%s (%d)\n", option, len(voters))
+		for _, voter := range voters {
+			fmt.Fprintln(env.stdout, "  -", voter)
+		}
+	}
+	return nil
+}
+
+func runEventsDelete(ctx context.Context, env *cliEnv, args []string) error {
+	eventID, err := parseIDArg(args)
+	if err != nil {
+		return err
+	}
+	if err := env.checkSchema(); err != nil {
+		return err
+	}
+	affectedRows, err := env.eventDAO.DeleteEvent(eventID)
+	if err != nil {
+		return err
+	}
+	if affectedRows == 0 {
+		return fmt.Errorf("event %d not found", eventID)
+	}
+	fmt.Fprintln(env.stdout, "deleted event", eventID)
+	return nil
+}
+
+func runActivitiesExport(ctx context.Context, env *cliEnv, args []string) error {
+	fs := env.newFlagSet("activities export")
+	output := fs.String("o", "", "output file, standard output if empty")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 {
+		return errUsage
+	}
+	if err := env.checkSchema(); err != nil {
+		return err
+	}
+	activities, err := env.activityDAO.GetAll()
+	if err != nil {
+		return err
+	}
+
+	w := env.stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := writeActivitiesCSV(w, activities, env.config.Timezone); err != nil {
+		return err
+	}
+	if *output != "" {
+		fmt.Fprintln(env.stdout, "exported", len(activities), "activities to", *output)
+	}
+	return nil
+}
+
+func runActivitiesImport(ctx context.Context, env *cliEnv, args []string) error {
+	fs := env.newFlagSet("activities import")
+	dryRun := fs.Bool("dry-run", false, "validate the file without saving")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return errUsage
+	}
+	if err := env.checkSchema(); err != nil {
+		return err
+	}
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	orgs, err := env.orgDAO.ListOrgs()
+	if err != nil {
+		return err
+	}
+	// validate the whole file before saving anything
+	activities, err := readActivitiesCSV(f, orgs)
+	if err != nil {
+		return err
+	}
+
+	var created, updated int
+	for i := range activities {
+		activity := &activities[i]
+		exists := false
+		if activity.ID != 0 {
+			prev, err := env.activityDAO.GetByID(activity.ID)
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+			exists = err == nil
+			if exists {
+				activity.keepLeadIDs(*prev)
+			}
+		}
+		if exists {
+			updated++
+		} else {
+			created++
+		}
+		if *dryRun {
+			continue
+		}
+		if exists {
+			err = env.activityDAO.Update(activity)
+		} else {
+			_, err = env.activityDAO.Save(activity)
+		}
+		if err != nil {
+			return fmt.Errorf("activity %q: %w", activity.Name, err)
+		}
+	}
+	if *dryRun {
+		fmt.Fprintf(env.stdout, "would create %d and update %d activities\n", created, updated)
+		return nil
+	}
+	fmt.Fprintf(env.stdout, "created %d and updated %d activities\n", created, updated)
+	return nil
+}
+
+func runDBBackup(ctx context.Context, env *cliEnv, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+	if err := BackupDB(ctx, env.db, args[0]); err != nil {
+		return err
+	}
+	fmt.Fprintln(env.stdout, "database backed up to", args[0])
+	return nil
+}
+
+func runDBCheck(ctx context.Context, env *cliEnv, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+	problems, err := CheckDB(ctx, env.db)
+	if err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		fmt.Fprintln(env.stdout, problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d problems found", len(problems))
+	}
+	fmt.Fprintln(env.stdout, "ok")
+	return nil
+}
+
+// writeActivitiesCSV writes the activities with their start time in the given timezone
+func writeActivitiesCSV(w io.Writer, activities []Activity, loc *time.Location) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(activityCSVHeader); err != nil {
+		return err
+	}
+	for _, a := range activities {
+		record := []string{
+			strconv.FormatInt(a.ID, 10),
+			a.Name,
+			string(a.Org),
+			a.Lead,
+			strings.Join(a.CoLeads, ";"),
+			a.StartedAt.In(loc).Format(time.RFC3339),
+			a.CreatedBy,
+			strconv.FormatInt(a.CreatedByID, 10),
+			"",
+			a.Location,
+			a.Description,
+			string(a.status()),
+		}
+		if a.EndsAt != nil {
+			record[8] = a.EndsAt.In(loc).Format(time.RFC3339)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// readActivitiesCSV parses activities in the format written by writeActivitiesCSV.
+// An empty id creates a new activity. The org must be one of orgs, archived ones included
+func readActivitiesCSV(r io.Reader, orgs []Organization) ([]Activity, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	// files exported by older versions lack the columns after created_by_id
+	if strings.Join(header, ",") != strings.Join(activityCSVHeader, ",") &&
+		strings.Join(header, ",") != strings.Join(activityCSVHeader[:activityCSVLegacyColumns], ",") {
+		return nil, fmt.Errorf("unexpected header %q, expected %q", header, activityCSVHeader)
+	}
+	cr.FieldsPerRecord = len(header)
+
+	var activities []Activity
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		activity, err := parseActivityRecord(record, orgs)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		activities = append(activities, activity)
+	}
+	return activities, nil
+}
+
+func parseActivityRecord(record []string, orgs []Organization) (Activity, error) {
+	var a Activity
+	var err error
+	if id := strings.TrimSpace(record[0]); id != "" {
+		if a.ID, err = strconv.ParseInt(id, 10, 64); err != nil {
+			return a, fmt.Errorf("invalid id %q", id)
+		}
+	}
+	if a.Name = strings.TrimSpace(record[1]); a.Name == "" {
+		return a, errors.New("name is required")
+	}
+	org, ok := findOrg(orgs, record[2])
+	if !ok {
+		return a, fmt.Errorf("invalid org %q, expected one of %v", record[2], orgNames(orgs))
+	}
+	a.Org = org.Name
+	if a.Lead = strings.TrimSpace(record[3]); a.Lead == "" {
+		return a, errors.New("lead is required")
+	}
+	a.CoLeads = []string{}
+	for _, coLead := range strings.Split(record[4], ";") {
+		if coLead = strings.TrimSpace(coLead); coLead != "" {
+			a.CoLeads = append(a.CoLeads, coLead)
+		}
+	}
+	if a.StartedAt, err = time.Parse(time.RFC3339, strings.TrimSpace(record[5])); err != nil {
+		return a, fmt.Errorf("invalid started_at %q, expected RFC 3339 such as 2025-03-01T18:30:00+08:00", record[5])
+	}
+	a.CreatedBy = record[6]
+	if createdByID := strings.TrimSpace(record[7]); createdByID != "" {
+		if a.CreatedByID, err = strconv.ParseInt(createdByID, 10, 64); err != nil {
+			return a, fmt.Errorf("invalid created_by_id %q", createdByID)
+		}
+	}
+	if len(record) == activityCSVLegacyColumns {
+		return a, nil
+	}
+	if endsAt := strings.TrimSpace(record[8]); endsAt != "" {
+		t, err := time.Parse(time.RFC3339, endsAt)
+		if err != nil || !t.After(a.StartedAt) {
+			return a, fmt.Errorf("invalid ends_at %q, expected RFC 3339 after started_at", record[8])
+		}
+		a.EndsAt = &t
+	}
+	a.Location = strings.TrimSpace(record[9])
+	a.Description = strings.TrimSpace(record[10])
+	if status := strings.TrimSpace(record[11]); status != "" {
+		if a.Status, ok = parseActivityStatus(status); !ok {
+			return a, fmt.Errorf("invalid status %q", record[11])
+		}
+	}
+	return a, nil
+}
+
+// firstLine shortens multi-line text for tabular output
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i] + "…"
+	}
+	return s
+}
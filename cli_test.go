@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func runTestCLI(t *testing.T, dbPath string, args ...string) (string, int) {
+	t.Helper()
+	config := &Config{Timezone: time.UTC, Language: langEnglish}
+	var stdout, stderr bytes.Buffer
+	code := runCLI(context.Background(), config, dbPath, args, &stdout, &stderr)
+	return stdout.String() + stderr.String(), code
+}
+
+func TestCLI(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "events.db")
+
+	if out, code := runTestCLI(t, dbPath, "events", "list"); code != 1 || !strings.Contains(out, "eventpoll migrate") {
+		t.Fatalf("Expected an unmigrated database to be rejected, got %d: %s", code, out)
+	}
+	if out, code := runTestCLI(t, dbPath, "migrate", "-dry-run"); code != 0 || !strings.Contains(out, "-- migration 1: ") || !strings.Contains(out, "CREATE TABLE IF NOT EXISTS events") {
+		t.Fatalf("Expected the dry run to print the migrations, got %d: %s", code, out)
+	}
+	if out, code := runTestCLI(t, dbPath, "migrate"); code != 0 {
+		t.Fatalf("migrate failed with %d: %s", code, out)
+	}
+	if out, code := runTestCLI(t, dbPath, "migrate", "-dry-run"); code != 0 || !strings.Contains(out, "database is up to date") {
+		t.Fatalf("Expected nothing to migrate, got %d: %s", code, out)
+	}
+
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	eventDAO := NewEventDAO(db)
+	startedAt := time.Date(2025, 3, 8, 18, 30, 0, 0, time.UTC)
+	eventID, err := eventDAO.SaveEvent(&Event{Description: "Board games", Options: []string{"Yes", "No"}, StartedAt: &startedAt, CreatedBy: "Alice"})
+	if err != nil {
+		t.Fatalf("SaveEvent failed: %v", err)
+	}
+	if err := eventDAO.SaveEventUser(&EventUser{EventID: eventID, User: "Bob", UserID: 2, Option: "Yes"}); err != nil {
+		t.Fatalf("SaveEventUser failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		want     []string
+	}{
+		{"unknown command", []string{"frobnicate"}, 2, []string{"usage: eventpoll"}},
+		{"list events", []string{"events", "list"}, 0, []string{"Board games", "Sat, 2025-03-08 18:30"}},
+		{"show event", []string{"events", "show", "1"}, 0, []string{"Board games", "Yes (1)", "- Bob", "No (0)"}},
+		{"show missing event", []string{"events", "show", "9"}, 1, []string{"event 9 not found"}},
+		{"show without id", []string{"events", "show"}, 2, []string{"usage: eventpoll events show <id>"}},
+		{"check database", []string{"db", "check"}, 0, []string{"ok"}},
+		{"delete event", []string{"events", "delete", "1"}, 0, []string{"deleted event 1"}},
+		{"delete missing event", []string{"events", "delete", "1"}, 1, []string{"event 1 not found"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, code := runTestCLI(t, dbPath, tt.args...)
+			if code != tt.wantCode {
+				t.Errorf("Expected exit code %d, got %d: %s", tt.wantCode, code, out)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("Expected output to contain %q, got %s", want, out)
+				}
+			}
+		})
+	}
+
+	users, err := eventDAO.GetEventUsers(eventID)
+	if err != nil || len(users) != 0 {
+		t.Errorf("Expected the votes to be deleted with the event, got %v (%v)", users, err)
+	}
+
+	backupPath := filepath.Join(dir, "backup.db")
+	if out, code := runTestCLI(t, dbPath, "db", "backup", backupPath); code != 0 {
+		t.Fatalf("backup failed with %d: %s", code, out)
+	}
+	if out, code := runTestCLI(t, backupPath, "db", "check"); code != 0 {
+		t.Errorf("Expected the backup to pass the check, got %d: %s", code, out)
+	}
+	if _, code := runTestCLI(t, dbPath, "db", "backup", backupPath); code != 1 {
+		t.Errorf("Expected an existing backup file not to be overwritten")
+	}
+}
+
+func TestCLIActivitiesImportExport(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "events.db")
+	if out, code := runTestCLI(t, dbPath, "migrate"); code != 0 {
+		t.Fatalf("migrate failed with %d: %s", code, out)
+	}
+
+	csvPath := filepath.Join(dir, "activities.csv")
+	input := "id,name,org,lead,co_leads,started_at,created_by,created_by_id\n" +
+		",Hike,peak,Alice,Bob;Carol,2025-03-08T10:00:00+08:00,Alice,1\n" +
+		",\"Quiz, night\",CC,Dave,,2025-04-01T19:00:00Z,,\n"
+	if err := os.WriteFile(csvPath, []byte(input), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if out, code := runTestCLI(t, dbPath, "activities", "import", "-dry-run", csvPath); code != 0 || !strings.Contains(out, "would create 2 and update 0") {
+		t.Fatalf("Unexpected dry run result %d: %s", code, out)
+	}
+	if out, _ := runTestCLI(t, dbPath, "activities", "export"); strings.Contains(out, "Hike") {
+		t.Fatalf("Expected the dry run not to save anything, got %s", out)
+	}
+	if out, code := runTestCLI(t, dbPath, "activities", "import", csvPath); code != 0 || !strings.Contains(out, "created 2 and updated 0") {
+		t.Fatalf("Unexpected import result %d: %s", code, out)
+	}
+
+	out, code := runTestCLI(t, dbPath, "activities", "export")
+	if code != 0 {
+		t.Fatalf("export failed with %d: %s", code, out)
+	}
+	want := "id,name,org,lead,co_leads,started_at,created_by,created_by_id,ends_at,location,description,status\n" +
+		"1,Hike,PEAK,Alice,Bob;Carol,2025-03-08T02:00:00Z,Alice,1,,,,planned\n" +
+		"2,\"Quiz, night\",CC,Dave,,2025-04-01T19:00:00Z,,0,,,,planned\n"
+	if out != want {
+		t.Errorf("Unexpected export\n%s\nwant\n%s", out, want)
+	}
+
+	// re-importing the export updates the existing activities
+	out = strings.Replace(out, "Dave,,2025-04-01T19:00:00Z,,0,,,,planned", "Erin,,2025-04-01T19:00:00Z,,0,2025-04-01T21:00:00Z,Pub,,confirmed", 1)
+	if err := os.WriteFile(csvPath, []byte(out), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if out, code := runTestCLI(t, dbPath, "activities", "import", csvPath); code != 0 || !strings.Contains(out, "created 0 and updated 2") {
+		t.Fatalf("Unexpected re-import result %d: %s", code, out)
+	}
+
+	invalid := "id,name,org,lead,co_leads,started_at,created_by,created_by_id\n" +
+		",Picnic,CC,Erin,,2025-05-01T12:00:00Z,,\n" +
+		",Party,XX,Erin,,2025-05-02T12:00:00Z,,\n"
+	if err := os.WriteFile(csvPath, []byte(invalid), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if out, code := runTestCLI(t, dbPath, "activities", "import", csvPath); code != 1 || !strings.Contains(out, "line 3: invalid org") {
+		t.Fatalf("Expected the invalid org to be rejected, got %d: %s", code, out)
+	}
+	out, _ = runTestCLI(t, dbPath, "activities", "export")
+	if strings.Contains(out, "Picnic") {
+		t.Errorf("Expected nothing to be imported from an invalid file, got %s", out)
+	}
+	if !strings.Contains(out, "Erin,,2025-04-01T19:00:00Z,,0,2025-04-01T21:00:00Z,Pub,,confirmed") {
+		t.Errorf("Expected the re-import to update the lead, end time, location and status, got %s", out)
+	}
+}
@@ -1,10 +1,12 @@
 package main
 
 const (
+	dbFileName        = "events.db"
 	timeFormat        = "2006-01-02 15:04"
-	displayTimeFormat = "Mon, 2006-01-02 15:04"
 	monthFormat       = "Jan 2006"
+	monthInputFormat  = "2006-01"
 	callbackSeparator = "_"
+	startTimeExamples = "e.g. 2025-03-01 18:30, tomorrow 7pm, next sat 10:00, 25/12 18:30 or in 3 days"
 
 	callbackNavBack = "back"
 )
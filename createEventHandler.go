@@ -2,11 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
@@ -22,99 +22,128 @@ const (
 	pollDeleteOptionCallbackPrefix = "deleteOptionCallback"
 )
 
-type UpdatePollResponse struct {
-	MsgText string
-	Step    int
+type CreateEventHandler struct {
+	eventDao         EventRepository
+	userStates       StateStore
+	chatSettings     ChatSettingsRepository
+	botName          string
+	createWizard     *Wizard
+	updatePollWizard *Wizard
 }
 
-var (
-	updatePollCallbackResponses = map[string]UpdatePollResponse{
-		updatePollCallbackkDesc:     {MsgText: "Please enter the new description for the event.", Step: 1},
-		updatePollCallbackStartedAt: {MsgText: "Please enter the new start time in the format YYYY-MM-DD HH:MM.", Step: 2},
-		updatePollCallbackAddOption: {MsgText: "Please enter the new option to add.", Step: 3},
+func NewCreateEventHandler(eventDao EventRepository, userStates StateStore, chatSettings ChatSettingsRepository, botName string) *CreateEventHandler {
+	h := &CreateEventHandler{eventDao: eventDao, userStates: userStates, chatSettings: chatSettings, botName: botName}
+	h.createWizard = &Wizard{
+		StateType: CREATE_EVENT,
+		Fields: []WizardField{
+			{
+				Name:   updatePollCallbackkDesc,
+				Prompt: msgCreateEventPrompt,
+				Parse:  parseEventDescription,
+			},
+		},
+		OnComplete:   h.completeCreateEvent,
+		userStates:   userStates,
+		chatSettings: chatSettings,
 	}
-)
-
-type CreateEventHandler struct {
-	eventDao *EventDAO
-	botName  string
+	h.updatePollWizard = &Wizard{
+		StateType: UPDATE_EVENT,
+		EditFields: []WizardField{
+			{
+				Name:   updatePollCallbackkDesc,
+				Prompt: msgEventDescPrompt,
+				Parse:  parseEventDescription,
+			},
+			{
+				Name:       updatePollCallbackStartedAt,
+				Prompt:     msgEventStartedAtPrompt,
+				PromptArgs: []any{startTimeExamples},
+				Parse:      parseEventStartedAt,
+				Echo:       echoEventStartedAt,
+				DatePicker: true,
+			},
+			{
+				Name:   updatePollCallbackAddOption,
+				Prompt: msgEventOptionPrompt,
+				Parse:  parseEventOption,
+			},
+		},
+		OnEdit:       h.completeUpdatePoll,
+		userStates:   userStates,
+		chatSettings: chatSettings,
+	}
+	return h
 }
 
-func NewCreateEventHandler(eventDao *EventDAO, botName string) *CreateEventHandler {
-	return &CreateEventHandler{eventDao: eventDao, botName: botName}
+// wizards returns the wizards driving the multi-step flows of this handler
+func (h *CreateEventHandler) wizards() []*Wizard {
+	return []*Wizard{h.createWizard, h.updatePollWizard}
 }
 
-func (h *CreateEventHandler) handleSend(ctx context.Context, b *bot.Bot, update *models.Update) {
+func (h *CreateEventHandler) handleSend(ctx context.Context, b Messenger, update *models.Update) {
 	eventID, err := strconv.ParseInt(getCommandArgument(update), 10, 64)
 	if err != nil {
-		log.Println("error parsing event ID", err)
+		slog.WarnContext(ctx, "error parsing event ID", "err", err)
 		return
 	}
 	event, err := h.eventDao.GetEventByID(eventID)
 	if err != nil {
-		log.Println("error getting event", err)
+		slog.ErrorContext(ctx, "error getting event", "err", err)
 		return
 	}
 	chatID := update.Message.Chat.ID
 	msgThreadID := update.Message.MessageThreadID
+	l := h.chatSettings.GetLocalizer(ctx, chatID)
 	if !isSameUser(update.Message.From, event.CreatedBy, event.CreatedByID) {
-		log.Println("event not created by user", getUserFullName(update.Message.From))
+		slog.WarnContext(ctx, "event not created by user", "event_id", event.ID)
 		b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:          chatID,
 			MessageThreadID: msgThreadID,
-			Text:            "You are not authorized to send this event",
+			Text:            l.T(msgNotAuthorizedSend),
 		})
 		return
 	}
 	users, err := h.eventDao.GetEventUsers(eventID)
 	if err != nil {
-		log.Println("error getting event users", err)
+		slog.ErrorContext(ctx, "error getting event users", "event_id", event.ID, "err", err)
 	}
-	eventMsgID := sendEventPoll(ctx, b, chatID, msgThreadID, *event, users)
+	eventMsgID := sendEventPoll(ctx, b, chatID, msgThreadID, *event, users, l)
 	event.updateDetails(chatID, eventMsgID, event.CreatedBy, event.CreatedByID)
 	err = h.eventDao.UpdateEvent(event)
 	if err != nil {
-		log.Println("error updating event", event.ID, err)
+		slog.ErrorContext(ctx, "error updating event", "event_id", event.ID, "err", err)
 	}
 }
 
-func (h *CreateEventHandler) handleStart(ctx context.Context, b *bot.Bot, update *models.Update) {
+func (h *CreateEventHandler) handleStart(ctx context.Context, b Messenger, update *models.Update) {
 	chatID := update.Message.Chat.ID
 	msgThreadID := update.Message.MessageThreadID
 	userStateKey := getUserStateKey(chatID, msgThreadID, update.Message.From)
 	// Initialize user state
-	userStates[userStateKey] = &UserState{
-		Step:      1,
-		StateType: CREATE_EVENT,
+	h.createWizard.Start(ctx, b, userStateKey, &UserState{
+		ChatID:      chatID,
+		MsgThreadID: msgThreadID,
 		Event: Event{
-			Options: []string{"Available"},
+			Options:     []string{h.chatSettings.GetLocalizer(ctx, chatID).T(msgDefaultPollOption)},
+			CreatedBy:   getUserFullName(update.Message.From),
+			CreatedByID: update.Message.From.ID,
 		},
-	}
-
-	b.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:          chatID,
-		MessageThreadID: msgThreadID,
-		Text:            "Let's start creating the event. First, please enter the description.",
 	})
 }
 
-// Handler for creating an event from description
-func (h *CreateEventHandler) handleCreateEvent(ctx context.Context, b *bot.Bot, update *models.Update, userStateKey string) {
-	chatID := update.Message.Chat.ID
-	msgThreadID := update.Message.MessageThreadID
-
-	event := Event{
-		Options:     []string{"Available"},
-		Description: update.Message.Text,
-	}
+// completeCreateEvent saves the event once the description is collected
+func (h *CreateEventHandler) completeCreateEvent(ctx context.Context, b Messenger, userStateKey string, userState *UserState) {
+	chatID := userState.ChatID
+	msgThreadID := userState.MsgThreadID
 
-	event.updateDetails(chatID, 0, getUserFullName(update.Message.From), update.Message.From.ID)
+	event := userState.Event
+	event.updateDetails(chatID, 0, event.CreatedBy, event.CreatedByID)
 	eventID, err := h.eventDao.SaveEvent(&event)
 	if err != nil {
-		log.Println("error saving event", err)
+		slog.ErrorContext(ctx, "error saving event", "err", err)
 	}
 	event.ID = eventID
-	h.sendEvent(b, chatID, msgThreadID, &event, true)
+	h.sendEvent(ctx, b, chatID, msgThreadID, &event, true, userState.localizer())
 
 	b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:          chatID,
@@ -122,51 +151,52 @@ func (h *CreateEventHandler) handleCreateEvent(ctx context.Context, b *bot.Bot,
 		Text:            fmt.Sprintf("/send@%s %d", h.botName, eventID),
 	})
 	// Clean up user state
-	delete(userStates, userStateKey)
+	h.deleteUserState(ctx, userStateKey)
 }
 
-func (h *CreateEventHandler) handleUpdatePollCallback(ctx context.Context, b *bot.Bot, update *models.Update) {
+func (h *CreateEventHandler) handleUpdatePollCallback(ctx context.Context, b Messenger, update *models.Update) {
 	chatID := update.CallbackQuery.Message.Message.Chat.ID
 	msgThreadID := update.CallbackQuery.Message.Message.MessageThreadID
 	userStateKey := getUserStateKey(chatID, msgThreadID, &update.CallbackQuery.From)
+	l := h.chatSettings.GetLocalizer(ctx, chatID)
 	optionInputs := strings.Split(update.CallbackQuery.Data, callbackSeparator)
 	if len(optionInputs) < 3 {
-		log.Println("invalid callback data", update.CallbackQuery.Data)
+		slog.WarnContext(ctx, "invalid callback data", "data", update.CallbackQuery.Data)
 		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 			CallbackQueryID: update.CallbackQuery.ID,
 			ShowAlert:       true,
-			Text:            "Invalid callback data",
+			Text:            l.T(msgInvalidCallbackData),
 		})
 		return
 	}
 	option := optionInputs[1]
 	eventID, err := strconv.ParseInt(optionInputs[2], 10, 64)
 	if err != nil {
-		log.Println("error parsing event ID", err)
+		slog.WarnContext(ctx, "error parsing event ID", "err", err)
 		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 			CallbackQueryID: update.CallbackQuery.ID,
 			ShowAlert:       true,
-			Text:            "Invalid event ID",
+			Text:            l.T(msgInvalidEventID),
 		})
 		return
 	}
 	event, err := h.eventDao.GetEventByID(eventID)
 	if err != nil {
-		log.Println("error getting event", err)
+		slog.ErrorContext(ctx, "error getting event", "err", err)
 		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 			CallbackQueryID: update.CallbackQuery.ID,
 			ShowAlert:       true,
-			Text:            "Event not found",
+			Text:            l.T(msgEventNotFound),
 		})
 		return
 	}
 
 	if !isSameUser(&update.CallbackQuery.From, event.CreatedBy, event.CreatedByID) {
-		log.Println("event not created by user", getUserFullName(update.Message.From))
+		slog.WarnContext(ctx, "event not created by user", "event_id", event.ID)
 		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 			CallbackQueryID: update.CallbackQuery.ID,
 			ShowAlert:       true,
-			Text:            "You are not authorized to update this event",
+			Text:            l.T(msgNotAuthorizedEvent),
 		})
 		return
 	}
@@ -185,7 +215,7 @@ func (h *CreateEventHandler) handleUpdatePollCallback(ctx context.Context, b *bo
 			})
 		}
 		inlineKeyboard = append(inlineKeyboard, []models.InlineKeyboardButton{
-			{Text: "<< back", CallbackData: strings.Join([]string{pollDeleteOptionCallbackPrefix, eventIDStr, callbackNavBack}, callbackSeparator)},
+			{Text: l.T(msgButtonBack), CallbackData: strings.Join([]string{pollDeleteOptionCallbackPrefix, eventIDStr, callbackNavBack}, callbackSeparator)},
 		})
 		kb := &models.InlineKeyboardMarkup{
 			InlineKeyboard: inlineKeyboard,
@@ -194,23 +224,22 @@ func (h *CreateEventHandler) handleUpdatePollCallback(ctx context.Context, b *bo
 		_, err := b.EditMessageText(ctx, &bot.EditMessageTextParams{
 			ChatID:      chatID,
 			MessageID:   update.CallbackQuery.Message.Message.ID,
-			Text:        "Select the option to delete",
+			Text:        l.T(msgSelectOptionToDelete),
 			ReplyMarkup: kb,
 		})
 		if err != nil {
-			log.Println("error editing message for delete option", err)
+			slog.ErrorContext(ctx, "error editing message for delete option", "err", err)
 		}
 		return
 	}
 
-	// Handle all 4 update options: description, start time, add option, delete option
-	response, ok := updatePollCallbackResponses[option]
-	if !ok {
-		log.Println("invalid option callback", option)
+	// Handle the other 3 update options: description, start time, add option
+	if h.updatePollWizard.editField(option) == nil {
+		slog.WarnContext(ctx, "invalid option callback", "option", option)
 		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 			CallbackQueryID: update.CallbackQuery.ID,
 			ShowAlert:       true,
-			Text:            "Invalid option callback",
+			Text:            l.T(msgInvalidOption),
 		})
 		return
 	}
@@ -218,74 +247,42 @@ func (h *CreateEventHandler) handleUpdatePollCallback(ctx context.Context, b *bo
 		CallbackQueryID: update.CallbackQuery.ID,
 		ShowAlert:       false,
 	})
-	b.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:          chatID,
-		MessageThreadID: msgThreadID,
-		Text:            response.MsgText,
-	})
-
-	userStates[userStateKey] = &UserState{
-		StateType: UPDATE_EVENT,
-		Event:     *event,
-		Step:      response.Step,
-	}
+	h.updatePollWizard.Edit(ctx, b, userStateKey, &UserState{
+		ChatID:      chatID,
+		MsgThreadID: msgThreadID,
+		Event:       *event,
+	}, option)
 }
 
-func (h *CreateEventHandler) handleUpdatePollInput(ctx context.Context, b *bot.Bot, update *models.Update, userStateKey string, userState *UserState) {
-	chatID := update.Message.Chat.ID
-	msgThreadID := update.Message.MessageThreadID
-
-	switch userState.Step {
-	case 1:
-		// Collect description
-		userState.Event.Description = update.Message.Text
-	case 2:
-		// Collect start time
-		startTime, err := time.Parse(timeFormat, update.Message.Text)
-		if err != nil {
-			b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:          chatID,
-				MessageThreadID: msgThreadID,
-				Text:            "Invalid input. Please enter a valid start time in the format YYYY-MM-DD HH:MM. For example, " + timeFormat,
-			})
-			return
-		}
-		userState.Event.StartedAt = &startTime
-	case 3:
-		// Collect option to add
-		option := strings.TrimSpace(update.Message.Text)
-		if option == "" {
-			b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID:          chatID,
-				MessageThreadID: msgThreadID,
-				Text:            "Empty input. Please enter the option to add.",
-			})
-			return
-		}
-		userState.Event.Options = append(userState.Event.Options, option)
-	}
+// completeUpdatePoll saves the event once the selected field is collected
+func (h *CreateEventHandler) completeUpdatePoll(ctx context.Context, b Messenger, userStateKey string, userState *UserState) {
+	chatID := userState.ChatID
+	msgThreadID := userState.MsgThreadID
 
 	err := h.eventDao.UpdateEvent(&userState.Event)
 	if err != nil {
-		log.Println("error updating poll", err)
+		slog.ErrorContext(ctx, "error updating poll", "err", err)
 		b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:          chatID,
 			MessageThreadID: msgThreadID,
-			Text:            "Error updating poll",
+			Text:            userState.localizer().T(msgUpdatePollFailed),
 		})
 		return
 	}
-	h.sendEvent(b, chatID, msgThreadID, &userState.Event, false)
-	delete(userStates, userStateKey)
+	h.sendEvent(ctx, b, chatID, msgThreadID, &userState.Event, false, userState.localizer())
+	h.deleteUserState(ctx, userStateKey)
 }
 
-func (h *CreateEventHandler) handleDeleteOptionCallback(ctx context.Context, b *bot.Bot, update *models.Update) {
+func (h *CreateEventHandler) handleDeleteOptionCallback(ctx context.Context, b Messenger, update *models.Update) {
+	chatID := update.CallbackQuery.Message.Message.Chat.ID
+	messageID := update.CallbackQuery.Message.Message.ID
+	l := h.chatSettings.GetLocalizer(ctx, chatID)
 	callbackData := strings.Split(update.CallbackQuery.Data, callbackSeparator)
 	if len(callbackData) < 3 {
-		log.Println("invalid callback data for delete option:", update.CallbackQuery.Data)
+		slog.WarnContext(ctx, "invalid callback data for delete option", "data", update.CallbackQuery.Data)
 		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 			CallbackQueryID: update.CallbackQuery.ID,
-			Text:            "Invalid callback data.",
+			Text:            l.T(msgInvalidCallbackData),
 			ShowAlert:       true,
 		})
 		return
@@ -295,10 +292,10 @@ func (h *CreateEventHandler) handleDeleteOptionCallback(ctx context.Context, b *
 	optionToDelete := callbackData[2]
 	eventID, err := strconv.ParseInt(eventIDStr, 10, 64)
 	if err != nil {
-		log.Println("invalid event id in callback:", eventIDStr)
+		slog.WarnContext(ctx, "invalid event ID in callback", "event_id", eventIDStr)
 		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 			CallbackQueryID: update.CallbackQuery.ID,
-			Text:            "Invalid event ID.",
+			Text:            l.T(msgInvalidEventID),
 			ShowAlert:       true,
 		})
 		return
@@ -306,7 +303,7 @@ func (h *CreateEventHandler) handleDeleteOptionCallback(ctx context.Context, b *
 	if optionToDelete == "" {
 		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 			CallbackQueryID: update.CallbackQuery.ID,
-			Text:            "No option specified to delete.",
+			Text:            l.T(msgNoOptionToDelete),
 			ShowAlert:       true,
 		})
 		return
@@ -314,21 +311,21 @@ func (h *CreateEventHandler) handleDeleteOptionCallback(ctx context.Context, b *
 
 	event, err := h.eventDao.GetEventByID(eventID)
 	if err != nil || event == nil {
-		log.Println("event not found for delete option:", eventID)
+		slog.WarnContext(ctx, "event not found for delete option", "event_id", eventID)
 		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 			CallbackQueryID: update.CallbackQuery.ID,
-			Text:            "Event not found.",
+			Text:            l.T(msgEventNotFound),
 			ShowAlert:       true,
 		})
 		return
 	}
 
 	if !isSameUser(&update.CallbackQuery.From, event.CreatedBy, event.CreatedByID) {
-		log.Println("event not created by user", getUserFullName(update.Message.From))
+		slog.WarnContext(ctx, "event not created by user", "event_id", event.ID)
 		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 			CallbackQueryID: update.CallbackQuery.ID,
 			ShowAlert:       true,
-			Text:            "You are not authorized to update this event",
+			Text:            l.T(msgNotAuthorizedEvent),
 		})
 		return
 	}
@@ -340,7 +337,7 @@ func (h *CreateEventHandler) handleDeleteOptionCallback(ctx context.Context, b *
 		if len(event.Options) == originalLen {
 			b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 				CallbackQueryID: update.CallbackQuery.ID,
-				Text:            "Option not found in event.",
+				Text:            l.T(msgOptionNotFound),
 				ShowAlert:       true,
 			})
 			return
@@ -348,10 +345,10 @@ func (h *CreateEventHandler) handleDeleteOptionCallback(ctx context.Context, b *
 
 		err = h.eventDao.UpdateEvent(event)
 		if err != nil {
-			log.Println("error updating event after deleting option:", err)
+			slog.ErrorContext(ctx, "error updating event after deleting option", "event_id", event.ID, "err", err)
 			b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 				CallbackQueryID: update.CallbackQuery.ID,
-				Text:            "Failed to update event.",
+				Text:            l.T(msgUpdateEventFailed),
 				ShowAlert:       true,
 			})
 			return
@@ -363,9 +360,7 @@ func (h *CreateEventHandler) handleDeleteOptionCallback(ctx context.Context, b *
 		ShowAlert:       false,
 	})
 
-	chatID := update.CallbackQuery.Message.Message.Chat.ID
-	messageID := update.CallbackQuery.Message.Message.ID
-	text, keyboard := h.getEventMsg(event, false)
+	text, keyboard := h.getEventMsg(event, false, l)
 	_, err = b.EditMessageText(ctx, &bot.EditMessageTextParams{
 		ChatID:      chatID,
 		MessageID:   messageID,
@@ -374,15 +369,49 @@ func (h *CreateEventHandler) handleDeleteOptionCallback(ctx context.Context, b *
 		ReplyMarkup: keyboard,
 	})
 	if err != nil {
-		log.Println("error updating event message after deleting option:", err)
+		slog.ErrorContext(ctx, "error updating event message after deleting option", "event_id", event.ID, "err", err)
+	}
+}
+
+func (h *CreateEventHandler) deleteUserState(ctx context.Context, userStateKey string) {
+	if err := h.userStates.Delete(userStateKey); err != nil {
+		slog.ErrorContext(ctx, "error deleting user state", "key", userStateKey, "err", err)
+	}
+}
+
+func parseEventDescription(ctx context.Context, input WizardInput, state *UserState) error {
+	state.Event.Description = input.Text
+	return nil
+}
+
+func parseEventStartedAt(ctx context.Context, input WizardInput, state *UserState) error {
+	startTime, err := parseUserInputTime(input.Text, state.location())
+	if err != nil {
+		return errors.New(state.localizer().T(msgInvalidStartTime, startTimeExamples))
+	}
+	state.Event.StartedAt = &startTime
+	return nil
+}
+
+func echoEventStartedAt(state *UserState) string {
+	l := state.localizer()
+	return l.T(msgStartTimeSet, l.FormatTime(*state.Event.StartedAt))
+}
+
+func parseEventOption(ctx context.Context, input WizardInput, state *UserState) error {
+	option := strings.TrimSpace(input.Text)
+	if option == "" {
+		return errors.New(state.localizer().T(msgEmptyOption))
 	}
+	state.Event.Options = append(state.Event.Options, option)
+	return nil
 }
 
-func (h *CreateEventHandler) sendEvent(b *bot.Bot, chatID int64, msgThreadID int, event *Event, isNew bool) error {
-	text, keyboard := h.getEventMsg(event, isNew)
+func (h *CreateEventHandler) sendEvent(ctx context.Context, b Messenger, chatID int64, msgThreadID int, event *Event, isNew bool, l *Localizer) error {
+	text, keyboard := h.getEventMsg(event, isNew, l)
 
 	// Send the event as a message
-	_, err := b.SendMessage(context.Background(), &bot.SendMessageParams{
+	_, err := b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:          chatID,
 		MessageThreadID: msgThreadID,
 		Text:            text,
@@ -393,7 +422,7 @@ func (h *CreateEventHandler) sendEvent(b *bot.Bot, chatID int64, msgThreadID int
 	return err
 }
 
-func (h *CreateEventHandler) getEventMsg(event *Event, isNew bool) (string, *models.InlineKeyboardMarkup) {
+func (h *CreateEventHandler) getEventMsg(event *Event, isNew bool, l *Localizer) (string, *models.InlineKeyboardMarkup) {
 	// Construct the inline keyboard for poll options
 	eventIDStr := strconv.FormatInt(event.ID, 10)
 
@@ -401,23 +430,23 @@ func (h *CreateEventHandler) getEventMsg(event *Event, isNew bool) (string, *mod
 	keyboard := &models.InlineKeyboardMarkup{
 		InlineKeyboard: [][]models.InlineKeyboardButton{
 			{
-				{Text: "Description", CallbackData: strings.Join([]string{updatePollCallbackPrefix, updatePollCallbackkDesc, eventIDStr}, callbackSeparator)},
-				{Text: "Start Time", CallbackData: strings.Join([]string{updatePollCallbackPrefix, updatePollCallbackStartedAt, eventIDStr}, callbackSeparator)},
+				{Text: l.T(msgButtonDescription), CallbackData: strings.Join([]string{updatePollCallbackPrefix, updatePollCallbackkDesc, eventIDStr}, callbackSeparator)},
+				{Text: l.T(msgButtonStartTime), CallbackData: strings.Join([]string{updatePollCallbackPrefix, updatePollCallbackStartedAt, eventIDStr}, callbackSeparator)},
 			},
 			{
-				{Text: "Add Option", CallbackData: strings.Join([]string{updatePollCallbackPrefix, updatePollCallbackAddOption, eventIDStr}, callbackSeparator)},
+				{Text: l.T(msgButtonAddOption), CallbackData: strings.Join([]string{updatePollCallbackPrefix, updatePollCallbackAddOption, eventIDStr}, callbackSeparator)},
 			},
 		},
 	}
 	// only allow delete option when it has more than 1
 	if len(event.Options) > 1 {
-		deleteOptionButton := models.InlineKeyboardButton{Text: "Delete Option", CallbackData: strings.Join([]string{updatePollCallbackPrefix, updatePollCallbackDeleteOption, eventIDStr}, callbackSeparator)}
+		deleteOptionButton := models.InlineKeyboardButton{Text: l.T(msgButtonDeleteOption), CallbackData: strings.Join([]string{updatePollCallbackPrefix, updatePollCallbackDeleteOption, eventIDStr}, callbackSeparator)}
 		keyboard.InlineKeyboard[1] = append(keyboard.InlineKeyboard[1], deleteOptionButton)
 	}
 
-	text := event.String() + "\n\n" + "You can update the poll by clicking the buttons below."
+	text := event.string(l) + "\n\n" + l.T(msgUpdatePollHint)
 	if isNew {
-		text += "\nYou can now send it to the group by copy pasting the following command sent as a separate message, in the format: " + fmt.Sprintf("/send@%s <EventID>", h.botName)
+		text += "\n" + l.T(msgSendPollHint, fmt.Sprintf("/send@%s <EventID>", h.botName))
 	}
 	return text, keyboard
 }
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCreateEventHandler(t *testing.T) {
+	en := NewLocalizer(langEnglish, time.UTC)
+	startPoll := func(tb *testBot) HandlerFunc { return tb.events.handleStart }
+	send := func(tb *testBot) HandlerFunc { return tb.events.handleSend }
+	input := func(tb *testBot) HandlerFunc { return tb.defaults.handle }
+	updatePoll := func(tb *testBot) HandlerFunc { return tb.events.handleUpdatePollCallback }
+	deleteOption := func(tb *testBot) HandlerFunc { return tb.events.handleDeleteOptionCallback }
+
+	tests := []struct {
+		name       string
+		steps      []testStep
+		wantTexts  []string
+		wantAlerts []string
+		check      func(t *testing.T, tb *testBot)
+	}{
+		{
+			name: "create event",
+			steps: []testStep{
+				{startPoll, textUpdate(testAlice, "/poll")},
+				{input, textUpdate(testAlice, "Team dinner")},
+			},
+			wantTexts: []string{en.T(msgCreateEventPrompt), "Team dinner", "/send@testbot 2"},
+			check: func(t *testing.T, tb *testBot) {
+				event, err := tb.eventDAO.GetEventByID(2)
+				if err != nil {
+					t.Fatalf("GetEventByID failed: %v", err)
+				}
+				if event.Description != "Team dinner" || event.CreatedByID != testAlice.ID {
+					t.Errorf("Unexpected event %+v", event)
+				}
+			},
+		},
+		{
+			name: "input from another user is ignored",
+			steps: []testStep{
+				{startPoll, textUpdate(testAlice, "/poll")},
+				{input, textUpdate(testBob, "Team dinner")},
+			},
+			wantTexts: []string{en.T(msgCreateEventPrompt)},
+		},
+		{
+			name:      "send poll",
+			steps:     []testStep{{send, textUpdate(testAlice, "/send 1")}},
+			wantTexts: []string{en.T(msgPollTitle)},
+			check: func(t *testing.T, tb *testBot) {
+				event, err := tb.eventDAO.GetEventByID(1)
+				if err != nil {
+					t.Fatalf("GetEventByID failed: %v", err)
+				}
+				if event.MessageID != tb.messenger.lastMessageID {
+					t.Errorf("Expected message ID %d to be stored, got %d", tb.messenger.lastMessageID, event.MessageID)
+				}
+			},
+		},
+		{
+			name:      "send poll of another user",
+			steps:     []testStep{{send, textUpdate(testBob, "/send 1")}},
+			wantTexts: []string{en.T(msgNotAuthorizedSend)},
+		},
+		{
+			name:  "send without event ID",
+			steps: []testStep{{send, textUpdate(testAlice, "/send")}},
+		},
+		{
+			name: "update description",
+			steps: []testStep{
+				{updatePoll, callbackUpdate(testAlice, "updatePoll_desc_1")},
+				{input, textUpdate(testAlice, "Team lunch")},
+			},
+			wantTexts: []string{en.T(msgEventDescPrompt), "Team lunch"},
+			check: func(t *testing.T, tb *testBot) {
+				event, err := tb.eventDAO.GetEventByID(1)
+				if err != nil {
+					t.Fatalf("GetEventByID failed: %v", err)
+				}
+				if event.Description != "Team lunch" {
+					t.Errorf("Expected updated description, got %q", event.Description)
+				}
+			},
+		},
+		{
+			name:       "update poll of another user",
+			steps:      []testStep{{updatePoll, callbackUpdate(testBob, "updatePoll_desc_1")}},
+			wantAlerts: []string{en.T(msgNotAuthorizedEvent)},
+		},
+		{
+			name:       "update unknown event",
+			steps:      []testStep{{updatePoll, callbackUpdate(testAlice, "updatePoll_desc_42")}},
+			wantAlerts: []string{en.T(msgEventNotFound)},
+		},
+		{
+			name: "delete option",
+			steps: []testStep{
+				{updatePoll, callbackUpdate(testAlice, "updatePoll_deleteOption_1")},
+				{deleteOption, callbackUpdate(testAlice, "deleteOptionCallback_1_Maybe")},
+			},
+			wantTexts: []string{en.T(msgSelectOptionToDelete), "Yes"},
+			check: func(t *testing.T, tb *testBot) {
+				event, err := tb.eventDAO.GetEventByID(1)
+				if err != nil {
+					t.Fatalf("GetEventByID failed: %v", err)
+				}
+				if len(event.Options) != 1 || event.Options[0] != "Yes" {
+					t.Errorf("Expected only the Yes option, got %q", event.Options)
+				}
+			},
+		},
+		{
+			name:       "delete unknown option",
+			steps:      []testStep{{deleteOption, callbackUpdate(testAlice, "deleteOptionCallback_1_No")}},
+			wantAlerts: []string{en.T(msgOptionNotFound)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := newTestBot(t)
+			startedAt := time.Now().Add(24 * time.Hour).UTC()
+			event := &Event{
+				Description: "Board games",
+				StartedAt:   &startedAt,
+				Options:     []string{"Yes", "Maybe"},
+			}
+			event.updateDetails(testChatID, 0, getUserFullName(testAlice), testAlice.ID)
+			if _, err := tb.eventDAO.SaveEvent(event); err != nil {
+				t.Fatalf("SaveEvent failed: %v", err)
+			}
+
+			assertTexts(t, tb.run(t, tt.steps), tt.wantTexts)
+			assertTexts(t, tb.messenger.alerts(), tt.wantAlerts)
+			if tt.check != nil {
+				tt.check(t, tb)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const dashboardPath = "/workplan"
+
+//go:embed templates/dashboard.html
+var dashboardTemplateText string
+
+// Dashboard serves the activities of a month as a read-only HTML calendar and list
+// to anyone with the shared token
+type Dashboard struct {
+	activities ActivityRepository
+	orgs       OrgRepository
+	token      string
+	localizer  *Localizer
+	template   *template.Template
+	now        func() time.Time
+}
+
+func NewDashboard(activities ActivityRepository, orgs OrgRepository, token string, l *Localizer) *Dashboard {
+	funcs := template.FuncMap{
+		"t": func(key string, args ...any) string { return l.T(MessageKey(key), args...) },
+	}
+	return &Dashboard{
+		activities: activities,
+		orgs:       orgs,
+		token:      token,
+		localizer:  l,
+		template:   template.Must(template.New("dashboard").Funcs(funcs).Parse(dashboardTemplateText)),
+		now:        time.Now,
+	}
+}
+
+// dashboardPage is what the template renders
+type dashboardPage struct {
+	Month      string
+	Language   string
+	Token      string
+	MonthParam string
+	PrevURL    string
+	NextURL    string
+	Org        string
+	Lead       string
+	Orgs       []Organization
+	Leads      []string
+	Weekdays   []string
+	Weeks      [][]dashboardDay
+	Activities []dashboardActivity
+}
+
+type dashboardDay struct {
+	Day        int
+	InMonth    bool
+	Today      bool
+	Activities []dashboardActivity
+}
+
+type dashboardActivity struct {
+	ID        int64
+	Date      string
+	Time      string
+	Name      string
+	Org       string
+	Lead      string
+	CoLeads   string
+	Location  string
+	Status    string
+	Cancelled bool
+}
+
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	if d.token == "" || subtle.ConstantTimeCompare([]byte(query.Get("token")), []byte(d.token)) != 1 {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	l := d.localizer
+	now := d.now().In(l.Location)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, l.Location)
+	if s := query.Get("month"); s != "" {
+		m, err := time.ParseInLocation(monthInputFormat, s, l.Location)
+		if err != nil {
+			http.Error(w, "invalid month, expected YYYY-MM", http.StatusBadRequest)
+			return
+		}
+		month = m
+	}
+	orgs, err := d.orgs.ListOrgs()
+	if err != nil {
+		slog.ErrorContext(req.Context(), "error listing orgs for dashboard", "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	var org Org
+	if s := query.Get("org"); s != "" {
+		found, ok := findOrg(orgs, s)
+		if !ok {
+			http.Error(w, "unknown org", http.StatusBadRequest)
+			return
+		}
+		org = found.Name
+	}
+	lead := strings.TrimSpace(query.Get("lead"))
+
+	activities, err := d.activities.GetByDuration(month, month.AddDate(0, 1, 0).Add(-time.Nanosecond))
+	if err != nil {
+		slog.ErrorContext(req.Context(), "error getting activities for dashboard", "month", month.Format(monthInputFormat), "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	page := dashboardPage{
+		Month:      l.FormatMonth(month),
+		Language:   l.Language,
+		Token:      d.token,
+		MonthParam: month.Format(monthInputFormat),
+		PrevURL:    d.monthURL(month.AddDate(0, -1, 0), org, lead),
+		NextURL:    d.monthURL(month.AddDate(0, 1, 0), org, lead),
+		Org:        string(org),
+		Lead:       lead,
+		Orgs:       orgs,
+		Weekdays:   l.language().PickerWeekdays,
+	}
+	byDay := make(map[int][]dashboardActivity)
+	for _, a := range activities {
+		for _, name := range append([]string{a.Lead}, a.CoLeads...) {
+			if name != "" && !slices.Contains(page.Leads, name) {
+				page.Leads = append(page.Leads, name)
+			}
+		}
+		if (org != "" && a.Org != org) || !matchesLead(a, lead) {
+			continue
+		}
+		startedAt := a.StartedAt.In(l.Location)
+		item := dashboardActivity{
+			ID:        a.ID,
+			Date:      l.FormatDate(startedAt),
+			Time:      startedAt.Format("15:04"),
+			Name:      a.Name,
+			Org:       orgLabel(orgs, a.Org),
+			Lead:      a.Lead,
+			CoLeads:   strings.Join(a.CoLeads, ", "),
+			Location:  a.Location,
+			Status:    a.status().label(l),
+			Cancelled: a.Status == ActivityCancelled,
+		}
+		if a.EndsAt != nil {
+			item.Time += "-" + a.EndsAt.In(l.Location).Format("15:04")
+		}
+		page.Activities = append(page.Activities, item)
+		byDay[startedAt.Day()] = append(byDay[startedAt.Day()], item)
+	}
+	slices.Sort(page.Leads)
+	page.Weeks = getDashboardWeeks(month, now, byDay)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := d.template.Execute(w, page); err != nil {
+		slog.ErrorContext(req.Context(), "error rendering dashboard", "err", err)
+	}
+}
+
+func (d *Dashboard) monthURL(month time.Time, org Org, lead string) string {
+	query := url.Values{"token": {d.token}, "month": {month.Format(monthInputFormat)}}
+	if org != "" {
+		query.Set("org", string(org))
+	}
+	if lead != "" {
+		query.Set("lead", lead)
+	}
+	return dashboardPath + "?" + query.Encode()
+}
+
+// orgLabel returns the emoji and display name of the org, or its name if it is unknown
+func orgLabel(orgs []Organization, name Org) string {
+	if org, ok := findOrg(orgs, string(name)); ok {
+		return org.Label()
+	}
+	return string(name)
+}
+
+// matchesLead reports whether the case-insensitive name is part of the lead or a co-lead
+func matchesLead(a Activity, name string) bool {
+	if name == "" {
+		return true
+	}
+	name = strings.ToLower(name)
+	for _, lead := range append([]string{a.Lead}, a.CoLeads...) {
+		if strings.Contains(strings.ToLower(lead), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// getDashboardWeeks returns the weeks of the month, starting on Monday like the date picker,
+// padded with the days of the neighbouring months
+func getDashboardWeeks(month, now time.Time, byDay map[int][]dashboardActivity) [][]dashboardDay {
+	first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location())
+	day := first.AddDate(0, 0, -((int(first.Weekday()) + 6) % 7))
+	var weeks [][]dashboardDay
+	for {
+		week := make([]dashboardDay, 0, 7)
+		for i := 0; i < 7; i++ {
+			inMonth := day.Month() == first.Month()
+			d := dashboardDay{
+				Day:     day.Day(),
+				InMonth: inMonth,
+				Today:   day.Year() == now.Year() && day.YearDay() == now.YearDay(),
+			}
+			if inMonth {
+				d.Activities = byDay[day.Day()]
+			}
+			week = append(week, d)
+			day = day.AddDate(0, 0, 1)
+		}
+		weeks = append(weeks, week)
+		if day.Month() != first.Month() {
+			return weeks
+		}
+	}
+}
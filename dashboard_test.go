@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testDashboardToken = "dashboard-secret"
+
+func getDashboard(t *testing.T, d *Dashboard, query string) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, dashboardPath+query, nil))
+	return rec.Code, rec.Body.String()
+}
+
+func TestDashboard(t *testing.T) {
+	tb := newTestBot(t)
+	for _, a := range []*Activity{
+		{Name: "Hike", Org: OrgPEAK, Lead: "Alice", CoLeads: []string{"Bob"}, StartedAt: time.Date(2025, 3, 8, 9, 0, 0, 0, time.UTC)},
+		{Name: "Quiz night", Org: OrgCC, Lead: "Carol", StartedAt: time.Date(2025, 3, 20, 19, 0, 0, 0, time.UTC)},
+		{Name: "Climbing", Org: OrgPEAK, Lead: "Alice", StartedAt: time.Date(2025, 4, 2, 18, 0, 0, 0, time.UTC)},
+	} {
+		if _, err := tb.activityDAO.Save(a); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+	d := NewDashboard(tb.activityDAO, tb.orgDAO, testDashboardToken, NewLocalizer(langEnglish, time.UTC))
+	d.now = func() time.Time { return time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC) }
+
+	for _, query := range []string{"", "?token=wrong"} {
+		if code, _ := getDashboard(t, d, query); code != http.StatusUnauthorized {
+			t.Errorf("Expected %d for %q, got %d", http.StatusUnauthorized, query, code)
+		}
+	}
+	for _, query := range []string{"&month=2025-13", "&month=March", "&org=NOPE"} {
+		if code, _ := getDashboard(t, d, "?token="+testDashboardToken+query); code != http.StatusBadRequest {
+			t.Errorf("Expected %d for %q, got %d", http.StatusBadRequest, query, code)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+		skip  []string
+	}{
+		{"", []string{"Workplan Mar 2025", "Hike", "Quiz night", "Mo"}, []string{"Climbing"}},
+		{"&month=2025-04", []string{"Workplan Apr 2025", "Climbing"}, []string{"Hike", "Quiz night"}},
+		{"&org=CC", []string{"Quiz night"}, []string{"Hike"}},
+		{"&lead=bob", []string{"Hike"}, []string{"Quiz night"}},
+		{"&org=CC&lead=alice", []string{"no activities found."}, []string{"Hike", "Quiz night"}},
+	}
+	for _, tt := range tests {
+		code, body := getDashboard(t, d, "?token="+testDashboardToken+tt.query)
+		if code != http.StatusOK {
+			t.Fatalf("%q: expected %d, got %d", tt.query, http.StatusOK, code)
+		}
+		for _, s := range tt.want {
+			if !strings.Contains(body, s) {
+				t.Errorf("%q: expected the page to contain %q", tt.query, s)
+			}
+		}
+		for _, s := range tt.skip {
+			if strings.Contains(body, s) {
+				t.Errorf("%q: expected the page not to contain %q", tt.query, s)
+			}
+		}
+	}
+
+	_, body := getDashboard(t, d, "?token="+testDashboardToken+"&org=PEAK&lead=Al")
+	if !strings.Contains(body, `href="/workplan?lead=Al&amp;month=2025-04&amp;org=PEAK&amp;token=`+testDashboardToken+`"`) {
+		t.Errorf("Expected the next month link to keep the filters, got %s", body)
+	}
+}
+
+func TestGetDashboardWeeks(t *testing.T) {
+	// March 2025 starts on a Saturday and ends on a Monday
+	weeks := getDashboardWeeks(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), time.Time{}, nil)
+	if len(weeks) != 6 {
+		t.Fatalf("Expected 6 weeks, got %d", len(weeks))
+	}
+	if first := weeks[0][0]; first.Day != 24 || first.InMonth {
+		t.Errorf("Expected the first week to start on Feb 24, got %+v", first)
+	}
+	if day := weeks[0][5]; day.Day != 1 || !day.InMonth {
+		t.Errorf("Expected Mar 1 to be a Saturday, got %+v", day)
+	}
+	if last := weeks[5][0]; last.Day != 31 || !last.InMonth {
+		t.Errorf("Expected the last week to start on Mar 31, got %+v", last)
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errUnknownDateTime = errors.New("unknown date time format")
+
+	relativeDateTimeRegex = regexp.MustCompile(`^in (\d+|a|an) (min|mins|minute|minutes|h|hr|hrs|hour|hours|d|day|days|w|wk|week|weeks|month|months)(?: at (.+))?$`)
+	clockRegex            = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?\s*(am|pm)?$`)
+	dayMonthRegex         = regexp.MustCompile(`^(\d{1,2})[/.](\d{1,2})(?:[/.](\d{2}|\d{4}))?$`)
+	dayMonthNameRegex     = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)? ([a-z]+)$`)
+	monthNameDayRegex     = regexp.MustCompile(`^([a-z]+) (\d{1,2})(?:st|nd|rd|th)?$`)
+
+	dateTimeLayouts = []string{timeFormat, "2006-01-02T15:04", "2006-01-02 15:04:05"}
+	dateLayouts     = []string{"2006-01-02"}
+
+	weekdayNames = map[string]time.Weekday{
+		"sun": time.Sunday, "sunday": time.Sunday,
+		"mon": time.Monday, "monday": time.Monday,
+		"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+		"wed": time.Wednesday, "wednesday": time.Wednesday,
+		"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+		"fri": time.Friday, "friday": time.Friday,
+		"sat": time.Saturday, "saturday": time.Saturday,
+	}
+	monthNames = map[string]time.Month{
+		"jan": time.January, "january": time.January,
+		"feb": time.February, "february": time.February,
+		"mar": time.March, "march": time.March,
+		"apr": time.April, "april": time.April,
+		"may": time.May,
+		"jun": time.June, "june": time.June,
+		"jul": time.July, "july": time.July,
+		"aug": time.August, "august": time.August,
+		"sep": time.September, "sept": time.September, "september": time.September,
+		"oct": time.October, "october": time.October,
+		"nov": time.November, "november": time.November,
+		"dec": time.December, "december": time.December,
+	}
+)
+
+// parseDateTime understands user friendly date time input, resolved against now in the given location.
+// Besides YYYY-MM-DD HH:MM it accepts for example
+//   - "tomorrow 7pm", "today 18:30", "tonight 8pm"
+//   - "sat 10:00" (the coming Saturday, today included), "next sat 10:00" (the coming Saturday after today)
+//   - "25/12 18:30", "25.12.2025 18:30", "25 dec 7pm", "dec 25 at 19:00"
+//   - "in 3 days", "in 2 hours", "in a week at 10am"
+//
+// If no time of day is given, the current time of day is used.
+func parseDateTime(input string, now time.Time, loc *time.Location) (time.Time, error) {
+	now = now.In(loc).Truncate(time.Minute)
+	input = strings.Join(strings.Fields(strings.ToLower(input)), " ")
+	if input == "" {
+		return time.Time{}, errUnknownDateTime
+	}
+
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.ParseInLocation(layout, input, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	if match := relativeDateTimeRegex.FindStringSubmatch(input); match != nil {
+		return parseRelativeDateTime(match, now)
+	}
+
+	datePart, clockPart := splitDateAndClock(input)
+	hour, minute := now.Hour(), now.Minute()
+	if clockPart != "" {
+		var ok bool
+		hour, minute, ok = parseClock(clockPart)
+		if !ok {
+			return time.Time{}, errUnknownDateTime
+		}
+	}
+	year, month, day, ok := parseDate(datePart, now, loc)
+	if !ok {
+		return time.Time{}, errUnknownDateTime
+	}
+	return time.Date(year, month, day, hour, minute, 0, 0, loc), nil
+}
+
+func parseRelativeDateTime(match []string, now time.Time) (time.Time, error) {
+	n := 1
+	if match[1] != "a" && match[1] != "an" {
+		var err error
+		if n, err = strconv.Atoi(match[1]); err != nil {
+			return time.Time{}, errUnknownDateTime
+		}
+	}
+	var t time.Time
+	switch match[2] {
+	case "min", "mins", "minute", "minutes":
+		t = now.Add(time.Duration(n) * time.Minute)
+	case "h", "hr", "hrs", "hour", "hours":
+		t = now.Add(time.Duration(n) * time.Hour)
+	case "d", "day", "days":
+		t = now.AddDate(0, 0, n)
+	case "w", "wk", "week", "weeks":
+		t = now.AddDate(0, 0, 7*n)
+	case "month", "months":
+		t = now.AddDate(0, n, 0)
+	}
+	if match[3] == "" {
+		return t, nil
+	}
+	hour, minute, ok := parseClock(match[3])
+	if !ok {
+		return time.Time{}, errUnknownDateTime
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), hour, minute, 0, 0, t.Location()), nil
+}
+
+// splitDateAndClock splits e.g. "next sat at 10:00" into "next sat" and "10:00"
+func splitDateAndClock(input string) (string, string) {
+	if i := strings.LastIndex(input, " at "); i >= 0 {
+		return input[:i], input[i+len(" at "):]
+	}
+	words := strings.Split(input, " ")
+	// the clock may be written as "7 pm"
+	if n := len(words); n >= 2 && (words[n-1] == "am" || words[n-1] == "pm") {
+		words = append(words[:n-2], words[n-2]+words[n-1])
+	}
+	last := words[len(words)-1]
+	if isClock(last) {
+		return strings.Join(words[:len(words)-1], " "), last
+	}
+	return input, ""
+}
+
+func isClock(s string) bool {
+	match := clockRegex.FindStringSubmatch(s)
+	if match == nil {
+		return s == "noon" || s == "midnight"
+	}
+	// a bare number is only a clock together with am/pm
+	return match[2] != "" || match[3] != ""
+}
+
+func parseClock(s string) (int, int, bool) {
+	switch s {
+	case "noon":
+		return 12, 0, true
+	case "midnight":
+		return 0, 0, true
+	}
+	match := clockRegex.FindStringSubmatch(strings.TrimSpace(s))
+	if match == nil {
+		return 0, 0, false
+	}
+	hour, _ := strconv.Atoi(match[1])
+	minute := 0
+	if match[2] != "" {
+		minute, _ = strconv.Atoi(match[2])
+	}
+	switch match[3] {
+	case "am":
+		if hour < 1 || hour > 12 {
+			return 0, 0, false
+		}
+		if hour == 12 {
+			hour = 0
+		}
+	case "pm":
+		if hour < 1 || hour > 12 {
+			return 0, 0, false
+		}
+		if hour != 12 {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return 0, 0, false
+	}
+	return hour, minute, true
+}
+
+func parseDate(s string, now time.Time, loc *time.Location) (int, time.Month, int, bool) {
+	switch s {
+	case "", "today", "tonight":
+		return now.Year(), now.Month(), now.Day(), true
+	case "tomorrow", "tmr", "tmrw":
+		t := now.AddDate(0, 0, 1)
+		return t.Year(), t.Month(), t.Day(), true
+	case "day after tomorrow":
+		t := now.AddDate(0, 0, 2)
+		return t.Year(), t.Month(), t.Day(), true
+	}
+
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t.Year(), t.Month(), t.Day(), true
+		}
+	}
+
+	words := strings.Split(s, " ")
+	if len(words) <= 2 {
+		weekdayName := words[len(words)-1]
+		if weekday, ok := weekdayNames[weekdayName]; ok {
+			days := (int(weekday) - int(now.Weekday()) + 7) % 7
+			switch {
+			case len(words) == 1 || words[0] == "this" || words[0] == "on":
+			case words[0] == "next":
+				if days == 0 {
+					days = 7
+				}
+			default:
+				return 0, 0, 0, false
+			}
+			t := now.AddDate(0, 0, days)
+			return t.Year(), t.Month(), t.Day(), true
+		}
+	}
+
+	var day, year int
+	var month time.Month
+	if match := dayMonthRegex.FindStringSubmatch(s); match != nil {
+		day, _ = strconv.Atoi(match[1])
+		m, _ := strconv.Atoi(match[2])
+		month = time.Month(m)
+		if match[3] != "" {
+			year, _ = strconv.Atoi(match[3])
+			if year < 100 {
+				year += 2000
+			}
+		}
+	} else if match := dayMonthNameRegex.FindStringSubmatch(s); match != nil {
+		day, _ = strconv.Atoi(match[1])
+		month = monthNames[match[2]]
+	} else if match := monthNameDayRegex.FindStringSubmatch(s); match != nil {
+		month = monthNames[match[1]]
+		day, _ = strconv.Atoi(match[2])
+	} else {
+		return 0, 0, 0, false
+	}
+	if month < time.January || month > time.December || day < 1 {
+		return 0, 0, 0, false
+	}
+
+	if year == 0 {
+		// without a year, take the next occurrence of the date
+		year = now.Year()
+		if time.Date(year, month, day, 0, 0, 0, 0, loc).Before(getBeginingOfDay(now)) {
+			year++
+		}
+	}
+	t := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	if t.Day() != day {
+		// e.g. 31/02
+		return 0, 0, 0, false
+	}
+	return year, month, day, true
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDateTime(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Singapore")
+	if err != nil {
+		t.Fatalf("Failed to load location: %v", err)
+	}
+	// Wednesday
+	now := time.Date(2025, 3, 5, 14, 27, 33, 0, loc)
+
+	tests := []struct {
+		input    string
+		expected time.Time
+	}{
+		{"2025-03-08 18:30", time.Date(2025, 3, 8, 18, 30, 0, 0, loc)},
+		{"2025-03-08", time.Date(2025, 3, 8, 14, 27, 0, 0, loc)},
+		{"tomorrow 7pm", time.Date(2025, 3, 6, 19, 0, 0, 0, loc)},
+		{"Tomorrow  7 PM", time.Date(2025, 3, 6, 19, 0, 0, 0, loc)},
+		{"today 12am", time.Date(2025, 3, 5, 0, 0, 0, 0, loc)},
+		{"tonight 8:15pm", time.Date(2025, 3, 5, 20, 15, 0, 0, loc)},
+		{"18:30", time.Date(2025, 3, 5, 18, 30, 0, 0, loc)},
+		{"sat 10:00", time.Date(2025, 3, 8, 10, 0, 0, 0, loc)},
+		{"next sat 10:00", time.Date(2025, 3, 8, 10, 0, 0, 0, loc)},
+		{"wed 10:00", time.Date(2025, 3, 5, 10, 0, 0, 0, loc)},
+		{"next wednesday 10:00", time.Date(2025, 3, 12, 10, 0, 0, 0, loc)},
+		{"next mon at noon", time.Date(2025, 3, 10, 12, 0, 0, 0, loc)},
+		{"25/12 18:30", time.Date(2025, 12, 25, 18, 30, 0, 0, loc)},
+		{"1/3 18:30", time.Date(2026, 3, 1, 18, 30, 0, 0, loc)},
+		{"25.12.26 18:30", time.Date(2026, 12, 25, 18, 30, 0, 0, loc)},
+		{"25 dec 7pm", time.Date(2025, 12, 25, 19, 0, 0, 0, loc)},
+		{"dec 25th at 19:00", time.Date(2025, 12, 25, 19, 0, 0, 0, loc)},
+		{"in 3 days", time.Date(2025, 3, 8, 14, 27, 0, 0, loc)},
+		{"in 2 hours", time.Date(2025, 3, 5, 16, 27, 0, 0, loc)},
+		{"in a week at 10am", time.Date(2025, 3, 12, 10, 0, 0, 0, loc)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := parseDateTime(tt.input, now, loc)
+			if err != nil {
+				t.Fatalf("parseDateTime(%q) failed: %v", tt.input, err)
+			}
+			if !result.Equal(tt.expected) {
+				t.Errorf("parseDateTime(%q) = %v; want %v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseDateTimeInvalid(t *testing.T) {
+	now := time.Date(2025, 3, 5, 14, 27, 0, 0, time.UTC)
+	for _, input := range []string{"", "someday", "31/02 10:00", "tomorrow 25:00", "13pm", "last sat", "in many days"} {
+		if result, err := parseDateTime(input, now, time.UTC); err == nil {
+			t.Errorf("parseDateTime(%q) = %v; want error", input, result)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// The date picker keeps its whole state in the callback data, e.g.
+// dp_m_202503 shows March 2025, dp_d_20250308 shows the hours of 8 March,
+// dp_h_2025030810 shows the minutes of 10 o'clock and dp_t_202503081030 picks the time
+const (
+	datePickerCallbackPrefix = "dp"
+	datePickerActionMonth    = "m"
+	datePickerActionDay      = "d"
+	datePickerActionHour     = "h"
+	datePickerActionTime     = "t"
+	datePickerActionNoop     = "x"
+
+	datePickerMonthFormat = "200601"
+	datePickerDayFormat   = "20060102"
+	datePickerHourFormat  = "2006010215"
+	datePickerTimeFormat  = "200601021504"
+
+	datePickerMinuteStep = 15
+)
+
+func getDatePickerCallbackData(action string, t time.Time, format string) string {
+	return strings.Join([]string{datePickerCallbackPrefix, action, t.Format(format)}, callbackSeparator)
+}
+
+func getDatePickerNoopButton(text string) models.InlineKeyboardButton {
+	return models.InlineKeyboardButton{
+		Text:         text,
+		CallbackData: strings.Join([]string{datePickerCallbackPrefix, datePickerActionNoop}, callbackSeparator),
+	}
+}
+
+// getDatePickerMonthRows returns a month grid with prev/next navigation
+func getDatePickerMonthRows(month time.Time, l *Localizer) [][]models.InlineKeyboardButton {
+	month = time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	rows := [][]models.InlineKeyboardButton{
+		{
+			{Text: "<", CallbackData: getDatePickerCallbackData(datePickerActionMonth, month.AddDate(0, -1, 0), datePickerMonthFormat)},
+			getDatePickerNoopButton(l.FormatMonth(month)),
+			{Text: ">", CallbackData: getDatePickerCallbackData(datePickerActionMonth, month.AddDate(0, 1, 0), datePickerMonthFormat)},
+		},
+	}
+	var weekdays []models.InlineKeyboardButton
+	for _, name := range l.language().PickerWeekdays {
+		weekdays = append(weekdays, getDatePickerNoopButton(name))
+	}
+	rows = append(rows, weekdays)
+
+	// weeks start on Monday
+	week := make([]models.InlineKeyboardButton, 0, 7)
+	for i := 0; i < (int(month.Weekday())+6)%7; i++ {
+		week = append(week, getDatePickerNoopButton(" "))
+	}
+	for day := month; day.Month() == month.Month(); day = day.AddDate(0, 0, 1) {
+		week = append(week, models.InlineKeyboardButton{
+			Text:         strconv.Itoa(day.Day()),
+			CallbackData: getDatePickerCallbackData(datePickerActionDay, day, datePickerDayFormat),
+		})
+		if len(week) == 7 {
+			rows = append(rows, week)
+			week = make([]models.InlineKeyboardButton, 0, 7)
+		}
+	}
+	if len(week) > 0 {
+		for len(week) < 7 {
+			week = append(week, getDatePickerNoopButton(" "))
+		}
+		rows = append(rows, week)
+	}
+	return rows
+}
+
+// getDatePickerHourRows returns the hours of the given day
+func getDatePickerHourRows(day time.Time, l *Localizer) [][]models.InlineKeyboardButton {
+	rows := [][]models.InlineKeyboardButton{
+		{getDatePickerNoopButton(l.FormatDate(day))},
+	}
+	var row []models.InlineKeyboardButton
+	for hour := 0; hour < 24; hour++ {
+		t := day.Add(time.Duration(hour) * time.Hour)
+		row = append(row, models.InlineKeyboardButton{
+			Text:         t.Format("15"),
+			CallbackData: getDatePickerCallbackData(datePickerActionHour, t, datePickerHourFormat),
+		})
+		if len(row) == 6 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	return append(rows, []models.InlineKeyboardButton{
+		{Text: l.T(msgButtonBack), CallbackData: getDatePickerCallbackData(datePickerActionMonth, day, datePickerMonthFormat)},
+	})
+}
+
+// getDatePickerMinuteRows returns the minutes of the given hour
+func getDatePickerMinuteRows(hour time.Time, l *Localizer) [][]models.InlineKeyboardButton {
+	rows := [][]models.InlineKeyboardButton{
+		{getDatePickerNoopButton(l.FormatDate(hour) + " " + hour.Format("15:__"))},
+	}
+	var row []models.InlineKeyboardButton
+	for minute := 0; minute < 60; minute += datePickerMinuteStep {
+		t := hour.Add(time.Duration(minute) * time.Minute)
+		row = append(row, models.InlineKeyboardButton{
+			Text:         t.Format("15:04"),
+			CallbackData: getDatePickerCallbackData(datePickerActionTime, t, datePickerTimeFormat),
+		})
+	}
+	return append(rows, row, []models.InlineKeyboardButton{
+		{Text: l.T(msgButtonBack), CallbackData: getDatePickerCallbackData(datePickerActionDay, hour, datePickerDayFormat)},
+	})
+}
+
+// handleDatePickerCallback navigates the date picker and submits the picked time to the user's wizard
+func (h *DefaultHandler) handleDatePickerCallback(ctx context.Context, b Messenger, update *models.Update) {
+	options := strings.Split(update.CallbackQuery.Data, callbackSeparator)
+	if len(options) < 2 || options[1] == datePickerActionNoop {
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			ShowAlert:       false,
+		})
+		return
+	}
+
+	chatID := update.CallbackQuery.Message.Message.Chat.ID
+	msgThreadID := update.CallbackQuery.Message.Message.MessageThreadID
+	messageID := update.CallbackQuery.Message.Message.ID
+	userStateKey := getUserStateKey(chatID, msgThreadID, &update.CallbackQuery.From)
+
+	var w *Wizard
+	userState, exists := h.userStates.Get(userStateKey)
+	if exists {
+		w = h.wizards[userState.StateType]
+	}
+	if w == nil || w.currentField(userState) == nil || !w.currentField(userState).DatePicker {
+		slog.WarnContext(ctx, "invalid user state for date picker callback", "key", userStateKey)
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			Text:            h.chatSettings.GetLocalizer(ctx, chatID).T(msgNotWaitingForTime),
+			ShowAlert:       true,
+		})
+		return
+	}
+
+	l := userState.localizer()
+	action, value := options[1], ""
+	if len(options) > 2 {
+		value = options[2]
+	}
+	var rows [][]models.InlineKeyboardButton
+	var err error
+	switch action {
+	case datePickerActionMonth:
+		var month time.Time
+		if month, err = time.Parse(datePickerMonthFormat, value); err == nil {
+			rows = getDatePickerMonthRows(month, l)
+		}
+	case datePickerActionDay:
+		var day time.Time
+		if day, err = time.Parse(datePickerDayFormat, value); err == nil {
+			rows = getDatePickerHourRows(day, l)
+		}
+	case datePickerActionHour:
+		var hour time.Time
+		if hour, err = time.Parse(datePickerHourFormat, value); err == nil {
+			rows = getDatePickerMinuteRows(hour, l)
+		}
+	case datePickerActionTime:
+		var t time.Time
+		if t, err = time.Parse(datePickerTimeFormat, value); err == nil {
+			b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+				CallbackQueryID: update.CallbackQuery.ID,
+				ShowAlert:       false,
+			})
+			// the picker is done, remove it from the prompt
+			_, err = b.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+				ChatID:      chatID,
+				MessageID:   messageID,
+				ReplyMarkup: &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{}},
+			})
+			if err != nil {
+				slog.ErrorContext(ctx, "error removing date picker", "err", err)
+			}
+			w.HandleInput(ctx, b, userStateKey, userState, WizardInput{Text: t.Format(timeFormat), User: &update.CallbackQuery.From})
+			return
+		}
+	default:
+		err = fmt.Errorf("unknown date picker action %s", action)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "invalid date picker callback", "data", update.CallbackQuery.Data, "err", err)
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			Text:            l.T(msgInvalidCallbackData),
+			ShowAlert:       true,
+		})
+		return
+	}
+
+	b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
+		ShowAlert:       false,
+	})
+	_, err = b.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+		ChatID:      chatID,
+		MessageID:   messageID,
+		ReplyMarkup: &models.InlineKeyboardMarkup{InlineKeyboard: append(rows, getCancelButtonRow(l))},
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "error editing date picker", "err", err)
+	}
+}
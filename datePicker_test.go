@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetDatePickerMonthRows(t *testing.T) {
+	// March 2025 starts on a Saturday and has 31 days
+	rows := getDatePickerMonthRows(time.Date(2025, 3, 18, 0, 0, 0, 0, time.UTC), NewLocalizer(langEnglish, time.UTC))
+
+	// navigation, weekday names and 6 weeks
+	if len(rows) != 8 {
+		t.Fatalf("Expected 8 rows, got %d", len(rows))
+	}
+	if rows[0][0].CallbackData != "dp_m_202502" || rows[0][2].CallbackData != "dp_m_202504" {
+		t.Errorf("Unexpected navigation %v", rows[0])
+	}
+	firstWeek := rows[2]
+	if len(firstWeek) != 7 || firstWeek[5].Text != "1" || firstWeek[5].CallbackData != "dp_d_20250301" {
+		t.Errorf("Expected 1 March on Saturday, got %v", firstWeek)
+	}
+	lastWeek := rows[7]
+	if lastWeek[0].Text != "31" || lastWeek[0].CallbackData != "dp_d_20250331" {
+		t.Errorf("Expected 31 March on Monday, got %v", lastWeek)
+	}
+	for _, row := range rows[2:] {
+		if len(row) != 7 {
+			t.Errorf("Expected 7 days in a week, got %d", len(row))
+		}
+		for _, button := range row {
+			if len(button.CallbackData) > 64 {
+				t.Errorf("Callback data too long: %s", button.CallbackData)
+			}
+		}
+	}
+}
+
+func TestGetDatePickerMinuteRows(t *testing.T) {
+	rows := getDatePickerMinuteRows(time.Date(2025, 3, 8, 10, 0, 0, 0, time.UTC), NewLocalizer(langEnglish, time.UTC))
+	minutes := rows[1]
+	if len(minutes) != 4 || minutes[2].CallbackData != "dp_t_202503081030" {
+		t.Errorf("Unexpected minutes %v", minutes)
+	}
+}
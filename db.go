@@ -1,77 +1,395 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
-	"log"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
-const (
-	target_db_version = 2
-)
+// Migration is one step in building the schema. Released migrations must not be
+// changed, their checksums are verified against the schema_migrations table
+type Migration struct {
+	Version     int
+	Description string
+	Queries     []string
+	// Func runs after Queries for changes which cannot be expressed in SQL
+	Func func(tx *sql.Tx, localTimezone *time.Location) error
+}
 
-var (
-	dbMigrationMap = map[int][]string{
-		1: {
+// dbMigrations build the schema from an empty database, in order
+var dbMigrations = []Migration{
+	{
+		Version:     1,
+		Description: "create events, event_users and activities",
+		Queries: []string{
+			`CREATE TABLE IF NOT EXISTS events (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				description TEXT,
+				options TEXT,
+				chat_id INTEGER,
+				message_id INTEGER,
+				created_by TEXT,
+				started_at DATETIME,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE TABLE IF NOT EXISTS event_users (
+				event_id INTEGER,
+				user TEXT,
+				option TEXT,
+				FOREIGN KEY(event_id) REFERENCES events(id)
+			)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_event_users ON event_users (event_id, user, option)`,
+			`CREATE TABLE IF NOT EXISTS activities (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL,
+				org TEXT NOT NULL,
+				lead TEXT NOT NULL,
+				co_leads TEXT,
+				started_at DATETIME NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+		},
+	},
+	{
+		Version:     2,
+		Description: "add activities.created_by",
+		Queries: []string{
 			`ALTER TABLE activities ADD COLUMN created_by TEXT DEFAULT 'S'`,
 		},
-		2: {
+	},
+	{
+		Version:     3,
+		Description: "add creator and voter user IDs, soft delete votes",
+		Queries: []string{
 			`ALTER TABLE activities ADD COLUMN created_by_id INTEGER DEFAULT 0`,
 			`ALTER TABLE events ADD COLUMN created_by_id INTEGER DEFAULT 0`,
 			`ALTER TABLE event_users ADD COLUMN user_id INTEGER DEFAULT 0`,
 			`ALTER TABLE event_users ADD COLUMN deleted BOOLEAN DEFAULT FALSE`,
 		},
+	},
+	{
+		Version:     4,
+		Description: "store start times as UTC instants",
+		Func:        migrateStartedAtToUTC,
+	},
+	{
+		Version:     5,
+		Description: "create chat_settings",
+		Queries: []string{
+			`CREATE TABLE IF NOT EXISTS chat_settings (
+				chat_id INTEGER PRIMARY KEY,
+				timezone TEXT,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`ALTER TABLE chat_settings ADD COLUMN language TEXT`,
+		},
+	},
+	{
+		Version:     6,
+		Description: "create user_states",
+		Queries: []string{
+			`CREATE TABLE IF NOT EXISTS user_states (
+				key TEXT PRIMARY KEY,
+				state TEXT NOT NULL,
+				expires_at INTEGER NOT NULL
+			)`,
+		},
+	},
+	{
+		// databases created before migrations covered table creation have either index
+		Version:     7,
+		Description: "include user_id in idx_event_users",
+		Queries: []string{
+			`DROP INDEX IF EXISTS idx_event_users`,
+			`CREATE UNIQUE INDEX idx_event_users ON event_users (event_id, user_id, user, option)`,
+		},
+	},
+	{
+		Version:     8,
+		Description: "create orgs",
+		Queries: []string{
+			`CREATE TABLE IF NOT EXISTS orgs (
+				name TEXT PRIMARY KEY,
+				display_name TEXT NOT NULL,
+				emoji TEXT NOT NULL DEFAULT '',
+				active INTEGER NOT NULL DEFAULT 1,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`INSERT OR IGNORE INTO orgs (name, display_name) VALUES ('CC', 'CC'), ('PEAK', 'PEAK')`,
+			`INSERT OR IGNORE INTO orgs (name, display_name) SELECT DISTINCT org, org FROM activities`,
+		},
+	},
+	{
+		Version:     9,
+		Description: "add activities.ends_at, location, description and status",
+		Queries: []string{
+			`ALTER TABLE activities ADD COLUMN ends_at DATETIME`,
+			`ALTER TABLE activities ADD COLUMN location TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE activities ADD COLUMN description TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE activities ADD COLUMN status TEXT NOT NULL DEFAULT 'planned'`,
+		},
+	},
+	{
+		Version:     10,
+		Description: "add events.activity_id",
+		Queries: []string{
+			`ALTER TABLE events ADD COLUMN activity_id INTEGER REFERENCES activities(id) ON DELETE SET NULL`,
+			`CREATE INDEX IF NOT EXISTS idx_events_activity_id ON events (activity_id)`,
+		},
+	},
+	{
+		Version:     11,
+		Description: "add activities.lead_id, co_lead_ids, reminded_at and known_users",
+		Queries: []string{
+			`ALTER TABLE activities ADD COLUMN lead_id INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE activities ADD COLUMN co_lead_ids TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE activities ADD COLUMN reminded_at DATETIME`,
+			`CREATE TABLE IF NOT EXISTS known_users (
+				id INTEGER PRIMARY KEY,
+				username TEXT NOT NULL DEFAULT '',
+				full_name TEXT NOT NULL DEFAULT '',
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_known_users_username ON known_users (username COLLATE NOCASE)`,
+		},
+	},
+	{
+		Version:     12,
+		Description: "create activity_leads and index activities by org",
+		Queries: []string{
+			`CREATE TABLE IF NOT EXISTS activity_leads (
+				activity_id INTEGER NOT NULL REFERENCES activities (id) ON DELETE CASCADE,
+				position INTEGER NOT NULL,
+				name TEXT NOT NULL,
+				user_id INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (activity_id, position)
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_activity_leads_name ON activity_leads (name COLLATE NOCASE)`,
+			`CREATE INDEX IF NOT EXISTS idx_activity_leads_user_id ON activity_leads (user_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_activities_org ON activities (org, started_at)`,
+		},
+		Func: migrateActivityLeads,
+	},
+	{
+		Version:     13,
+		Description: "add activities.deleted_at",
+		Queries: []string{
+			`ALTER TABLE activities ADD COLUMN deleted_at DATETIME`,
+			`CREATE INDEX IF NOT EXISTS idx_activities_deleted_at ON activities (deleted_at)`,
+		},
+	},
+	{
+		Version:     14,
+		Description: "create org_admins",
+		Queries: []string{
+			`CREATE TABLE IF NOT EXISTS org_admins (
+				org TEXT NOT NULL,
+				user_id INTEGER NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (org, user_id)
+			)`,
+		},
+	},
+}
+
+// legacyMigrationOffset maps the PRAGMA user_version of databases migrated before
+// schema_migrations existed to the last migration applied to them
+const legacyMigrationOffset = 1
+
+// latestSchemaVersion is the version of the last migration
+func latestSchemaVersion() int {
+	return dbMigrations[len(dbMigrations)-1].Version
+}
+
+// checksum identifies the content of a migration. The code of Func cannot be
+// hashed, so the description stands in for it
+func (m Migration) checksum() string {
+	h := sha256.New()
+	for _, q := range m.Queries {
+		h.Write([]byte(q))
+		h.Write([]byte{0})
 	}
-)
+	if m.Func != nil {
+		h.Write([]byte("func:" + m.Description))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// dbQuerier is a database or a transaction
+type dbQuerier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
 
-func MigrateDB(db *sql.DB) error {
-	setDbUserVersionQuery := "PRAGMA user_version = " + strconv.Itoa(target_db_version)
+// MigrationPlan is what MigrateDB is going to do
+type MigrationPlan struct {
+	// Baseline are migrations which a database from before schema_migrations
+	// already has; they are only recorded
+	Baseline []Migration
+	// Pending are migrations to apply
+	Pending []Migration
+}
 
-	// Check if the database is empty
-	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table'").Scan(&count)
+// PlanMigrations returns the migrations the database is missing without changing it.
+// It fails if an applied migration has changed since
+func PlanMigrations(q dbQuerier) (*MigrationPlan, error) {
+	var tableCount int
+	err := q.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`).Scan(&tableCount)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	// If database is empty, just set the version and return
-	// otherwise, check to migrate db
-	if count == 0 {
-		_, err = db.Exec(setDbUserVersionQuery)
-		return err
+	applied := make(map[int]string)
+	if tableCount > 0 {
+		if applied, err = appliedMigrations(q); err != nil {
+			return nil, err
+		}
+	}
+
+	plan := &MigrationPlan{}
+	baseline := make(map[int]bool)
+	if len(applied) == 0 {
+		// a database from before schema_migrations has tables and its version in user_version
+		err := q.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'
+			AND name NOT IN ('schema_migrations', 'sqlite_sequence')`).Scan(&tableCount)
+		if err != nil {
+			return nil, err
+		}
+		if tableCount > 0 {
+			var userVersion int
+			if err := q.QueryRow(`PRAGMA user_version`).Scan(&userVersion); err != nil {
+				return nil, err
+			}
+			for _, m := range dbMigrations {
+				if m.Version <= userVersion+legacyMigrationOffset {
+					plan.Baseline = append(plan.Baseline, m)
+					baseline[m.Version] = true
+				}
+			}
+		}
 	}
 
-	userVersion, err := GetDBVersion(db)
+	plan.Pending, err = pendingMigrations(dbMigrations, applied, baseline)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	log.Println("DB user version", userVersion, "Target version", target_db_version)
-	if userVersion >= target_db_version {
-		return nil
+	return plan, nil
+}
+
+// appliedMigrations maps the versions in schema_migrations to their checksums
+func appliedMigrations(q dbQuerier) (map[int]string, error) {
+	rows, err := q.Query(`SELECT version, checksum FROM schema_migrations`)
+	if err != nil {
+		return nil, err
 	}
-	queries := []string{}
-	for i := userVersion; i < target_db_version; i++ {
-		queries = append(queries, dbMigrationMap[i+1]...)
+	defer rows.Close()
+	applied := make(map[int]string)
+	for rows.Next() {
+		var version int
+		var checksum string
+		if err := rows.Scan(&version, &checksum); err != nil {
+			return nil, err
+		}
+		applied[version] = checksum
 	}
-	queries = append(queries, setDbUserVersionQuery)
-	log.Println("Migrating DB to version", target_db_version)
-	log.Println("Executing queries:\n", strings.Join(queries, "\n"))
+	return applied, rows.Err()
+}
+
+// pendingMigrations returns the migrations which are neither applied nor in the baseline.
+// applied maps the versions in schema_migrations to their checksums
+func pendingMigrations(migrations []Migration, applied map[int]string, baseline map[int]bool) ([]Migration, error) {
+	var pending []Migration
+	known := make(map[int]bool)
+	for _, m := range migrations {
+		known[m.Version] = true
+		checksum, ok := applied[m.Version]
+		if ok && checksum != m.checksum() {
+			return nil, fmt.Errorf("migration %d (%s) has changed since it was applied", m.Version, m.Description)
+		}
+		if !ok && !baseline[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	for version := range applied {
+		if !known[version] {
+			return nil, fmt.Errorf("database has unknown migration %d, it was migrated by a newer version", version)
+		}
+	}
+	return pending, nil
+}
+
+// MigrateDB applies the pending migrations in a single transaction.
+// localTimezone is the timezone user input times were stored in before migration 4
+func MigrateDB(db *sql.DB, localTimezone *time.Location) error {
 	tx, err := db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	for _, q := range queries {
-		_, err := tx.Exec(q)
-		if err != nil {
+	plan, err := PlanMigrations(tx)
+	if err != nil {
+		return err
+	}
+	if len(plan.Baseline) == 0 && len(plan.Pending) == 0 {
+		return nil
+	}
+	_, err = tx.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return err
+	}
+
+	const recordQuery = `INSERT INTO schema_migrations (version, description, checksum) VALUES (?, ?, ?)`
+	for _, m := range plan.Baseline {
+		slog.Info("recording existing migration", "version", m.Version, "description", m.Description)
+		if _, err := tx.Exec(recordQuery, m.Version, m.Description, m.checksum()); err != nil {
 			return err
 		}
 	}
-
-	if err := tx.Commit(); err != nil {
+	if err := applyMigrations(tx, plan.Pending, recordQuery, localTimezone); err != nil {
 		return err
 	}
+	// kept up to date for tools which only know user_version
+	if _, err := tx.Exec("PRAGMA user_version = " + strconv.Itoa(latestSchemaVersion())); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// applyMigrations runs the migrations and records each of them with recordQuery,
+// which takes the version, description and checksum
+func applyMigrations(tx *sql.Tx, migrations []Migration, recordQuery string, localTimezone *time.Location) error {
+	for _, m := range migrations {
+		slog.Info("applying migration", "version", m.Version, "description", m.Description)
+		for _, q := range m.Queries {
+			if _, err := tx.Exec(q); err != nil {
+				return fmt.Errorf("migration %d: %w", m.Version, err)
+			}
+		}
+		if m.Func != nil {
+			if err := m.Func(tx, localTimezone); err != nil {
+				return fmt.Errorf("migration %d: %w", m.Version, err)
+			}
+		}
+		if _, err := tx.Exec(recordQuery, m.Version, m.Description, m.checksum()); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -84,3 +402,129 @@ func GetDBVersion(db *sql.DB) (int, error) {
 	}
 	return userVersion, nil
 }
+
+// migrateStartedAtToUTC reinterprets the start times, which used to keep the local clock
+// in UTC, in the local timezone and stores them as real UTC instants
+func migrateStartedAtToUTC(tx *sql.Tx, localTimezone *time.Location) error {
+	for _, table := range []string{"events", "activities"} {
+		rows, err := tx.Query(`SELECT id, started_at FROM ` + table + ` WHERE started_at IS NOT NULL`)
+		if err != nil {
+			return err
+		}
+		startTimes := make(map[int64]time.Time)
+		for rows.Next() {
+			var id int64
+			var startedAt time.Time
+			if err := rows.Scan(&id, &startedAt); err != nil {
+				rows.Close()
+				return err
+			}
+			startTimes[id] = startedAt
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for id, t := range startTimes {
+			local := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), localTimezone)
+			if _, err := tx.Exec(`UPDATE `+table+` SET started_at = ? WHERE id = ?`, local.UTC(), id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// migrateActivityLeads fills activity_leads from the leads and co-leads of the existing activities
+func migrateActivityLeads(tx *sql.Tx, localTimezone *time.Location) error {
+	rows, err := tx.Query(`SELECT id, COALESCE(lead, ''), lead_id, COALESCE(co_leads, ''), co_lead_ids FROM activities`)
+	if err != nil {
+		return err
+	}
+	var activities []Activity
+	for rows.Next() {
+		var a Activity
+		var coLeads, coLeadIDs string
+		if err := rows.Scan(&a.ID, &a.Lead, &a.LeadID, &coLeads, &coLeadIDs); err != nil {
+			rows.Close()
+			return err
+		}
+		if coLeads != "" {
+			a.CoLeads = strings.Split(coLeads, ",")
+			a.CoLeadIDs = splitIDs(coLeadIDs, len(a.CoLeads))
+		}
+		activities = append(activities, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range activities {
+		if err := saveActivityLeads(tx, &activities[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BackupDB writes a consistent copy of the database to path, which must not exist yet
+func BackupDB(ctx context.Context, db *sql.DB, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("backup file %s already exists", path)
+	}
+	_, err := db.ExecContext(ctx, `VACUUM INTO ?`, path)
+	return err
+}
+
+// CheckDB runs the SQLite integrity and foreign key checks and verifies the migrations.
+// It returns the problems found, which is empty for a healthy database
+func CheckDB(ctx context.Context, db *sql.DB) ([]string, error) {
+	var problems []string
+	rows, err := db.QueryContext(ctx, `PRAGMA integrity_check`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if result != "ok" {
+			problems = append(problems, "integrity: "+result)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.QueryContext(ctx, `PRAGMA foreign_key_check`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var table, parent string
+		var rowID sql.NullInt64
+		var fkID int
+		if err := rows.Scan(&table, &rowID, &parent, &fkID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		problems = append(problems, fmt.Sprintf("foreign key: %s row %d references missing %s", table, rowID.Int64, parent))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	plan, err := PlanMigrations(db)
+	if err != nil {
+		problems = append(problems, "migrations: "+err.Error())
+	} else if len(plan.Baseline)+len(plan.Pending) > 0 {
+		problems = append(problems, fmt.Sprintf("schema: %d migrations pending, run eventpoll migrate", len(plan.Baseline)+len(plan.Pending)))
+	}
+	return problems, nil
+}
@@ -2,53 +2,188 @@ package main
 
 import (
 	"database/sql"
-	"os"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	_ "modernc.org/sqlite"
 )
 
+// legacyInitializeQueries are the tables the DAOs created on startup before
+// migrations covered table creation. Fresh databases got them at user_version 4
+var legacyInitializeQueries = []string{
+	`CREATE TABLE IF NOT EXISTS events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		description TEXT,
+		options TEXT,
+		chat_id INTEGER,
+		message_id INTEGER,
+		created_by TEXT,
+		created_by_id,
+		started_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE IF NOT EXISTS event_users (
+		event_id INTEGER,
+		user TEXT,
+		user_id INTEGER,
+		option TEXT,
+		deleted BOOLEAN DEFAULT FALSE,
+		FOREIGN KEY(event_id) REFERENCES events(id)
+	)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_event_users ON event_users (event_id, user_id, user, option)`,
+	`CREATE TABLE IF NOT EXISTS activities (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		org TEXT NOT NULL,
+		lead TEXT NOT NULL,
+		co_leads TEXT,
+		started_at DATETIME NOT NULL,
+		created_by TEXT,
+		created_by_id INTEGER,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE IF NOT EXISTS chat_settings (
+		chat_id INTEGER PRIMARY KEY,
+		timezone TEXT,
+		language TEXT,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE IF NOT EXISTS user_states (
+		key TEXT PRIMARY KEY,
+		state TEXT NOT NULL,
+		expires_at INTEGER NOT NULL
+	)`,
+}
+
+// setupTestDB opens an empty database
 func setupTestDB(t *testing.T) *sql.DB {
-	// Create a temporary database file
-	tmpfile, err := os.CreateTemp("", "testdb-*.db")
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
 	if err != nil {
-		t.Fatalf("Failed to create temp file: %v", err)
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	return db
+}
+
+func cleanupTestDB(t *testing.T, db *sql.DB) {
+	if err := db.Close(); err != nil {
+		t.Errorf("Failed to close database: %v", err)
+	}
+}
+
+// setupLegacyDB builds the schema a database migrated to userVersion had
+// before schema_migrations existed
+func setupLegacyDB(t *testing.T, userVersion int) *sql.DB {
+	db := setupTestDB(t)
+	for _, m := range dbMigrations {
+		if m.Version > userVersion+legacyMigrationOffset {
+			break
+		}
+		for _, q := range m.Queries {
+			if _, err := db.Exec(q); err != nil {
+				t.Fatalf("Failed to setup db for testing. query: %s, err: %v", q, err)
+			}
+		}
 	}
-	tmpfile.Close()
+	if _, err := db.Exec("PRAGMA user_version = " + strconv.Itoa(userVersion)); err != nil {
+		t.Fatalf("Failed to set user version: %v", err)
+	}
+	return db
+}
 
-	// Open the database
-	db, err := sql.Open("sqlite", tmpfile.Name())
+// dumpSchema describes the tables and indexes in a form which does not depend on
+// column order or on whether a column was added by ALTER TABLE
+func dumpSchema(t *testing.T, db *sql.DB) []string {
+	var schema []string
+	rows, err := db.Query(`SELECT type, name, tbl_name FROM sqlite_master
+		WHERE type IN ('table', 'index') AND name NOT LIKE 'sqlite_%' AND name != 'schema_migrations'`)
 	if err != nil {
-		t.Fatalf("Failed to open database: %v", err)
+		t.Fatalf("Failed to query schema: %v", err)
+	}
+	type object struct{ typ, name, table string }
+	var objects []object
+	for rows.Next() {
+		var o object
+		if err := rows.Scan(&o.typ, &o.name, &o.table); err != nil {
+			t.Fatalf("Failed to scan schema: %v", err)
+		}
+		objects = append(objects, o)
 	}
+	rows.Close()
 
-	// Create the activities table if it doesn't exist
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS activities (
-			id INTEGER PRIMARY KEY
-		)`,
-		`CREATE TABLE IF NOT EXISTS events (
-			id INTEGER PRIMARY KEY
-		)`,
-		`CREATE TABLE IF NOT EXISTS event_users (
-			id INTEGER PRIMARY KEY
-		)`,
-	}
-	for _, q := range queries {
-		_, err := db.Exec(q)
+	for _, o := range objects {
+		if o.typ == "table" {
+			var columns []string
+			rows, err := db.Query(`SELECT name, "notnull", pk FROM pragma_table_info(?)`, o.name)
+			if err != nil {
+				t.Fatalf("Failed to query columns of %s: %v", o.name, err)
+			}
+			for rows.Next() {
+				var name string
+				var notNull, pk int
+				if err := rows.Scan(&name, &notNull, &pk); err != nil {
+					t.Fatalf("Failed to scan column: %v", err)
+				}
+				columns = append(columns, fmt.Sprintf("%s notnull=%d pk=%d", name, notNull, pk))
+			}
+			rows.Close()
+			sort.Strings(columns)
+			schema = append(schema, "table "+o.name+": "+strings.Join(columns, ", "))
+			continue
+		}
+		var unique int
+		if err := db.QueryRow(`SELECT "unique" FROM pragma_index_list(?) WHERE name = ?`, o.table, o.name).Scan(&unique); err != nil {
+			t.Fatalf("Failed to query index %s: %v", o.name, err)
+		}
+		var columns []string
+		rows, err := db.Query(`SELECT name FROM pragma_index_info(?) ORDER BY seqno`, o.name)
 		if err != nil {
-			t.Fatalf("Failed to setup db for testing. query: %s, err: %v", q, err)
+			t.Fatalf("Failed to query index %s: %v", o.name, err)
+		}
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				t.Fatalf("Failed to scan index column: %v", err)
+			}
+			columns = append(columns, name)
 		}
+		rows.Close()
+		schema = append(schema, fmt.Sprintf("index %s on %s unique=%d: %s", o.name, o.table, unique, strings.Join(columns, ", ")))
 	}
+	sort.Strings(schema)
+	return schema
+}
 
-	return db
+func appliedVersions(t *testing.T, db *sql.DB) []int {
+	rows, err := db.Query(`SELECT version FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		t.Fatalf("Failed to query schema_migrations: %v", err)
+	}
+	defer rows.Close()
+	var versions []int
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			t.Fatalf("Failed to scan version: %v", err)
+		}
+		versions = append(versions, version)
+	}
+	return versions
 }
 
-func cleanupTestDB(t *testing.T, db *sql.DB) {
-	if err := db.Close(); err != nil {
-		t.Errorf("Failed to close database: %v", err)
+func allMigrationVersions() []int {
+	var versions []int
+	for _, m := range dbMigrations {
+		versions = append(versions, m.Version)
 	}
+	return versions
 }
 
 func TestGetDBVersion(t *testing.T) {
@@ -79,75 +214,218 @@ func TestGetDBVersion(t *testing.T) {
 	}
 }
 
+func TestMigrationVersionsAreOrdered(t *testing.T) {
+	for i, m := range dbMigrations {
+		if m.Version != i+1 {
+			t.Errorf("Expected migration %d to have version %d, got %d", i, i+1, m.Version)
+		}
+		if m.Description == "" {
+			t.Errorf("Migration %d has no description", m.Version)
+		}
+	}
+}
+
 func TestMigrateDB(t *testing.T) {
 	db := setupTestDB(t)
 	defer cleanupTestDB(t, db)
 
-	// Test migration from version 0 to 1
-	err := MigrateDB(db)
-	if err != nil {
-		t.Errorf("MigrateDB failed: %v", err)
+	if err := MigrateDB(db, time.UTC); err != nil {
+		t.Fatalf("MigrateDB failed: %v", err)
 	}
 
-	// Verify the migration was successful
 	version, err := GetDBVersion(db)
 	if err != nil {
 		t.Errorf("GetDBVersion failed: %v", err)
 	}
-	if version != target_db_version {
-		t.Errorf("Expected version to be %d, got %d", target_db_version, version)
+	if version != latestSchemaVersion() {
+		t.Errorf("Expected version to be %d, got %d", latestSchemaVersion(), version)
+	}
+	if got, want := appliedVersions(t, db), allMigrationVersions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected applied migrations %v, got %v", want, got)
+	}
+
+	// the tables the DAOs use are there
+	if _, err := NewActivityDAO(db).Save(&Activity{Name: "Hike", Org: OrgPEAK, Lead: "Alice", StartedAt: time.Now()}); err != nil {
+		t.Errorf("Failed to save activity: %v", err)
+	}
+	if err := NewChatSettingsDAO(db).SetTimezone(1, time.UTC); err != nil {
+		t.Errorf("Failed to set timezone: %v", err)
+	}
+}
+
+func TestMigrateDBNoMigrationNeeded(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	if err := MigrateDB(db, time.UTC); err != nil {
+		t.Fatalf("MigrateDB failed: %v", err)
+	}
+	// Try to migrate again
+	if err := MigrateDB(db, time.UTC); err != nil {
+		t.Errorf("MigrateDB failed: %v", err)
 	}
 
-	// Verify the column was added
-	rows, err := db.Query("PRAGMA table_info(activities)")
+	if got, want := appliedVersions(t, db), allMigrationVersions(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected applied migrations %v, got %v", want, got)
+	}
+	plan, err := PlanMigrations(db)
 	if err != nil {
-		t.Fatalf("Failed to query table info: %v", err)
+		t.Fatalf("PlanMigrations failed: %v", err)
 	}
-	defer rows.Close()
+	if len(plan.Baseline) != 0 || len(plan.Pending) != 0 {
+		t.Errorf("Expected nothing to do, got %+v", plan)
+	}
+}
 
-	var columnName string
-	var found bool
-	for rows.Next() {
-		var cid, notnull, pk int
-		var dtype string
-		var dflt_value sql.NullString
-		err := rows.Scan(&cid, &columnName, &dtype, &notnull, &dflt_value, &pk)
-		if err != nil {
-			t.Fatalf("Failed to scan row: %v", err)
-		}
-		if columnName == "created_by" {
-			found = true
-			break
-		}
+func TestMigrateDBUpgradedSchemaMatchesFresh(t *testing.T) {
+	fresh := setupTestDB(t)
+	defer cleanupTestDB(t, fresh)
+	if err := MigrateDB(fresh, time.UTC); err != nil {
+		t.Fatalf("MigrateDB of fresh db failed: %v", err)
 	}
+	want := dumpSchema(t, fresh)
 
-	if !found {
-		t.Error("Column 'created_by' was not found after migration")
+	tests := []struct {
+		name string
+		db   func(t *testing.T) *sql.DB
+	}{
+		{"legacy version 0", func(t *testing.T) *sql.DB { return setupLegacyDB(t, 0) }},
+		{"legacy version 2", func(t *testing.T) *sql.DB { return setupLegacyDB(t, 2) }},
+		{"legacy version 4 created by the DAOs", func(t *testing.T) *sql.DB {
+			db := setupTestDB(t)
+			for _, q := range legacyInitializeQueries {
+				if _, err := db.Exec(q); err != nil {
+					t.Fatalf("Failed to setup db for testing. query: %s, err: %v", q, err)
+				}
+			}
+			if _, err := db.Exec("PRAGMA user_version = 4"); err != nil {
+				t.Fatalf("Failed to set user version: %v", err)
+			}
+			return db
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := tt.db(t)
+			defer cleanupTestDB(t, db)
+			if err := MigrateDB(db, time.UTC); err != nil {
+				t.Fatalf("MigrateDB failed: %v", err)
+			}
+			if got := dumpSchema(t, db); !reflect.DeepEqual(got, want) {
+				t.Errorf("Upgraded schema differs from fresh schema\ngot:  %q\nwant: %q", got, want)
+			}
+			if got, want := appliedVersions(t, db), allMigrationVersions(); !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected applied migrations %v, got %v", want, got)
+			}
+		})
 	}
 }
 
-func TestMigrateDBNoMigrationNeeded(t *testing.T) {
-	db := setupTestDB(t)
+func TestPlanMigrationsDoesNotChangeDB(t *testing.T) {
+	db := setupLegacyDB(t, 2)
 	defer cleanupTestDB(t, db)
+	before := dumpSchema(t, db)
 
-	// Set version to target version
-	_, err := db.Exec("PRAGMA user_version = " + strconv.Itoa(target_db_version))
+	plan, err := PlanMigrations(db)
 	if err != nil {
-		t.Fatalf("Failed to set user version: %v", err)
+		t.Fatalf("PlanMigrations failed: %v", err)
+	}
+	var baseline, pending []int
+	for _, m := range plan.Baseline {
+		baseline = append(baseline, m.Version)
+	}
+	for _, m := range plan.Pending {
+		pending = append(pending, m.Version)
+	}
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(baseline, want) {
+		t.Errorf("Expected baseline %v, got %v", want, baseline)
+	}
+	if want := []int{4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14}; !reflect.DeepEqual(pending, want) {
+		t.Errorf("Expected pending %v, got %v", want, pending)
 	}
+	if after := dumpSchema(t, db); !reflect.DeepEqual(after, before) {
+		t.Errorf("PlanMigrations changed the schema\nbefore: %q\nafter:  %q", before, after)
+	}
+}
 
-	// Try to migrate again
-	err = MigrateDB(db)
+func TestMigrateDBChangedMigration(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+	if err := MigrateDB(db, time.UTC); err != nil {
+		t.Fatalf("MigrateDB failed: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE schema_migrations SET checksum = 'edited' WHERE version = 2`); err != nil {
+		t.Fatalf("Failed to edit checksum: %v", err)
+	}
+
+	err := MigrateDB(db, time.UTC)
+	if err == nil || !strings.Contains(err.Error(), "migration 2") {
+		t.Errorf("Expected an error about migration 2, got %v", err)
+	}
+}
+
+func TestMigrateDBStartedAtToUTC(t *testing.T) {
+	// local clock stored as UTC before migration 4
+	db := setupLegacyDB(t, 2)
+	defer cleanupTestDB(t, db)
+
+	loc, err := time.LoadLocation("Europe/Berlin")
 	if err != nil {
-		t.Errorf("MigrateDB failed: %v", err)
+		t.Fatalf("Failed to load location: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO activities (id, name, org, lead, started_at) VALUES (1, 'Hike', 'PEAK', 'Alice', ?)", time.Date(2025, 7, 1, 19, 30, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Failed to insert activity: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO events (id, started_at) VALUES (1, ?), (2, NULL)", time.Date(2025, 1, 1, 19, 30, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Failed to insert events: %v", err)
 	}
 
-	// Verify version hasn't changed
-	version, err := GetDBVersion(db)
+	if err := MigrateDB(db, loc); err != nil {
+		t.Fatalf("MigrateDB failed: %v", err)
+	}
+
+	var activityStartedAt, eventStartedAt time.Time
+	if err := db.QueryRow("SELECT started_at FROM activities WHERE id = 1").Scan(&activityStartedAt); err != nil {
+		t.Fatalf("Failed to query activity: %v", err)
+	}
+	if expected := time.Date(2025, 7, 1, 17, 30, 0, 0, time.UTC); !activityStartedAt.Equal(expected) {
+		t.Errorf("Expected activity to start at %v, got %v", expected, activityStartedAt)
+	}
+	if err := db.QueryRow("SELECT started_at FROM events WHERE id = 1").Scan(&eventStartedAt); err != nil {
+		t.Fatalf("Failed to query event: %v", err)
+	}
+	if expected := time.Date(2025, 1, 1, 18, 30, 0, 0, time.UTC); !eventStartedAt.Equal(expected) {
+		t.Errorf("Expected event to start at %v, got %v", expected, eventStartedAt)
+	}
+}
+
+func TestMigrateDBActivityLeads(t *testing.T) {
+	db := setupLegacyDB(t, 2)
+	defer cleanupTestDB(t, db)
+	if _, err := db.Exec("INSERT INTO activities (id, name, org, lead, co_leads, started_at) VALUES (1, 'Hike', 'PEAK', 'Alice', 'Bob,Carol', ?), (2, 'Quiz', 'CC', 'Dave', NULL, ?)",
+		time.Date(2025, 7, 1, 19, 30, 0, 0, time.UTC), time.Date(2025, 7, 2, 19, 30, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("Failed to insert activities: %v", err)
+	}
+
+	if err := MigrateDB(db, time.UTC); err != nil {
+		t.Fatalf("MigrateDB failed: %v", err)
+	}
+
+	rows, err := db.Query("SELECT activity_id, position, name FROM activity_leads ORDER BY activity_id, position")
 	if err != nil {
-		t.Errorf("GetDBVersion failed: %v", err)
+		t.Fatalf("Failed to query activity leads: %v", err)
+	}
+	defer rows.Close()
+	var leads []string
+	for rows.Next() {
+		var activityID, position int64
+		var name string
+		if err := rows.Scan(&activityID, &position, &name); err != nil {
+			t.Fatalf("Failed to scan activity lead: %v", err)
+		}
+		leads = append(leads, fmt.Sprintf("%d/%d %s", activityID, position, name))
 	}
-	if version != target_db_version {
-		t.Errorf("Expected version to be %d, got %d", target_db_version, version)
+	if want := []string{"1/0 Alice", "1/1 Bob", "1/2 Carol", "2/0 Dave"}; !reflect.DeepEqual(leads, want) {
+		t.Errorf("Expected activity leads %v, got %v", want, leads)
 	}
 }
@@ -2,21 +2,32 @@ package main
 
 import (
 	"context"
+	"log/slog"
+	"strings"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 )
 
+const (
+	cancelStateCallbackPrefix = "cancelState"
+)
+
 type DefaultHandler struct {
-	createEventHandler *CreateEventHandler
-	activityHandler    *ActivityHandler
+	userStates   StateStore
+	chatSettings ChatSettingsRepository
+	wizards      map[StateType]*Wizard
 }
 
-func NewDefaultHandler(createEventHandler *CreateEventHandler, activityHandler *ActivityHandler) *DefaultHandler {
-	return &DefaultHandler{createEventHandler: createEventHandler, activityHandler: activityHandler}
+func NewDefaultHandler(userStates StateStore, chatSettings ChatSettingsRepository, wizards ...*Wizard) *DefaultHandler {
+	h := &DefaultHandler{userStates: userStates, chatSettings: chatSettings, wizards: make(map[StateType]*Wizard)}
+	for _, w := range wizards {
+		h.wizards[w.StateType] = w
+	}
+	return h
 }
 
-func (h *DefaultHandler) handle(ctx context.Context, b *bot.Bot, update *models.Update) {
+func (h *DefaultHandler) handle(ctx context.Context, b Messenger, update *models.Update) {
 	if update.Message == nil {
 		return
 	}
@@ -24,22 +35,133 @@ func (h *DefaultHandler) handle(ctx context.Context, b *bot.Bot, update *models.
 	chatID := update.Message.Chat.ID
 	msgThreadID := update.Message.MessageThreadID
 	userStateKey := getUserStateKey(chatID, msgThreadID, update.Message.From)
-	userState, exists := userStates[userStateKey]
+	userState, exists := h.userStates.Get(userStateKey)
 
 	if !exists {
 		return
 	}
 
-	switch userState.StateType {
-	case CREATE_EVENT:
-		h.createEventHandler.handleCreateEvent(ctx, b, update, userStateKey)
-	case UPDATE_EVENT:
-		h.createEventHandler.handleUpdatePollInput(ctx, b, update, userStateKey, userState)
-	case ADD_ACTIVITY:
-		h.activityHandler.handleAddActivitySteps(ctx, b, update, userStateKey, userState)
-	case UPDATE_ACTIVITY:
-		h.activityHandler.handleUpdateActivitySteps(ctx, b, update, userStateKey, userState)
-	case DELETE_ACTIVITY:
-		h.activityHandler.handleDeleteActivitySteps(ctx, b, update, userStateKey, userState)
+	w, ok := h.wizards[userState.StateType]
+	if !ok {
+		slog.WarnContext(ctx, "no wizard for state", "state_type", userState.StateType)
+		return
+	}
+	w.HandleInput(ctx, b, userStateKey, userState, WizardInput{
+		Text:      update.Message.Text,
+		User:      update.Message.From,
+		Entities:  update.Message.Entities,
+		Document:  update.Message.Document,
+		messenger: b,
+	})
+}
+
+// handleWizardInputCallback submits the input of a button returned by WizardField.Keyboard,
+// or of the buttons confirming a WizardField.Confirm warning, to the user's wizard
+func (h *DefaultHandler) handleWizardInputCallback(ctx context.Context, b Messenger, update *models.Update) {
+	chatID := update.CallbackQuery.Message.Message.Chat.ID
+	msgThreadID := update.CallbackQuery.Message.Message.MessageThreadID
+	userStateKey := getUserStateKey(chatID, msgThreadID, &update.CallbackQuery.From)
+	input := strings.TrimPrefix(update.CallbackQuery.Data, wizardInputCallbackPrefix+callbackSeparator)
+
+	var w *Wizard
+	userState, exists := h.userStates.Get(userStateKey)
+	if exists {
+		w = h.wizards[userState.StateType]
+	}
+	if w == nil || w.currentField(userState) == nil || (w.currentField(userState).Keyboard == nil && !userState.Confirming) {
+		slog.WarnContext(ctx, "invalid user state for wizard input callback", "key", userStateKey)
+		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			Text:            h.chatSettings.GetLocalizer(ctx, chatID).T(msgStateExpired),
+			ShowAlert:       true,
+		})
+		return
+	}
+
+	b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
+		ShowAlert:       false,
+	})
+	// the buttons are answered, remove them from the prompt
+	_, err := b.EditMessageReplyMarkup(ctx, &bot.EditMessageReplyMarkupParams{
+		ChatID:      chatID,
+		MessageID:   update.CallbackQuery.Message.Message.ID,
+		ReplyMarkup: &models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{}},
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "error removing wizard buttons", "err", err)
+	}
+	w.HandleInput(ctx, b, userStateKey, userState, WizardInput{Text: input, User: &update.CallbackQuery.From})
+}
+
+func (h *DefaultHandler) handleCancel(ctx context.Context, b Messenger, update *models.Update) {
+	chatID := update.Message.Chat.ID
+	msgThreadID := update.Message.MessageThreadID
+	h.cancel(ctx, b, chatID, msgThreadID, update.Message.From)
+}
+
+func (h *DefaultHandler) handleCancelCallback(ctx context.Context, b Messenger, update *models.Update) {
+	b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
+		ShowAlert:       false,
+	})
+	chatID := update.CallbackQuery.Message.Message.Chat.ID
+	msgThreadID := update.CallbackQuery.Message.Message.MessageThreadID
+	h.cancel(ctx, b, chatID, msgThreadID, &update.CallbackQuery.From)
+}
+
+func (h *DefaultHandler) cancel(ctx context.Context, b Messenger, chatID int64, msgThreadID int, user *models.User) {
+	userStateKey := getUserStateKey(chatID, msgThreadID, user)
+	text := h.chatSettings.GetLocalizer(ctx, chatID).T(msgNothingToCancel)
+	if userState, exists := h.userStates.Get(userStateKey); exists {
+		if err := h.userStates.Delete(userStateKey); err != nil {
+			slog.ErrorContext(ctx, "error deleting user state", "key", userStateKey, "err", err)
+		}
+		text = userState.localizer().T(msgCancelled)
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          chatID,
+		MessageThreadID: msgThreadID,
+		Text:            text,
+	})
+}
+
+func (h *DefaultHandler) handleBack(ctx context.Context, b Messenger, update *models.Update) {
+	chatID := update.Message.Chat.ID
+	msgThreadID := update.Message.MessageThreadID
+	userStateKey := getUserStateKey(chatID, msgThreadID, update.Message.From)
+	var w *Wizard
+	userState, exists := h.userStates.Get(userStateKey)
+	if exists {
+		w = h.wizards[userState.StateType]
+	}
+	if w == nil {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: msgThreadID,
+			Text:            h.chatSettings.GetLocalizer(ctx, chatID).T(msgNothingToGoBack),
+		})
+		return
+	}
+	if !w.Back(ctx, b, userStateKey, userState) {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: msgThreadID,
+			Text:            userState.localizer().T(msgAlreadyFirstStep),
+			ReplyMarkup:     getCancelKeyboard(userState.localizer()),
+		})
+	}
+}
+
+// getCancelKeyboard returns the inline keyboard attached to every prompt of a multi-step flow
+func getCancelKeyboard(l *Localizer) *models.InlineKeyboardMarkup {
+	return &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{getCancelButtonRow(l)},
+	}
+}
+
+func getCancelButtonRow(l *Localizer) []models.InlineKeyboardButton {
+	return []models.InlineKeyboardButton{
+		{Text: l.T(msgButtonCancel), CallbackData: cancelStateCallbackPrefix},
 	}
 }
@@ -3,7 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 
 	"github.com/go-telegram/bot"
@@ -16,14 +16,14 @@ const (
 	callbackPostFixOut  = "OUT"
 )
 
-func (e *Event) String() string {
-	msg := fmt.Sprintf("*Description:* %s", e.Description)
+func (e *Event) string(l *Localizer) string {
+	msg := l.T(msgEventDescription, e.Description)
 	if e.StartedAt != nil {
-		msg += fmt.Sprintf("\n*Starts at:* %s", e.StartedAt.Format(displayTimeFormat))
+		msg += "\n" + l.T(msgEventStartsAt, l.FormatTime(*e.StartedAt))
 	} else {
-		msg += "\n*Starts at:* Not set"
+		msg += "\n" + l.T(msgEventStartsAt, l.T(msgNotSet))
 	}
-	msg += "\n*Options:*\n"
+	msg += "\n" + l.T(msgEventOptions) + "\n"
 	msg += "• " + strings.Join(e.Options, "\n• ")
 	return msg
 }
@@ -40,7 +40,7 @@ type EventAndUsers struct {
 	OptionUsers map[string][]string
 }
 
-func (e *EventAndUsers) GetPollMessage() (string, *models.InlineKeyboardMarkup) {
+func (e *EventAndUsers) GetPollMessage(l *Localizer) (string, *models.InlineKeyboardMarkup) {
 	inlineKeyboard := make([][]models.InlineKeyboardButton, 0)
 	for _, option := range e.Options {
 		inlineKeyboard = append(inlineKeyboard, []models.InlineKeyboardButton{
@@ -51,9 +51,9 @@ func (e *EventAndUsers) GetPollMessage() (string, *models.InlineKeyboardMarkup)
 		InlineKeyboard: inlineKeyboard,
 	}
 
-	msg := fmt.Sprintf("*Please cast your votes*\n%s\n", e.Description)
+	msg := l.T(msgPollTitle) + "\n" + e.Description + "\n"
 	if e.StartedAt != nil {
-		msg += fmt.Sprintf("*Start Time:* %s\n", e.StartedAt.Format(displayTimeFormat))
+		msg += l.T(msgPollStartTime, l.FormatTime(*e.StartedAt)) + "\n"
 	}
 	for _, option := range e.Options {
 		msg += fmt.Sprintf("*%s*:\n", option)
@@ -62,8 +62,8 @@ func (e *EventAndUsers) GetPollMessage() (string, *models.InlineKeyboardMarkup)
 	return msg, kb
 }
 
-func sendEventPoll(ctx context.Context, b *bot.Bot, chatID any, messageThreadID int, event Event, users []EventUser) int {
-	msgText, kb := getPollParams(event, users)
+func sendEventPoll(ctx context.Context, b Messenger, chatID any, messageThreadID int, event Event, users []EventUser, l *Localizer) int {
+	msgText, kb := getPollParams(event, users, l)
 	msg, err := b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:          chatID,
 		MessageThreadID: messageThreadID,
@@ -72,14 +72,54 @@ func sendEventPoll(ctx context.Context, b *bot.Bot, chatID any, messageThreadID
 		ParseMode:       "Markdown",
 	})
 	if err != nil {
-		log.Println("Error sending event poll to", chatID, err)
+		slog.ErrorContext(ctx, "error sending event poll", "event_id", event.ID, "target_chat_id", chatID, "err", err)
 		return 0
 	}
-	log.Println("event poll", event.ID, "has been sent to chatID", chatID, "messageThreadID", messageThreadID)
+	slog.InfoContext(ctx, "event poll sent", "event_id", event.ID, "target_chat_id", chatID, "message_thread_id", messageThreadID)
 	return msg.ID
 }
 
-func getPollParams(event Event, users []EventUser) (string, *models.InlineKeyboardMarkup) {
+// refreshPollMessage re-renders the poll message of the event with its current details and votes
+func refreshPollMessage(ctx context.Context, b Messenger, events EventRepository, l *Localizer, event *Event) error {
+	if event.MessageID == 0 {
+		// not sent yet
+		return nil
+	}
+	users, err := events.GetEventUsers(event.ID)
+	if err != nil {
+		return err
+	}
+	msgText, kb := getPollParams(*event, users, l)
+	_, err = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      event.ChatID,
+		MessageID:   event.MessageID,
+		Text:        msgText,
+		ReplyMarkup: kb,
+		ParseMode:   "Markdown",
+	})
+	return err
+}
+
+// closePollMessage replaces the poll message of a deleted event so nobody votes on it
+func closePollMessage(ctx context.Context, b Messenger, l *Localizer, event *Event) error {
+	if event.MessageID == 0 {
+		return nil
+	}
+	_, err := b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    event.ChatID,
+		MessageID: event.MessageID,
+		Text:      l.T(msgPollDeleted),
+	})
+	return err
+}
+
+func getPollParams(event Event, users []EventUser, l *Localizer) (string, *models.InlineKeyboardMarkup) {
+	eventAndUsers := getEventAndUsers(event, users)
+	return eventAndUsers.GetPollMessage(l)
+}
+
+// getEventAndUsers groups the voters by option
+func getEventAndUsers(event Event, users []EventUser) EventAndUsers {
 	eventAndUsers := EventAndUsers{
 		Event:       event,
 		OptionUsers: make(map[string][]string),
@@ -87,5 +127,5 @@ func getPollParams(event Event, users []EventUser) (string, *models.InlineKeyboa
 	for _, user := range users {
 		eventAndUsers.OptionUsers[user.Option] = append(eventAndUsers.OptionUsers[user.Option], user.User)
 	}
-	return eventAndUsers.GetPollMessage()
+	return eventAndUsers
 }
@@ -2,7 +2,6 @@ package main
 
 import (
 	"database/sql"
-	"fmt"
 	"strings"
 	"time"
 
@@ -19,8 +18,10 @@ type Event struct {
 	CreatedBy   string
 	CreatedByID int64
 	StartedAt   *time.Time
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	// ActivityID is the workplan activity the event is the poll of, 0 if none
+	ActivityID int64
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
 }
 
 type EventUser struct {
@@ -30,55 +31,20 @@ type EventUser struct {
 	Option  string
 }
 
-// DAO layer for Event and EventUser
-type EventDAO struct {
-	db *sql.DB
-}
-
-func NewEventDAO(db *sql.DB) *EventDAO {
-	return &EventDAO{db: db}
+// rowScanner is a *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
 }
 
-func (dao *EventDAO) Initialize() error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS events (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			description TEXT,
-			options TEXT,
-			chat_id INTEGER,
-			message_id INTEGER,
-			created_by TEXT,
-			created_by_id,
-			started_at DATETIME,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE TABLE IF NOT EXISTS event_users (
-			event_id INTEGER,
-			user TEXT,
-			user_id INTEGER,
-			option TEXT,
-			deleted BOOLEAN DEFAULT FALSE,
-			FOREIGN KEY(event_id) REFERENCES events(id)
-		)`,
-		`CREATE UNIQUE INDEX IF NOT EXISTS idx_event_users ON event_users (event_id, user_id, user, option)`,
-	}
-
-	for _, q := range queries {
-		_, err := dao.db.Exec(q)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
+// eventColumns are the columns read by scanEvent
+const eventColumns = `id, description, options, chat_id, message_id, created_by, created_by_id,
+	started_at, activity_id, created_at, updated_at`
 
-func (dao *EventDAO) GetEventByID(eventID int64) (*Event, error) {
-	query := `SELECT id, description, options, chat_id, message_id, created_by, created_by_id,
-		started_at, created_at, updated_at FROM events WHERE id = ?`
-	row := dao.db.QueryRow(query, eventID)
+// scanEvent scans the eventColumns
+func scanEvent(row rowScanner) (*Event, error) {
 	event := &Event{}
 	var optionsStr string
+	var activityID sql.NullInt64
 	err := row.Scan(
 		&event.ID,
 		&event.Description,
@@ -88,18 +54,69 @@ func (dao *EventDAO) GetEventByID(eventID int64) (*Event, error) {
 		&event.CreatedBy,
 		&event.CreatedByID,
 		&event.StartedAt,
+		&activityID,
 		&event.CreatedAt,
 		&event.UpdatedAt,
 	)
 	if err != nil {
 		return nil, err
 	}
+	event.ActivityID = activityID.Int64
 	if optionsStr != "" {
 		event.Options = strings.Split(optionsStr, ";")
 	}
 	return event, nil
 }
 
+// nullID returns the ID as a database value, NULL if it is 0
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
+
+func scanEvents(rows *sql.Rows) ([]*Event, error) {
+	defer rows.Close()
+
+	var events []*Event
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// scanEventUsers scans the columns event_id, user, option and user_id
+func scanEventUsers(rows *sql.Rows) ([]EventUser, error) {
+	defer rows.Close()
+
+	var eventUsers []EventUser
+	for rows.Next() {
+		var eventUser EventUser
+		err := rows.Scan(&eventUser.EventID, &eventUser.User, &eventUser.Option, &eventUser.UserID)
+		if err != nil {
+			return nil, err
+		}
+		eventUsers = append(eventUsers, eventUser)
+	}
+	return eventUsers, rows.Err()
+}
+
+// EventDAO is the EventRepository kept in SQLite
+type EventDAO struct {
+	db *sql.DB
+}
+
+func NewEventDAO(db *sql.DB) *EventDAO {
+	return &EventDAO{db: db}
+}
+
+func (dao *EventDAO) GetEventByID(eventID int64) (*Event, error) {
+	query := `SELECT ` + eventColumns + ` FROM events WHERE id = ?`
+	return scanEvent(dao.db.QueryRow(query, eventID))
+}
+
 func (dao *EventDAO) GetEventsByIDs(eventIDs []int64) ([]*Event, error) {
 	if len(eventIDs) == 0 {
 		return nil, nil
@@ -112,42 +129,22 @@ func (dao *EventDAO) GetEventsByIDs(eventIDs []int64) ([]*Event, error) {
 		args[i] = id
 	}
 	placeholderStr := strings.Join(placeholders, ",")
-	query := `SELECT id, description, options, chat_id, message_id, created_by, created_by_id,
-		started_at, created_at, updated_at FROM events WHERE id IN (` + placeholderStr + `)`
+	query := `SELECT ` + eventColumns + ` FROM events WHERE id IN (` + placeholderStr + `)`
 	rows, err := dao.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return scanEvents(rows)
+}
 
-	var events []*Event
-	for rows.Next() {
-		var event Event
-		var optionsStr string
-		err := rows.Scan(
-			&event.ID,
-			&event.Description,
-			&optionsStr,
-			&event.ChatID,
-			&event.MessageID,
-			&event.CreatedBy,
-			&event.CreatedByID,
-			&event.StartedAt,
-			&event.CreatedAt,
-			&event.UpdatedAt,
-		)
-		if err != nil {
-			return nil, err
-		}
-		if optionsStr != "" {
-			event.Options = strings.Split(optionsStr, ";")
-		}
-		events = append(events, &event)
-	}
-	if err := rows.Err(); err != nil {
+// ListEvents returns the most recently created events first
+func (dao *EventDAO) ListEvents(limit int) ([]*Event, error) {
+	query := `SELECT ` + eventColumns + ` FROM events ORDER BY id DESC LIMIT ?`
+	rows, err := dao.db.Query(query, limit)
+	if err != nil {
 		return nil, err
 	}
-	return events, nil
+	return scanEvents(rows)
 }
 
 func (dao *EventDAO) SaveEvent(event *Event) (int64, error) {
@@ -155,8 +152,8 @@ func (dao *EventDAO) SaveEvent(event *Event) (int64, error) {
 
 	query := `INSERT INTO events (
 		description, options, chat_id, message_id, created_by, created_by_id,
-		started_at, created_at, updated_at
-	) VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
+		started_at, activity_id, created_at, updated_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
 
 	result, err := dao.db.Exec(
 		query,
@@ -167,6 +164,7 @@ func (dao *EventDAO) SaveEvent(event *Event) (int64, error) {
 		event.CreatedBy,
 		event.CreatedByID,
 		event.StartedAt,
+		nullID(event.ActivityID),
 	)
 	if err != nil {
 		return 0, err
@@ -179,7 +177,7 @@ func (dao *EventDAO) UpdateEvent(event *Event) error {
 
 	query := `UPDATE events 
 		SET description = ?, options = ?, chat_id = ?, message_id = ?, created_by = ?, created_by_id = ?,
-		started_at = ?, updated_at = CURRENT_TIMESTAMP 
+		started_at = ?, activity_id = ?, updated_at = CURRENT_TIMESTAMP 
 		WHERE id = ?`
 	_, err := dao.db.Exec(query,
 		event.Description,
@@ -189,11 +187,34 @@ func (dao *EventDAO) UpdateEvent(event *Event) error {
 		event.CreatedBy,
 		event.CreatedByID,
 		event.StartedAt,
+		nullID(event.ActivityID),
 		event.ID,
 	)
 	return err
 }
 
+// DeleteEvent removes an event together with its votes and returns the number of deleted events
+func (dao *EventDAO) DeleteEvent(eventID int64) (int64, error) {
+	tx, err := dao.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM event_users WHERE event_id = ?`, eventID); err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec(`DELETE FROM events WHERE id = ?`, eventID)
+	if err != nil {
+		return 0, err
+	}
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return affectedRows, tx.Commit()
+}
+
 func (dao *EventDAO) UpdateMessageID(eventID int64, messageID int) error {
 	query := `UPDATE events 
 		SET message_id = ?, updated_at = CURRENT_TIMESTAMP 
@@ -203,30 +224,52 @@ func (dao *EventDAO) UpdateMessageID(eventID int64, messageID int) error {
 }
 
 func (dao *EventDAO) GetEventByMessageID(messageID int) (*Event, error) {
-	query := `SELECT id, description, options, chat_id, message_id, created_by, created_by_id,
-		started_at, created_at, updated_at FROM events WHERE message_id = ?`
-	row := dao.db.QueryRow(query, messageID)
-	event := &Event{}
-	var optionsStr string
-	err := row.Scan(
-		&event.ID,
-		&event.Description,
-		&optionsStr,
-		&event.ChatID,
-		&event.MessageID,
-		&event.CreatedBy,
-		&event.CreatedByID,
-		&event.StartedAt,
-		&event.CreatedAt,
-		&event.UpdatedAt,
-	)
+	query := `SELECT ` + eventColumns + ` FROM events WHERE message_id = ?`
+	return scanEvent(dao.db.QueryRow(query, messageID))
+}
+
+// GetEventByActivityID returns the most recent poll of the activity
+func (dao *EventDAO) GetEventByActivityID(activityID int64) (*Event, error) {
+	query := `SELECT ` + eventColumns + ` FROM events WHERE activity_id = ? ORDER BY id DESC LIMIT 1`
+	return scanEvent(dao.db.QueryRow(query, activityID))
+}
+
+// GetHeadcounts returns the number of people who voted in the polls of each of the activities which have one
+func (dao *EventDAO) GetHeadcounts(activityIDs []int64) (map[int64]int, error) {
+	headcounts := make(map[int64]int)
+	if len(activityIDs) == 0 {
+		return headcounts, nil
+	}
+	placeholders := make([]string, len(activityIDs))
+	args := make([]any, len(activityIDs))
+	for i, id := range activityIDs {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+	query := `SELECT e.activity_id, COUNT(DISTINCT eu.user)
+		FROM events e
+		LEFT JOIN event_users eu ON eu.event_id = e.id AND NOT eu.deleted
+		WHERE e.activity_id IN (` + strings.Join(placeholders, ",") + `)
+		GROUP BY e.activity_id`
+	rows, err := dao.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
-	if optionsStr != "" {
-		event.Options = strings.Split(optionsStr, ";")
+	return scanHeadcounts(rows, headcounts)
+}
+
+// scanHeadcounts adds the rows of activity_id and headcount to headcounts
+func scanHeadcounts(rows *sql.Rows, headcounts map[int64]int) (map[int64]int, error) {
+	defer rows.Close()
+	for rows.Next() {
+		var activityID int64
+		var headcount int
+		if err := rows.Scan(&activityID, &headcount); err != nil {
+			return nil, err
+		}
+		headcounts[activityID] = headcount
 	}
-	return event, nil
+	return headcounts, rows.Err()
 }
 
 func (dao *EventDAO) GetEventUsers(eventID int64) ([]EventUser, error) {
@@ -235,18 +278,7 @@ func (dao *EventDAO) GetEventUsers(eventID int64) ([]EventUser, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var users []EventUser
-	for rows.Next() {
-		var eventUser EventUser
-		err := rows.Scan(&eventUser.EventID, &eventUser.User, &eventUser.Option, &eventUser.UserID)
-		if err != nil {
-			return nil, err
-		}
-		users = append(users, eventUser)
-	}
-	return users, nil
+	return scanEventUsers(rows)
 }
 
 func (dao *EventDAO) SaveEventUser(eventUser *EventUser) error {
@@ -256,15 +288,8 @@ func (dao *EventDAO) SaveEventUser(eventUser *EventUser) error {
 }
 
 func (dao *EventDAO) ToggleEventUser(eventUser *EventUser) error {
-	// old unique index in db event_id, user, option
-	query := "INSERT INTO event_users (event_id, user, option, user_id) VALUES (?, ?, ?, ?) ON CONFLICT(event_id, user, option) DO UPDATE SET deleted = NOT deleted, user_id = excluded.user_id"
+	query := "INSERT INTO event_users (event_id, user, option, user_id) VALUES (?, ?, ?, ?) ON CONFLICT(event_id, user_id, user, option) DO UPDATE SET deleted = NOT deleted, user_id = excluded.user_id"
 	_, err := dao.db.Exec(query, eventUser.EventID, eventUser.User, eventUser.Option, eventUser.UserID)
-	if err == nil {
-		return nil
-	}
-	// new unique index in db event_id, user_id, user, option
-	query = "INSERT INTO event_users (event_id, user, option, user_id) VALUES (?, ?, ?, ?) ON CONFLICT(event_id, user_id, user, option) DO UPDATE SET deleted = NOT deleted, user_id = excluded.user_id"
-	_, err = dao.db.Exec(query, eventUser.EventID, eventUser.User, eventUser.Option, eventUser.UserID)
 	return err
 }
 
@@ -285,31 +310,18 @@ func (dao *EventDAO) GetEventUsersByUser(user string, userID int64) ([]EventUser
 	query := `
 		SELECT event_id, user, option, user_id
 		FROM event_users
-		WHERE (user_id = ? AND user_id != 0) OR (user = ? AND user_id = 0)
-		AND not deleted
+		WHERE ((user_id = ? AND user_id != 0) OR (user = ? AND user_id = 0))
+		AND NOT deleted
 	`
-	rows, err := dao.db.Query(query, userID, userID, user)
+	rows, err := dao.db.Query(query, userID, user)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var eventUsers []EventUser
-	for rows.Next() {
-		var eventUser EventUser
-		err := rows.Scan(&eventUser.EventID, &eventUser.User, &eventUser.Option, &eventUser.UserID)
-		if err != nil {
-			return nil, err
-		}
-		eventUsers = append(eventUsers, eventUser)
-	}
-	return eventUsers, nil
+	return scanEventUsers(rows)
 }
 
 func (dao *EventDAO) GetEventsVotedByUser(user string, userID int64) ([]*Event, []EventUser, error) {
-	fmt.Println(user, userID)
 	eventUsers, err := dao.GetEventUsersByUser(user, userID)
-	fmt.Println(eventUsers)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -317,7 +329,6 @@ func (dao *EventDAO) GetEventsVotedByUser(user string, userID int64) ([]*Event,
 	for _, eu := range eventUsers {
 		eventIDs = append(eventIDs, eu.EventID)
 	}
-	fmt.Println(eventIDs)
 	events, err := dao.GetEventsByIDs(eventIDs)
 	return events, eventUsers, err
 }
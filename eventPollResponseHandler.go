@@ -2,7 +2,7 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"strings"
 	"time"
 
@@ -11,32 +11,34 @@ import (
 )
 
 type EventPollResponseHandler struct {
-	eventDao *EventDAO
+	eventDao     EventRepository
+	chatSettings ChatSettingsRepository
 }
 
-func NewEventPollResponseHandler(eventDao *EventDAO) *EventPollResponseHandler {
-	return &EventPollResponseHandler{eventDao: eventDao}
+func NewEventPollResponseHandler(eventDao EventRepository, chatSettings ChatSettingsRepository) *EventPollResponseHandler {
+	return &EventPollResponseHandler{eventDao: eventDao, chatSettings: chatSettings}
 }
 
-func (h *EventPollResponseHandler) handle(ctx context.Context, b *bot.Bot, update *models.Update) {
+func (h *EventPollResponseHandler) handle(ctx context.Context, b Messenger, update *models.Update) {
 	messageID := update.CallbackQuery.Message.Message.ID
-	log.Println("event callback for message", messageID, "from", update.CallbackQuery.From.FirstName, update.CallbackQuery.From.LastName)
+	slog.InfoContext(ctx, "event callback", "message_id", messageID)
 	event, err := h.eventDao.GetEventByMessageID(messageID)
 	if err != nil || event == nil {
-		log.Println("unknow messageID", messageID, err)
+		slog.WarnContext(ctx, "unknown message ID", "message_id", messageID, "err", err)
 		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 			CallbackQueryID: update.CallbackQuery.ID,
 			ShowAlert:       true,
-			Text:            "Event not found. Potentially the event was sent to somewhere else. No more modification here.",
+			Text:            h.chatSettings.GetLocalizer(ctx, update.CallbackQuery.Message.Message.Chat.ID).T(msgPollEventNotFound),
 		})
 		return
 	}
-	if event.StartedAt != nil && getBeginingOfDay(addLocalTimezone(*event.StartedAt)).AddDate(0, 0, 1).Before(time.Now()) {
-		log.Println("event already started", event.Description, event.StartedAt)
+	l := h.chatSettings.GetLocalizer(ctx, event.ChatID)
+	if event.StartedAt != nil && getBeginingOfDay(event.StartedAt.In(l.Location)).AddDate(0, 0, 1).Before(time.Now()) {
+		slog.InfoContext(ctx, "event already started", "event_id", event.ID, "started_at", event.StartedAt)
 		b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 			CallbackQueryID: update.CallbackQuery.ID,
 			ShowAlert:       true,
-			Text:            "Event already started. No more modification here.",
+			Text:            l.T(msgPollEventStarted),
 		})
 		return
 	}
@@ -47,7 +49,7 @@ func (h *EventPollResponseHandler) handle(ctx context.Context, b *bot.Bot, updat
 	user := getUserFullName(&update.CallbackQuery.From)
 	optionInputs := strings.Split(update.CallbackQuery.Data, callbackSeparator)
 	if len(optionInputs) < 2 {
-		log.Println("invalid option callback", update.CallbackQuery.Data)
+		slog.WarnContext(ctx, "invalid option callback", "data", update.CallbackQuery.Data)
 		return
 	}
 	option := optionInputs[1]
@@ -60,38 +62,29 @@ func (h *EventPollResponseHandler) handle(ctx context.Context, b *bot.Bot, updat
 	if len(optionInputs) == 2 {
 		err := h.eventDao.ToggleEventUser(&eventUser)
 		if err != nil {
-			log.Println("error updating event user", err)
+			slog.ErrorContext(ctx, "error updating event user", "event_id", event.ID, "err", err)
 			return
 		}
+		votesTotal.WithLabelValues("toggle").Inc()
 	} else {
 		optionType := optionInputs[2]
 		if optionType == callbackPostFixIn {
 			err = h.eventDao.SaveEventUser(&eventUser)
 			if err != nil {
-				log.Println("error updating event user", err)
+				slog.ErrorContext(ctx, "error updating event user", "event_id", event.ID, "err", err)
 				return
 			}
+			votesTotal.WithLabelValues("in").Inc()
 		} else if optionType == callbackPostFixOut {
 			affectedRows, err := h.eventDao.DeleteEventUser(&eventUser)
 			if affectedRows == 0 {
-				log.Println("no event user deleted", err)
+				slog.WarnContext(ctx, "no event user deleted", "event_id", event.ID, "err", err)
 				return
 			}
+			votesTotal.WithLabelValues("out").Inc()
 		}
 	}
-	users, err := h.eventDao.GetEventUsers(event.ID)
-	if err != nil {
-		log.Println("error getting event users", err)
-	}
-	msgText, kb := getPollParams(*event, users)
-	_, err = b.EditMessageText(ctx, &bot.EditMessageTextParams{
-		ChatID:      event.ChatID,
-		MessageID:   messageID,
-		Text:        msgText,
-		ReplyMarkup: kb,
-		ParseMode:   "Markdown",
-	})
-	if err != nil {
-		log.Println("error editing message", err)
+	if err := refreshPollMessage(ctx, b, h.eventDao, l, event); err != nil {
+		slog.ErrorContext(ctx, "error editing message", "event_id", event.ID, "err", err)
 	}
 }
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	healthCheckTimeout    = 5 * time.Second
+	httpShutdownTimeout   = 10 * time.Second
+	healthStatusOK        = "ok"
+	healthStatusUnhealthy = "unhealthy"
+)
+
+// HealthStatus is the response of /healthz
+type HealthStatus struct {
+	Status     string     `json:"status"`
+	DB         string     `json:"db"`
+	Postgres   string     `json:"postgres,omitempty"`
+	LastUpdate *time.Time `json:"last_update,omitempty"`
+}
+
+// runHTTPServer serves /healthz, /metrics and the api and dashboard, if not nil, on the listen address until ctx is done.
+// postgres is the shared database checked along with db, nil if everything is kept in SQLite
+func runHTTPServer(ctx context.Context, cfg HTTPConfig, db, postgres *sql.DB, api *API, dashboard *Dashboard) error {
+	ln, err := net.Listen("tcp", cfg.ListenAddr)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: getHTTPHandler(db, postgres, api, dashboard)}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Error("error shutting down http server", "err", err)
+		}
+	}()
+	slog.Info("http server listening", "addr", ln.Addr().String())
+	if err := server.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func getHTTPHandler(db, postgres *sql.DB, api *API, dashboard *Dashboard) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	if api != nil {
+		mux.Handle(apiPathPrefix, api.Handler())
+	}
+	if dashboard != nil {
+		mux.Handle("GET "+dashboardPath, dashboard)
+	}
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, req *http.Request) {
+		status := getHealthStatus(req.Context(), db, postgres)
+		w.Header().Set("Content-Type", "application/json")
+		if status.Status != healthStatusOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(w).Encode(status); err != nil {
+			slog.ErrorContext(req.Context(), "error writing health status", "err", err)
+		}
+	})
+	return mux
+}
+
+func getHealthStatus(ctx context.Context, db, postgres *sql.DB) HealthStatus {
+	status := HealthStatus{Status: healthStatusOK, DB: healthStatusOK}
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		slog.WarnContext(ctx, "health check db ping failed", "err", err)
+		status.Status = healthStatusUnhealthy
+		status.DB = err.Error()
+	}
+	if postgres != nil {
+		status.Postgres = healthStatusOK
+		if err := postgres.PingContext(ctx); err != nil {
+			slog.WarnContext(ctx, "health check postgres ping failed", "err", err)
+			status.Status = healthStatusUnhealthy
+			status.Postgres = err.Error()
+		}
+	}
+	if nanos := lastUpdateReceived.Load(); nanos != 0 {
+		lastUpdate := time.Unix(0, nanos).UTC()
+		status.LastUpdate = &lastUpdate
+	}
+	return status
+}
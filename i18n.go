@@ -0,0 +1,740 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MessageKey identifies a message in the catalogue
+type MessageKey string
+
+const (
+	langEnglish = "en"
+	langChinese = "zh"
+
+	defaultLanguage = langEnglish
+)
+
+// common
+const (
+	msgButtonCancel        MessageKey = "button.cancel"
+	msgButtonBack          MessageKey = "button.back"
+	msgButtonConfirm       MessageKey = "button.confirm"
+	msgNothingToCancel     MessageKey = "state.nothingToCancel"
+	msgCancelled           MessageKey = "state.cancelled"
+	msgNothingToGoBack     MessageKey = "state.nothingToGoBack"
+	msgAlreadyFirstStep    MessageKey = "state.alreadyFirstStep"
+	msgStateExpired        MessageKey = "state.expired"
+	msgInvalidCallbackData MessageKey = "callback.invalidData"
+	msgInvalidOption       MessageKey = "callback.invalidOption"
+	msgNotWaitingForTime   MessageKey = "datePicker.notWaiting"
+	msgInvalidStartTime    MessageKey = "input.invalidStartTime"
+	msgStartTimeSet        MessageKey = "input.startTimeSet"
+	msgNotSet              MessageKey = "format.notSet"
+	msgNone                MessageKey = "format.none"
+	msgCurrentTimezone     MessageKey = "settings.currentTimezone"
+	msgUnknownTimezone     MessageKey = "settings.unknownTimezone"
+	msgSetTimezoneFailed   MessageKey = "settings.setTimezoneFailed"
+	msgTimezoneSet         MessageKey = "settings.timezoneSet"
+	msgCurrentLanguage     MessageKey = "settings.currentLanguage"
+	msgUnknownLanguage     MessageKey = "settings.unknownLanguage"
+	msgSetLanguageFailed   MessageKey = "settings.setLanguageFailed"
+	msgLanguageSet         MessageKey = "settings.languageSet"
+	msgNotAdmin            MessageKey = "admin.notAdmin"
+	msgBackupDisabled      MessageKey = "backup.disabled"
+	msgBackupShared        MessageKey = "backup.shared"
+	msgBackupFailed        MessageKey = "backup.failed"
+	msgBackupCaption       MessageKey = "backup.caption"
+	msgBackupSent          MessageKey = "backup.sent"
+)
+
+// events and polls
+const (
+	msgNotAuthorizedEvent   MessageKey = "event.notAuthorized"
+	msgNotAuthorizedSend    MessageKey = "event.notAuthorizedSend"
+	msgEventNotFound        MessageKey = "event.notFound"
+	msgInvalidEventID       MessageKey = "event.invalidID"
+	msgUpdateEventFailed    MessageKey = "event.updateFailed"
+	msgUpdatePollFailed     MessageKey = "event.updatePollFailed"
+	msgNoOptionToDelete     MessageKey = "event.noOptionToDelete"
+	msgOptionNotFound       MessageKey = "event.optionNotFound"
+	msgSelectOptionToDelete MessageKey = "event.selectOptionToDelete"
+	msgCreateEventPrompt    MessageKey = "event.createPrompt"
+	msgEventDescPrompt      MessageKey = "event.descPrompt"
+	msgEventStartedAtPrompt MessageKey = "event.startedAtPrompt"
+	msgEventOptionPrompt    MessageKey = "event.optionPrompt"
+	msgEmptyOption          MessageKey = "event.emptyOption"
+	msgDefaultPollOption    MessageKey = "event.defaultOption"
+	msgButtonDescription    MessageKey = "button.description"
+	msgButtonStartTime      MessageKey = "button.startTime"
+	msgButtonAddOption      MessageKey = "button.addOption"
+	msgButtonDeleteOption   MessageKey = "button.deleteOption"
+	msgUpdatePollHint       MessageKey = "event.updatePollHint"
+	msgSendPollHint         MessageKey = "event.sendPollHint"
+	msgEventDescription     MessageKey = "event.description"
+	msgEventStartsAt        MessageKey = "event.startsAt"
+	msgEventOptions         MessageKey = "event.options"
+	msgPollTitle            MessageKey = "poll.title"
+	msgPollStartTime        MessageKey = "poll.startTime"
+	msgPollEventNotFound    MessageKey = "poll.eventNotFound"
+	msgPollEventStarted     MessageKey = "poll.eventStarted"
+	msgPollDeleted          MessageKey = "poll.deleted"
+	msgMyVotesTitle         MessageKey = "myVotes.title"
+	msgMyVotesEventTitle    MessageKey = "myVotes.eventTitle"
+	msgMyVotesVotedOptions  MessageKey = "myVotes.votedOptions"
+)
+
+// workplan
+const (
+	msgWorkplanChooseOption  MessageKey = "workplan.chooseOption"
+	msgWorkplanSelectMonth   MessageKey = "workplan.selectMonth"
+	msgWorkplanInvalidMonth  MessageKey = "workplan.invalidMonth"
+	msgButtonViewThisMonth   MessageKey = "button.viewThisMonth"
+	msgButtonViewByMonth     MessageKey = "button.viewByMonth"
+	msgButtonViewAll         MessageKey = "button.viewAll"
+	msgButtonAddActivity     MessageKey = "button.addActivity"
+	msgButtonUpdateActivity  MessageKey = "button.updateActivity"
+	msgButtonDeleteActivity  MessageKey = "button.deleteActivity"
+	msgButtonAll             MessageKey = "button.all"
+	msgButtonName            MessageKey = "button.name"
+	msgButtonCommittee       MessageKey = "button.committee"
+	msgButtonLead            MessageKey = "button.lead"
+	msgButtonCoLead          MessageKey = "button.coLead"
+	msgActivityNamePrompt    MessageKey = "activity.namePrompt"
+	msgActivityStartedPrompt MessageKey = "activity.startedAtPrompt"
+	msgActivityOrgPrompt     MessageKey = "activity.orgPrompt"
+	msgActivityLeadPrompt    MessageKey = "activity.leadPrompt"
+	msgActivityCoLeadPrompt  MessageKey = "activity.coLeadPrompt"
+	msgActivityUpdatePrompt  MessageKey = "activity.updatePrompt"
+	msgActivityDeletePrompt  MessageKey = "activity.deletePrompt"
+	msgActivityNotAuthorized MessageKey = "activity.notAuthorized"
+	msgActivityInvalidOrg    MessageKey = "activity.invalidOrg"
+	msgActivityInvalidID     MessageKey = "activity.invalidID"
+	msgActivityNotFound      MessageKey = "activity.notFound"
+	msgActivityGetFailed     MessageKey = "activity.getFailed"
+	msgActivitySaveFailed    MessageKey = "activity.saveFailed"
+	msgActivitySaved         MessageKey = "activity.saved"
+	msgActivityDeleteFailed  MessageKey = "activity.deleteFailed"
+	msgActivityDeleted       MessageKey = "activity.deleted"
+	msgActivityUpdateFailed  MessageKey = "activity.updateFailed"
+	msgActivityUpdated       MessageKey = "activity.updated"
+	msgActivityNotUpdating   MessageKey = "activity.notUpdating"
+	msgActivitySelectField   MessageKey = "activity.selectField"
+	msgActivitiesTitle       MessageKey = "activity.listTitle"
+	msgNoActivities          MessageKey = "activity.noActivities"
+	msgActivityLine          MessageKey = "activity.line"
+	msgActivityCoLeadSep     MessageKey = "activity.coLeadSeparator"
+
+	msgButtonEndTime             MessageKey = "button.endTime"
+	msgButtonLocation            MessageKey = "button.location"
+	msgButtonStatus              MessageKey = "button.status"
+	msgActivityEndsAtPrompt      MessageKey = "activity.endsAtPrompt"
+	msgActivityInvalidEndsAt     MessageKey = "activity.invalidEndsAt"
+	msgEndTimeSet                MessageKey = "activity.endTimeSet"
+	msgEndTimeCleared            MessageKey = "activity.endTimeCleared"
+	msgActivityLocationPrompt    MessageKey = "activity.locationPrompt"
+	msgActivityDescriptionPrompt MessageKey = "activity.descriptionPrompt"
+	msgActivityStatusPrompt      MessageKey = "activity.statusPrompt"
+	msgActivityInvalidStatus     MessageKey = "activity.invalidStatus"
+	msgActivityStatusTag         MessageKey = "activity.statusTag"
+	msgActivityLocation          MessageKey = "activity.location"
+	msgActivityStatusPlanned     MessageKey = "activity.status.planned"
+	msgActivityStatusConfirmed   MessageKey = "activity.status.confirmed"
+	msgActivityStatusDone        MessageKey = "activity.status.done"
+	msgActivityStatusCancelled   MessageKey = "activity.status.cancelled"
+
+	msgActivitySlotConflicts  MessageKey = "activity.slotConflicts"
+	msgActivityLeadConflicts  MessageKey = "activity.leadConflicts"
+	msgActivityClashSlot      MessageKey = "activity.clashSlot"
+	msgActivityClashLeads     MessageKey = "activity.clashLeads"
+	msgActivityConflictsTitle MessageKey = "activity.conflictsTitle"
+	msgNoActivityConflicts    MessageKey = "activity.noConflicts"
+	msgActivityConflictsUsage MessageKey = "activity.conflictsUsage"
+
+	msgButtonCreatePoll    MessageKey = "button.createPoll"
+	msgActivityPollPrompt  MessageKey = "activity.pollPrompt"
+	msgActivityPollExists  MessageKey = "activity.pollExists"
+	msgActivityPollFailed  MessageKey = "activity.pollFailed"
+	msgActivityPollCreated MessageKey = "activity.pollCreated"
+	msgActivityHeadcount   MessageKey = "activity.headcount"
+
+	msgRoleLead                     MessageKey = "lead.role.lead"
+	msgRoleCoLead                   MessageKey = "lead.role.coLead"
+	msgLeadAssigned                 MessageKey = "lead.assigned"
+	msgLeadUnassigned               MessageKey = "lead.unassigned"
+	msgLeadActivityChanged          MessageKey = "lead.activityChanged"
+	msgLeadActivityDeleted          MessageKey = "lead.activityDeleted"
+	msgLeadReminder                 MessageKey = "lead.reminder"
+	msgLeadUnknownUsers             MessageKey = "lead.unknownUsers"
+	msgButtonByOrg                  MessageKey = "button.byOrg"
+	msgButtonByLead                 MessageKey = "button.byLead"
+	msgButtonMine                   MessageKey = "button.mine"
+	msgActivitySearchUsage          MessageKey = "activity.searchUsage"
+	msgActivitySearchLabel          MessageKey = "activity.searchLabel"
+	msgActivitySearchLimited        MessageKey = "activity.searchLimited"
+	msgActivityFilterOrgPrompt      MessageKey = "activity.filterOrgPrompt"
+	msgActivityFilterLeadPrompt     MessageKey = "activity.filterLeadPrompt"
+	msgActivityFilterMine           MessageKey = "activity.filterMine"
+	msgActivityDeleteNotAuthorized  MessageKey = "activity.deleteNotAuthorized"
+	msgActivityDeleteConfirm        MessageKey = "activity.deleteConfirm"
+	msgActivityTrashTitle           MessageKey = "activity.trashTitle"
+	msgActivityTrashEmpty           MessageKey = "activity.trashEmpty"
+	msgActivityTrashLimited         MessageKey = "activity.trashLimited"
+	msgActivityDeletedAt            MessageKey = "activity.deletedAt"
+	msgButtonRestore                MessageKey = "button.restore"
+	msgActivityNotInTrash           MessageKey = "activity.notInTrash"
+	msgActivityRestoreNotAuthorized MessageKey = "activity.restoreNotAuthorized"
+	msgActivityRestoreFailed        MessageKey = "activity.restoreFailed"
+	msgActivityRestored             MessageKey = "activity.restored"
+	msgActivityImportPrompt         MessageKey = "activity.importPrompt"
+	msgActivityImportNoFile         MessageKey = "activity.importNoFile"
+	msgActivityImportReadFailed     MessageKey = "activity.importReadFailed"
+	msgActivityImportInvalidFile    MessageKey = "activity.importInvalidFile"
+	msgActivityImportEmpty          MessageKey = "activity.importEmpty"
+	msgActivityImportRowError       MessageKey = "activity.importRowError"
+	msgActivityImportErrors         MessageKey = "activity.importErrors"
+	msgActivityImportMissing        MessageKey = "activity.importMissing"
+	msgActivityImportColumns        MessageKey = "activity.importColumns"
+	msgActivityImportInvalidTime    MessageKey = "activity.importInvalidTime"
+	msgActivityImportMore           MessageKey = "activity.importMore"
+	msgActivityImportLine           MessageKey = "activity.importLine"
+	msgActivityImportPreview        MessageKey = "activity.importPreview"
+	msgActivitiesImported           MessageKey = "activity.imported"
+	msgActivityImportFailed         MessageKey = "activity.importFailed"
+)
+
+// dashboard
+const (
+	msgDashboardTitle     MessageKey = "dashboard.title"
+	msgDashboardAllOrgs   MessageKey = "dashboard.allOrgs"
+	msgDashboardFilter    MessageKey = "dashboard.filter"
+	msgDashboardPrint     MessageKey = "dashboard.print"
+	msgDashboardCalendar  MessageKey = "dashboard.calendar"
+	msgDashboardList      MessageKey = "dashboard.list"
+	msgDashboardPrevMonth MessageKey = "dashboard.prevMonth"
+	msgDashboardNextMonth MessageKey = "dashboard.nextMonth"
+)
+
+// orgs
+const (
+	msgOrgUsage         MessageKey = "org.usage"
+	msgOrgLine          MessageKey = "org.line"
+	msgOrgArchivedTag   MessageKey = "org.archivedTag"
+	msgOrgInvalidUserID MessageKey = "org.invalidUserID"
+	msgOrgAdminAdded    MessageKey = "org.adminAdded"
+	msgOrgAdminRemoved  MessageKey = "org.adminRemoved"
+	msgOrgAdminNotFound MessageKey = "org.adminNotFound"
+	msgOrgAdminsTag     MessageKey = "org.adminsTag"
+	msgNoOrgs           MessageKey = "org.noOrgs"
+	msgOrgInvalidName   MessageKey = "org.invalidName"
+	msgOrgExists        MessageKey = "org.exists"
+	msgOrgNotFound      MessageKey = "org.notFound"
+	msgOrgGetFailed     MessageKey = "org.getFailed"
+	msgOrgSaveFailed    MessageKey = "org.saveFailed"
+	msgOrgAdded         MessageKey = "org.added"
+	msgOrgRenamed       MessageKey = "org.renamed"
+	msgOrgArchived      MessageKey = "org.archived"
+	msgOrgRestored      MessageKey = "org.restored"
+)
+
+// Language is a message bundle together with the date formats of a language.
+// The formats are Go layouts, "Mon" is replaced by Weekdays if set
+type Language struct {
+	Name           string
+	TimeFormat     string
+	DateFormat     string
+	MonthFormat    string
+	Weekdays       []string // Sunday first
+	PickerWeekdays []string // Monday first
+	Messages       map[MessageKey]string
+}
+
+var languages = map[string]*Language{
+	langEnglish: {
+		Name:           "English",
+		TimeFormat:     "Mon, 2006-01-02 15:04",
+		DateFormat:     "Mon, 2006-01-02",
+		MonthFormat:    "Jan 2006",
+		PickerWeekdays: []string{"Mo", "Tu", "We", "Th", "Fr", "Sa", "Su"},
+		Messages: map[MessageKey]string{
+			msgButtonCancel:                 "Cancel",
+			msgButtonBack:                   "<< back",
+			msgButtonConfirm:                "✅ Confirm",
+			msgNothingToCancel:              "Nothing to cancel.",
+			msgCancelled:                    "Cancelled.",
+			msgNothingToGoBack:              "Nothing to go back to.",
+			msgAlreadyFirstStep:             "Already at the first step. Use /cancel to stop.",
+			msgStateExpired:                 "Your previous request has timed out due to inactivity. Please start again.",
+			msgInvalidCallbackData:          "Invalid callback data",
+			msgInvalidOption:                "Invalid option callback",
+			msgNotWaitingForTime:            "request too old. not waiting for a time",
+			msgInvalidStartTime:             "Invalid input. Please enter a valid start time, %s.",
+			msgStartTimeSet:                 "Start time set to %s",
+			msgNotSet:                       "Not set",
+			msgNone:                         "None",
+			msgCurrentTimezone:              "The timezone of this chat is %s.\nUse /settimezone <IANA timezone> to change it, e.g. /settimezone Europe/Berlin",
+			msgUnknownTimezone:              "Unknown timezone %s. Please use an IANA timezone, e.g. Europe/Berlin",
+			msgSetTimezoneFailed:            "Failed to set the timezone! Please try again.",
+			msgTimezoneSet:                  "The timezone of this chat is now %s. Current time: %s",
+			msgCurrentLanguage:              "The language of this chat is %s.\nUse /setlanguage <language> to change it. Available languages: %s",
+			msgUnknownLanguage:              "Unknown language %s. Available languages: %s",
+			msgSetLanguageFailed:            "Failed to set the language! Please try again.",
+			msgLanguageSet:                  "The language of this chat is now %s.",
+			msgNotAdmin:                     "This command is only available to admins.",
+			msgBackupDisabled:               "Backups are not configured.",
+			msgBackupShared:                 "The data is kept in PostgreSQL, which the bot does not back up. Please back it up with pg_dump instead.",
+			msgBackupFailed:                 "Failed to back up the database! Please check the logs.",
+			msgBackupCaption:                "Database backup %s",
+			msgBackupSent:                   "The backup has been sent to the admin chat.",
+			msgNotAuthorizedEvent:           "You are not authorized to update this event",
+			msgNotAuthorizedSend:            "You are not authorized to send this event",
+			msgActivityNotAuthorized:        "You are not authorized to update this activity!",
+			msgEventNotFound:                "Event not found.",
+			msgInvalidEventID:               "Invalid event ID.",
+			msgUpdateEventFailed:            "Failed to update event.",
+			msgUpdatePollFailed:             "Error updating poll",
+			msgNoOptionToDelete:             "No option specified to delete.",
+			msgOptionNotFound:               "Option not found in event.",
+			msgSelectOptionToDelete:         "Select the option to delete",
+			msgCreateEventPrompt:            "Let's start creating the event. First, please enter the description.",
+			msgEventDescPrompt:              "Please enter the new description for the event.",
+			msgEventStartedAtPrompt:         "Please enter the new start time, %s, or pick it below.",
+			msgEventOptionPrompt:            "Please enter the new option to add.",
+			msgEmptyOption:                  "Empty input. Please enter the option to add.",
+			msgDefaultPollOption:            "Available",
+			msgButtonDescription:            "Description",
+			msgButtonStartTime:              "Start Time",
+			msgButtonAddOption:              "Add Option",
+			msgButtonDeleteOption:           "Delete Option",
+			msgUpdatePollHint:               "You can update the poll by clicking the buttons below.",
+			msgSendPollHint:                 "You can now send it to the group by copy pasting the following command sent as a separate message, in the format: %s",
+			msgEventDescription:             "*Description:* %s",
+			msgEventStartsAt:                "*Starts at:* %s",
+			msgEventOptions:                 "*Options:*",
+			msgPollTitle:                    "*Please cast your votes*",
+			msgPollStartTime:                "*Start Time:* %s",
+			msgPollEventNotFound:            "Event not found. Potentially the event was sent to somewhere else. No more modification here.",
+			msgPollEventStarted:             "Event already started. No more modification here.",
+			msgPollDeleted:                  "This poll has been deleted.",
+			msgMyVotesTitle:                 "You Voted Events: %d",
+			msgMyVotesEventTitle:            "*%d. Description:* %s",
+			msgMyVotesVotedOptions:          "*Voted Option(s):*",
+			msgWorkplanChooseOption:         "Please choose an option:",
+			msgWorkplanSelectMonth:          "Select a month to view activities:",
+			msgWorkplanInvalidMonth:         "cannot get the month",
+			msgButtonViewThisMonth:          "View This Mo",
+			msgButtonViewByMonth:            "View By Mo",
+			msgButtonViewAll:                "View All",
+			msgButtonAddActivity:            "Add Event",
+			msgButtonUpdateActivity:         "Update Event",
+			msgButtonDeleteActivity:         "Delete Event",
+			msgButtonAll:                    "All",
+			msgButtonName:                   "Name",
+			msgButtonCommittee:              "Committee",
+			msgButtonLead:                   "Lead",
+			msgButtonCoLead:                 "Co-lead",
+			msgActivityNamePrompt:           "Please provide the name for the activity.",
+			msgActivityStartedPrompt:        "Please enter the start time, %s, or pick it below.",
+			msgActivityOrgPrompt:            "Please choose the organizing committee below or enter its name.",
+			msgActivityLeadPrompt:           "Please enter the name of the lead.",
+			msgActivityCoLeadPrompt:         "Please enter the name of the co-lead, separated by semicolon(e.g. Person A; Person B)",
+			msgActivityUpdatePrompt:         "Please provide the ID of the activity you want to update.",
+			msgActivityDeletePrompt:         "Please provide the ID of the activity you want to delete.",
+			msgActivityInvalidOrg:           "Invalid org. Please choose one of %v",
+			msgActivityInvalidID:            "Invalid activity ID! Please enter a valid number.",
+			msgActivityNotFound:             "No activity found with the given ID! Please try again.",
+			msgActivityGetFailed:            "Failed to retrieve activity! Please try again.",
+			msgActivitySaveFailed:           "Failed to save activity! Please send the co-leads again to retry!",
+			msgActivitySaved:                "Activity details collected successfully!\n%s",
+			msgActivityDeleteFailed:         "Failed to delete activity! Please try again.",
+			msgActivityDeleted:              "Activity moved to the trash. Use /workplan trash to restore it within %d days.",
+			msgActivityUpdateFailed:         "Failed to update activity! Please select the field and try again!",
+			msgActivityUpdated:              "Activity updated successfully!\n%s",
+			msgActivityNotUpdating:          "request too old. not in update mode",
+			msgActivitySelectField:          "Select what you want to update:\n\n%s",
+			msgActivitiesTitle:              "Activities (%s):\n%s",
+			msgNoActivities:                 "no activities found.",
+			msgActivityLine:                 "<b>%s %s - (Org: %s) - (ID:%d):</b> %s(L), %s(CoL)",
+			msgActivityCoLeadSep:            "(CoL), ",
+			msgButtonEndTime:                "End Time",
+			msgButtonLocation:               "Location",
+			msgButtonStatus:                 "Status",
+			msgActivityEndsAtPrompt:         "Please enter the end time, %s, or pick it below. Send - to clear it.",
+			msgActivityInvalidEndsAt:        "Invalid input. Please enter an end time after the start time %s, or - to clear it.",
+			msgEndTimeSet:                   "End time set to %s",
+			msgEndTimeCleared:               "End time cleared.",
+			msgActivityLocationPrompt:       "Please enter the location. Send - to clear it.",
+			msgActivityDescriptionPrompt:    "Please enter the description. Send - to clear it.",
+			msgActivityStatusPrompt:         "Please choose the new status below.",
+			msgActivityInvalidStatus:        "A %s activity cannot become %s. Please choose one of the buttons below.",
+			msgActivityStatusTag:            "[%s]",
+			msgActivityLocation:             "📍 %s",
+			msgActivityStatusPlanned:        "Planned",
+			msgActivityStatusConfirmed:      "Confirmed",
+			msgActivityStatusDone:           "Done",
+			msgActivityStatusCancelled:      "Cancelled",
+			msgActivitySlotConflicts:        "⚠️ Other activities take place at the same time:\n\n%s\n\nConfirm to keep the time or go back to change it.",
+			msgActivityLeadConflicts:        "⚠️ The lead is double-booked:\n\n%s\n\nConfirm to keep the lead or go back to change it.",
+			msgActivityClashSlot:            "<b>🕒 Same time slot</b>",
+			msgActivityClashLeads:           "<b>👥 Double-booked: %s</b>",
+			msgActivityConflictsTitle:       "Conflicts (%s):\n\n%s",
+			msgNoActivityConflicts:          "no conflicts found.",
+			msgActivityConflictsUsage:       "Usage: /workplan conflicts [YYYY-MM] [YYYY-MM] - list the clashes from the first to the last month, this month by default",
+			msgButtonCreatePoll:             "Create Poll",
+			msgActivityPollPrompt:           "Please enter the ID of the activity to create a poll for:",
+			msgActivityPollExists:           "This activity already has a poll, send it with /send %d. Please enter another activity ID.",
+			msgActivityPollFailed:           "Failed to create the poll! Please send the activity ID again to retry!",
+			msgActivityPollCreated:          "Poll %d created for the activity. Use /send %d to send it to another chat.",
+			msgActivityHeadcount:            "👥 %d attending",
+			msgRoleLead:                     "lead",
+			msgRoleCoLead:                   "co-lead",
+			msgLeadAssigned:                 "You are now the %s of this activity:\n\n%s",
+			msgLeadUnassigned:               "You are no longer the %s of this activity:\n\n%s",
+			msgLeadActivityChanged:          "An activity you are the %s of has changed:\n\n%s",
+			msgLeadActivityDeleted:          "An activity you are the %s of has been deleted:\n\n%s",
+			msgLeadReminder:                 "Reminder: you are the %s of this upcoming activity:\n\n%s",
+			msgLeadUnknownUsers:             "%s will not be notified until they send a message where the bot can see it. Mention them by name to notify them now.",
+			msgButtonByOrg:                  "By Org",
+			msgButtonByLead:                 "By Lead",
+			msgButtonMine:                   "Mine",
+			msgActivitySearchUsage:          "Usage: /workplan search <text>",
+			msgActivitySearchLabel:          "“%s”",
+			msgActivitySearchLimited:        "\nOnly the latest %d matching activities are shown.",
+			msgActivityFilterOrgPrompt:      "Show the activities of which organization?",
+			msgActivityFilterLeadPrompt:     "Whose activities? Enter or mention the lead.",
+			msgActivityFilterMine:           "led by you",
+			msgActivityDeleteNotAuthorized:  "Only the creator, the leads and the admins can delete this activity!",
+			msgActivityDeleteConfirm:        "Delete this activity?\n%s",
+			msgActivityTrashTitle:           "Deleted in the last %d days:\n\n%s",
+			msgActivityTrashEmpty:           "No activities were deleted in the last %d days.",
+			msgActivityTrashLimited:         "\n\nOnly the %d most recently deleted activities are shown.",
+			msgActivityDeletedAt:            "Deleted %s",
+			msgButtonRestore:                "Restore #%d %s",
+			msgActivityNotInTrash:           "The activity is no longer in the trash.",
+			msgActivityRestoreNotAuthorized: "Only the creator, the leads and the admins can restore this activity!",
+			msgActivityRestoreFailed:        "Failed to restore the activity! Please try again.",
+			msgActivityRestored:             "Activity restored:\n%s",
+			msgActivityImportPrompt:         "Send a CSV or .ics file with the activities to import.\n\nCSV columns: name, start, org, lead, co-leads separated by ;, and optionally end, location and description. Times are written like the ones you type, %s. A first row starting with \"name\" is skipped.\n\nIn .ics files the summary is the name, the first category the org and the organizer the lead.",
+			msgActivityImportNoFile:         "Please send the activities as a CSV or .ics file.",
+			msgActivityImportReadFailed:     "The file could not be downloaded. Files up to %d KB can be imported.",
+			msgActivityImportInvalidFile:    "The file could not be read: %s",
+			msgActivityImportEmpty:          "The file has no activities.",
+			msgActivityImportRowError:       "Line %d: %s",
+			msgActivityImportErrors:         "%d of %d activities are invalid, nothing has been imported. Fix them and send the file again:\n\n%s",
+			msgActivityImportMissing:        "%s is missing",
+			msgActivityImportColumns:        "expected %d to %d columns, got %d",
+			msgActivityImportInvalidTime:    "invalid time %q",
+			msgActivityImportMore:           "… and %d more",
+			msgActivityImportLine:           "%s <b>%s</b> (%s) %s",
+			msgActivityImportPreview:        "<b>%d activities will be imported:</b>\n\n%s\n\nConfirm to save them all, or go back to send another file.",
+			msgActivitiesImported:           "✅ %d activities imported. Use /workplan conflicts to check them against the work plan.",
+			msgActivityImportFailed:         "Failed to import the activities, nothing has been saved. Send the file again to retry.",
+			msgDashboardTitle:               "Workplan %s",
+			msgDashboardAllOrgs:             "All committees",
+			msgDashboardFilter:              "Filter",
+			msgDashboardPrint:               "Print",
+			msgDashboardCalendar:            "Calendar",
+			msgDashboardList:                "Activities",
+			msgDashboardPrevMonth:           "Previous month",
+			msgDashboardNextMonth:           "Next month",
+			msgOrgUsage:                     "Usage:\n/org - list the organizations\n/org add <name> <display name> [emoji]\n/org rename <name> <display name> [emoji]\n/org archive <name>\n/org restore <name>\n/org addadmin <name> <user id>\n/org removeadmin <name> <user id>",
+			msgOrgLine:                      "%s - %s",
+			msgOrgArchivedTag:               " (archived)",
+			msgOrgInvalidUserID:             "Invalid user ID %q! Use the numeric Telegram user ID.",
+			msgOrgAdminAdded:                "User %d is now an admin of %s.",
+			msgOrgAdminRemoved:              "User %d is no longer an admin of %s.",
+			msgOrgAdminNotFound:             "User %d is not an admin of %s.",
+			msgOrgAdminsTag:                 " (admins: %s)",
+			msgNoOrgs:                       "No organizations yet. Use /org add <name> <display name> [emoji] to add one.",
+			msgOrgInvalidName:               "Invalid name %s. Please use up to 16 letters, digits or -.",
+			msgOrgExists:                    "The organization %s already exists.",
+			msgOrgNotFound:                  "Organization %s not found.",
+			msgOrgGetFailed:                 "Failed to retrieve the organizations! Please try again.",
+			msgOrgSaveFailed:                "Failed to save the organization! Please try again.",
+			msgOrgAdded:                     "Organization %s added.",
+			msgOrgRenamed:                   "Organization %s renamed.",
+			msgOrgArchived:                  "Organization %s archived. It can no longer be chosen for new activities.",
+			msgOrgRestored:                  "Organization %s restored.",
+		},
+	},
+	langChinese: {
+		Name:           "中文",
+		TimeFormat:     "2006-01-02 (Mon) 15:04",
+		DateFormat:     "2006-01-02 (Mon)",
+		MonthFormat:    "2006年1月",
+		Weekdays:       []string{"周日", "周一", "周二", "周三", "周四", "周五", "周六"},
+		PickerWeekdays: []string{"一", "二", "三", "四", "五", "六", "日"},
+		Messages: map[MessageKey]string{
+			msgButtonCancel:                 "取消",
+			msgButtonBack:                   "<< 返回",
+			msgButtonConfirm:                "✅ 确认",
+			msgNothingToCancel:              "没有可以取消的操作。",
+			msgCancelled:                    "已取消。",
+			msgNothingToGoBack:              "没有可以返回的步骤。",
+			msgAlreadyFirstStep:             "已经是第一步了。使用 /cancel 停止。",
+			msgStateExpired:                 "您之前的请求因长时间未操作已超时，请重新开始。",
+			msgInvalidCallbackData:          "无效的回调数据",
+			msgInvalidOption:                "无效的选项",
+			msgNotWaitingForTime:            "请求已过期，当前不需要输入时间",
+			msgInvalidStartTime:             "输入无效。请输入有效的开始时间，%s。",
+			msgStartTimeSet:                 "开始时间已设为 %s",
+			msgNotSet:                       "未设置",
+			msgNone:                         "无",
+			msgCurrentTimezone:              "本聊天的时区为 %s。\n使用 /settimezone <IANA 时区> 修改，例如 /settimezone Asia/Shanghai",
+			msgUnknownTimezone:              "未知时区 %s。请使用 IANA 时区，例如 Asia/Shanghai",
+			msgSetTimezoneFailed:            "设置时区失败！请重试。",
+			msgTimezoneSet:                  "本聊天的时区已设为 %s。当前时间：%s",
+			msgCurrentLanguage:              "本聊天的语言为 %s。\n使用 /setlanguage <语言> 修改。可用语言：%s",
+			msgUnknownLanguage:              "未知语言 %s。可用语言：%s",
+			msgSetLanguageFailed:            "设置语言失败！请重试。",
+			msgLanguageSet:                  "本聊天的语言已设为 %s。",
+			msgNotAdmin:                     "此命令仅限管理员使用。",
+			msgBackupDisabled:               "未配置备份。",
+			msgBackupShared:                 "数据保存在 PostgreSQL 中，机器人不会备份。请改用 pg_dump 备份。",
+			msgBackupFailed:                 "数据库备份失败！请查看日志。",
+			msgBackupCaption:                "数据库备份 %s",
+			msgBackupSent:                   "备份已发送到管理员聊天。",
+			msgNotAuthorizedEvent:           "您无权修改此活动",
+			msgNotAuthorizedSend:            "您无权发送此活动",
+			msgActivityNotAuthorized:        "您无权修改此活动！",
+			msgEventNotFound:                "未找到活动。",
+			msgInvalidEventID:               "无效的活动 ID。",
+			msgUpdateEventFailed:            "更新活动失败。",
+			msgUpdatePollFailed:             "更新投票出错",
+			msgNoOptionToDelete:             "未指定要删除的选项。",
+			msgOptionNotFound:               "活动中没有此选项。",
+			msgSelectOptionToDelete:         "请选择要删除的选项",
+			msgCreateEventPrompt:            "开始创建活动。首先，请输入活动描述。",
+			msgEventDescPrompt:              "请输入新的活动描述。",
+			msgEventStartedAtPrompt:         "请输入新的开始时间，%s，或在下方选择。",
+			msgEventOptionPrompt:            "请输入要添加的新选项。",
+			msgEmptyOption:                  "输入为空。请输入要添加的选项。",
+			msgDefaultPollOption:            "参加",
+			msgButtonDescription:            "描述",
+			msgButtonStartTime:              "开始时间",
+			msgButtonAddOption:              "添加选项",
+			msgButtonDeleteOption:           "删除选项",
+			msgUpdatePollHint:               "您可以点击下方按钮更新投票。",
+			msgSendPollHint:                 "您现在可以复制下方单独发送的命令，将投票发送到群组，格式为：%s",
+			msgEventDescription:             "*描述：* %s",
+			msgEventStartsAt:                "*开始时间：* %s",
+			msgEventOptions:                 "*选项：*",
+			msgPollTitle:                    "*请投票*",
+			msgPollStartTime:                "*开始时间：* %s",
+			msgPollEventNotFound:            "未找到活动。活动可能已发送到其他地方，此处无法再修改。",
+			msgPollEventStarted:             "活动已开始，无法再修改。",
+			msgPollDeleted:                  "此投票已被删除。",
+			msgMyVotesTitle:                 "您投票的活动：%d",
+			msgMyVotesEventTitle:            "*%d. 描述：* %s",
+			msgMyVotesVotedOptions:          "*已投选项：*",
+			msgWorkplanChooseOption:         "请选择一个选项：",
+			msgWorkplanSelectMonth:          "请选择要查看的月份：",
+			msgWorkplanInvalidMonth:         "无法识别月份",
+			msgButtonViewThisMonth:          "本月",
+			msgButtonViewByMonth:            "按月查看",
+			msgButtonViewAll:                "全部查看",
+			msgButtonAddActivity:            "添加活动",
+			msgButtonUpdateActivity:         "更新活动",
+			msgButtonDeleteActivity:         "删除活动",
+			msgButtonAll:                    "全部",
+			msgButtonName:                   "名称",
+			msgButtonCommittee:              "委员会",
+			msgButtonLead:                   "负责人",
+			msgButtonCoLead:                 "协办人",
+			msgActivityNamePrompt:           "请输入活动名称。",
+			msgActivityStartedPrompt:        "请输入开始时间，%s，或在下方选择。",
+			msgActivityOrgPrompt:            "请在下方选择主办委员会，或输入其名称。",
+			msgActivityLeadPrompt:           "请输入负责人姓名。",
+			msgActivityCoLeadPrompt:         "请输入协办人姓名，用分号分隔（例如 张三; 李四）",
+			msgActivityUpdatePrompt:         "请输入要更新的活动 ID。",
+			msgActivityDeletePrompt:         "请输入要删除的活动 ID。",
+			msgActivityInvalidOrg:           "无效的委员会。请选择以下之一：%v",
+			msgActivityInvalidID:            "无效的活动 ID！请输入一个有效的数字。",
+			msgActivityNotFound:             "未找到该 ID 的活动！请重试。",
+			msgActivityGetFailed:            "获取活动失败！请重试。",
+			msgActivitySaveFailed:           "保存活动失败！请重新发送协办人以重试！",
+			msgActivitySaved:                "活动信息收集成功！\n%s",
+			msgActivityDeleteFailed:         "删除活动失败！请重试。",
+			msgActivityDeleted:              "活动已移至回收站。%d 天内可使用 /workplan trash 恢复。",
+			msgActivityUpdateFailed:         "更新活动失败！请重新选择字段并重试！",
+			msgActivityUpdated:              "活动已更新！\n%s",
+			msgActivityNotUpdating:          "请求已过期，当前不在更新模式",
+			msgActivitySelectField:          "请选择要更新的内容：\n\n%s",
+			msgActivitiesTitle:              "活动（%s）：\n%s",
+			msgNoActivities:                 "没有找到活动。",
+			msgActivityLine:                 "<b>%s %s - (委员会: %s) - (ID:%d):</b> %s(负责), %s(协办)",
+			msgActivityCoLeadSep:            "(协办), ",
+			msgButtonEndTime:                "结束时间",
+			msgButtonLocation:               "地点",
+			msgButtonStatus:                 "状态",
+			msgActivityEndsAtPrompt:         "请输入结束时间，%s，或在下方选择。发送 - 可清除。",
+			msgActivityInvalidEndsAt:        "输入无效。请输入晚于开始时间 %s 的结束时间，或发送 - 清除。",
+			msgEndTimeSet:                   "结束时间已设为 %s",
+			msgEndTimeCleared:               "已清除结束时间。",
+			msgActivityLocationPrompt:       "请输入地点。发送 - 可清除。",
+			msgActivityDescriptionPrompt:    "请输入活动说明。发送 - 可清除。",
+			msgActivityStatusPrompt:         "请在下方选择新的状态。",
+			msgActivityInvalidStatus:        "%s的活动不能改为%s。请选择下方的按钮。",
+			msgActivityStatusTag:            "[%s]",
+			msgActivityLocation:             "📍 %s",
+			msgActivityStatusPlanned:        "计划中",
+			msgActivityStatusConfirmed:      "已确认",
+			msgActivityStatusDone:           "已完成",
+			msgActivityStatusCancelled:      "已取消",
+			msgActivitySlotConflicts:        "⚠️ 同一时间还有其他活动：\n\n%s\n\n确认保留此时间，或返回修改。",
+			msgActivityLeadConflicts:        "⚠️ 负责人时间冲突：\n\n%s\n\n确认保留此负责人，或返回修改。",
+			msgActivityClashSlot:            "<b>🕒 时间重叠</b>",
+			msgActivityClashLeads:           "<b>👥 重复安排：%s</b>",
+			msgActivityConflictsTitle:       "冲突（%s）：\n\n%s",
+			msgNoActivityConflicts:          "没有发现冲突。",
+			msgActivityConflictsUsage:       "用法：/workplan conflicts [YYYY-MM] [YYYY-MM] - 列出从第一个月到最后一个月的冲突，默认为本月",
+			msgButtonCreatePoll:             "创建投票",
+			msgActivityPollPrompt:           "请输入要创建投票的活动ID：",
+			msgActivityPollExists:           "此活动已有投票，可用 /send %d 发送。请输入其他活动ID。",
+			msgActivityPollFailed:           "创建投票失败！请重新发送活动ID重试！",
+			msgActivityPollCreated:          "已为活动创建投票 %d。可用 /send %d 发送到其他聊天。",
+			msgActivityHeadcount:            "👥 %d 人参加",
+			msgRoleLead:                     "负责人",
+			msgRoleCoLead:                   "协办人",
+			msgLeadAssigned:                 "你现在是以下活动的%s：\n\n%s",
+			msgLeadUnassigned:               "你不再是以下活动的%s：\n\n%s",
+			msgLeadActivityChanged:          "你担任%s的活动有更新：\n\n%s",
+			msgLeadActivityDeleted:          "你担任%s的活动已被删除：\n\n%s",
+			msgLeadReminder:                 "提醒：你是以下即将举行的活动的%s：\n\n%s",
+			msgLeadUnknownUsers:             "%s 在机器人能看到的地方发送消息之前不会收到通知。直接提及其名字可立即通知。",
+			msgButtonByOrg:                  "按委员会",
+			msgButtonByLead:                 "按负责人",
+			msgButtonMine:                   "我的",
+			msgActivitySearchUsage:          "用法：/workplan search <关键词>",
+			msgActivitySearchLabel:          "“%s”",
+			msgActivitySearchLimited:        "\n仅显示最近的 %d 个匹配活动。",
+			msgActivityFilterOrgPrompt:      "显示哪个委员会的活动？",
+			msgActivityFilterLeadPrompt:     "要查看谁的活动？请输入或提及负责人。",
+			msgActivityFilterMine:           "由你负责",
+			msgActivityDeleteNotAuthorized:  "只有创建者、负责人和管理员可以删除此活动！",
+			msgActivityDeleteConfirm:        "确定删除此活动吗？\n%s",
+			msgActivityTrashTitle:           "最近 %d 天内删除的活动：\n\n%s",
+			msgActivityTrashEmpty:           "最近 %d 天内没有删除的活动。",
+			msgActivityTrashLimited:         "\n\n仅显示最近删除的 %d 个活动。",
+			msgActivityDeletedAt:            "删除于 %s",
+			msgButtonRestore:                "恢复 #%d %s",
+			msgActivityNotInTrash:           "该活动已不在回收站中。",
+			msgActivityRestoreNotAuthorized: "只有创建者、负责人和管理员可以恢复此活动！",
+			msgActivityRestoreFailed:        "恢复活动失败！请重试。",
+			msgActivityRestored:             "活动已恢复：\n%s",
+			msgActivityImportPrompt:         "请发送包含要导入的活动的 CSV 或 .ics 文件。\n\nCSV 列：名称 (name)、开始时间 (start)、组织 (org)、负责人 (lead)、以 ; 分隔的协办人 (co-leads)，以及可选的结束时间、地点和描述。时间的写法与输入时相同，%s。以 \"name\" 开头的第一行会被跳过。\n\n.ics 文件中，摘要为名称，第一个类别为组织，组织者为负责人。",
+			msgActivityImportNoFile:         "请以 CSV 或 .ics 文件发送活动。",
+			msgActivityImportReadFailed:     "无法下载文件。最多可导入 %d KB 的文件。",
+			msgActivityImportInvalidFile:    "无法读取文件：%s",
+			msgActivityImportEmpty:          "文件中没有活动。",
+			msgActivityImportRowError:       "第 %d 行：%s",
+			msgActivityImportErrors:         "%d/%d 个活动无效，未导入任何活动。请修正后重新发送文件：\n\n%s",
+			msgActivityImportMissing:        "缺少 %s",
+			msgActivityImportColumns:        "应有 %d 至 %d 列，实际为 %d 列",
+			msgActivityImportInvalidTime:    "无效的时间 %q",
+			msgActivityImportMore:           "……还有 %d 项",
+			msgActivityImportLine:           "%s <b>%s</b>（%s）%s",
+			msgActivityImportPreview:        "<b>将导入 %d 个活动：</b>\n\n%s\n\n确认以全部保存，或返回发送其他文件。",
+			msgActivitiesImported:           "✅ 已导入 %d 个活动。可使用 /workplan conflicts 检查与工作计划的冲突。",
+			msgActivityImportFailed:         "导入活动失败，未保存任何内容。请重新发送文件以重试。",
+			msgDashboardTitle:               "工作计划 %s",
+			msgDashboardAllOrgs:             "所有委员会",
+			msgDashboardFilter:              "筛选",
+			msgDashboardPrint:               "打印",
+			msgDashboardCalendar:            "日历",
+			msgDashboardList:                "活动列表",
+			msgDashboardPrevMonth:           "上个月",
+			msgDashboardNextMonth:           "下个月",
+			msgOrgUsage:                     "用法：\n/org - 列出所有委员会\n/org add <名称> <显示名称> [表情]\n/org rename <名称> <显示名称> [表情]\n/org archive <名称>\n/org restore <名称>\n/org addadmin <名称> <用户 ID>\n/org removeadmin <名称> <用户 ID>",
+			msgOrgLine:                      "%s - %s",
+			msgOrgArchivedTag:               "（已归档）",
+			msgOrgInvalidUserID:             "无效的用户 ID %q！请使用数字形式的 Telegram 用户 ID。",
+			msgOrgAdminAdded:                "用户 %d 现在是 %s 的管理员。",
+			msgOrgAdminRemoved:              "用户 %d 不再是 %s 的管理员。",
+			msgOrgAdminNotFound:             "用户 %d 不是 %s 的管理员。",
+			msgOrgAdminsTag:                 "（管理员：%s）",
+			msgNoOrgs:                       "还没有委员会。使用 /org add <名称> <显示名称> [表情] 添加。",
+			msgOrgInvalidName:               "无效的名称 %s。请使用最多 16 个字母、数字或 -。",
+			msgOrgExists:                    "委员会 %s 已存在。",
+			msgOrgNotFound:                  "未找到委员会 %s。",
+			msgOrgGetFailed:                 "获取委员会失败！请重试。",
+			msgOrgSaveFailed:                "保存委员会失败！请重试。",
+			msgOrgAdded:                     "已添加委员会 %s。",
+			msgOrgRenamed:                   "已重命名委员会 %s。",
+			msgOrgArchived:                  "已归档委员会 %s，新活动将无法再选择它。",
+			msgOrgRestored:                  "已恢复委员会 %s。",
+		},
+	},
+}
+
+// languageCodes returns the codes of all supported languages
+func languageCodes() []string {
+	codes := make([]string, 0, len(languages))
+	for code := range languages {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Localizer renders messages and times in the language and timezone of a chat
+type Localizer struct {
+	Language string
+	Location *time.Location
+}
+
+// NewLocalizer creates a Localizer, unknown languages fall back to the default language
+func NewLocalizer(language string, loc *time.Location) *Localizer {
+	if _, ok := languages[language]; !ok {
+		language = defaultLanguage
+	}
+	return &Localizer{Language: language, Location: loc}
+}
+
+func (l *Localizer) language() *Language {
+	if lang, ok := languages[l.Language]; ok {
+		return lang
+	}
+	return languages[defaultLanguage]
+}
+
+// T returns the message for the key formatted with the args
+func (l *Localizer) T(key MessageKey, args ...any) string {
+	msg, ok := l.language().Messages[key]
+	if !ok {
+		slog.Warn("missing message", "language", l.Language, "key", key)
+		if msg, ok = languages[defaultLanguage].Messages[key]; !ok {
+			msg = string(key)
+		}
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// FormatTime formats a stored UTC time in the timezone of the chat
+func (l *Localizer) FormatTime(t time.Time) string {
+	return l.format(t.In(l.Location), l.language().TimeFormat)
+}
+
+// FormatDate formats the date of t, keeping the location of t
+func (l *Localizer) FormatDate(t time.Time) string {
+	return l.format(t, l.language().DateFormat)
+}
+
+// FormatMonth formats the month of t, keeping the location of t
+func (l *Localizer) FormatMonth(t time.Time) string {
+	return l.format(t, l.language().MonthFormat)
+}
+
+func (l *Localizer) format(t time.Time, layout string) string {
+	lang := l.language()
+	if lang.Weekdays != nil {
+		layout = strings.ReplaceAll(layout, "Mon", lang.Weekdays[t.Weekday()])
+	}
+	return t.Format(layout)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+	"time"
+)
+
+// getMessageKeys returns all MessageKey constants declared in i18n.go
+func getMessageKeys(t *testing.T) []MessageKey {
+	file, err := parser.ParseFile(token.NewFileSet(), "i18n.go", nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse i18n.go: %v", err)
+	}
+	var keys []MessageKey
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			value := spec.(*ast.ValueSpec)
+			if ident, ok := value.Type.(*ast.Ident); !ok || ident.Name != "MessageKey" {
+				continue
+			}
+			for _, v := range value.Values {
+				keys = append(keys, MessageKey(strings.Trim(v.(*ast.BasicLit).Value, `"`)))
+			}
+		}
+	}
+	return keys
+}
+
+func TestMessageBundlesComplete(t *testing.T) {
+	keys := getMessageKeys(t)
+	if len(keys) == 0 {
+		t.Fatal("No message keys found")
+	}
+	english := languages[langEnglish].Messages
+	for code, lang := range languages {
+		for _, key := range keys {
+			msg, ok := lang.Messages[key]
+			if !ok || msg == "" {
+				t.Errorf("Language %s is missing message %s", code, key)
+				continue
+			}
+			if strings.Count(msg, "%") != strings.Count(english[key], "%") {
+				t.Errorf("Language %s has different format verbs for %s: %q vs %q", code, key, msg, english[key])
+			}
+		}
+		if len(lang.Messages) != len(keys) {
+			t.Errorf("Language %s has %d messages, expected %d", code, len(lang.Messages), len(keys))
+		}
+		if len(lang.PickerWeekdays) != 7 || (lang.Weekdays != nil && len(lang.Weekdays) != 7) {
+			t.Errorf("Language %s has incomplete weekday names", code)
+		}
+	}
+}
+
+func TestLocalizerFormatTime(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatalf("Failed to load location: %v", err)
+	}
+	// Saturday 8 March 2025 18:30 in Shanghai
+	startedAt := time.Date(2025, 3, 8, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		language string
+		time     string
+		month    string
+	}{
+		{langEnglish, "Sat, 2025-03-08 18:30", "Mar 2025"},
+		{langChinese, "2025-03-08 (周六) 18:30", "2025年3月"},
+		{"unknown", "Sat, 2025-03-08 18:30", "Mar 2025"},
+	}
+	for _, tt := range tests {
+		l := NewLocalizer(tt.language, loc)
+		if got := l.FormatTime(startedAt); got != tt.time {
+			t.Errorf("FormatTime in %s = %q; want %q", tt.language, got, tt.time)
+		}
+		if got := l.FormatMonth(startedAt.In(loc)); got != tt.month {
+			t.Errorf("FormatMonth in %s = %q; want %q", tt.language, got, tt.month)
+		}
+	}
+
+	if got := NewLocalizer(langChinese, loc).T(msgStartTimeSet, "x"); got != "开始时间已设为 x" {
+		t.Errorf("Unexpected translation %q", got)
+	}
+}
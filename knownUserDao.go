@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// KnownUser is a Telegram user the bot has seen, so that @username mentions
+// can be resolved to the user ID needed to send direct messages
+type KnownUser struct {
+	ID       int64
+	Username string
+	FullName string
+}
+
+// KnownUserDAO keeps the users the bot has seen in the local database
+type KnownUserDAO struct {
+	db *sql.DB
+}
+
+// NewKnownUserDAO creates a new KnownUserDAO instance
+func NewKnownUserDAO(db *sql.DB) *KnownUserDAO {
+	return &KnownUserDAO{db: db}
+}
+
+// Remember stores the user, or updates the username and name if they changed
+func (dao *KnownUserDAO) Remember(user *models.User) error {
+	query := `INSERT INTO known_users (id, username, full_name, updated_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(id) DO UPDATE SET username = excluded.username, full_name = excluded.full_name, updated_at = CURRENT_TIMESTAMP`
+	_, err := dao.db.Exec(query, user.ID, user.Username, getUserFullName(user))
+	return err
+}
+
+// GetByUsername returns the user with the case-insensitive username, without the leading @
+func (dao *KnownUserDAO) GetByUsername(username string) (*KnownUser, error) {
+	query := `SELECT id, username, full_name FROM known_users WHERE username = ? COLLATE NOCASE
+		ORDER BY updated_at DESC LIMIT 1`
+	user := &KnownUser{}
+	err := dao.db.QueryRow(query, strings.TrimPrefix(username, "@")).Scan(&user.ID, &user.Username, &user.FullName)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// knownUsersMiddleware remembers the senders of updates and the users mentioned by name in messages
+func knownUsersMiddleware(users KnownUserRepository) bot.Middleware {
+	return func(next bot.HandlerFunc) bot.HandlerFunc {
+		return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+			for _, user := range getUpdateUsers(update) {
+				if user.IsBot {
+					continue
+				}
+				if err := users.Remember(user); err != nil {
+					slog.ErrorContext(ctx, "error remembering user", "user_id", user.ID, "err", err)
+				}
+			}
+			next(ctx, b, update)
+		}
+	}
+}
+
+// getUpdateUsers returns the sender of the update and the users of its text mentions
+func getUpdateUsers(update *models.Update) []*models.User {
+	var users []*models.User
+	switch {
+	case update.Message != nil:
+		if update.Message.From != nil {
+			users = append(users, update.Message.From)
+		}
+		for _, entity := range update.Message.Entities {
+			if entity.Type == models.MessageEntityTypeTextMention && entity.User != nil {
+				users = append(users, entity.User)
+			}
+		}
+	case update.CallbackQuery != nil:
+		users = append(users, &update.CallbackQuery.From)
+	}
+	return users
+}
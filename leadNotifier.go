@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/go-telegram/bot"
+)
+
+const leadReminderCheckInterval = time.Hour
+
+// LeadNotifier sends direct messages to the leads and co-leads of activities
+// whose Telegram user IDs are known. Telegram only delivers them to users who
+// have started a private chat with the bot
+type LeadNotifier struct {
+	activities   ActivityRepository
+	chatSettings ChatSettingsRepository
+	// reminderDays is how many days before an activity its leads are reminded, 0 disables reminders
+	reminderDays int
+}
+
+func NewLeadNotifier(activities ActivityRepository, chatSettings ChatSettingsRepository, reminderDays int) *LeadNotifier {
+	return &LeadNotifier{activities: activities, chatSettings: chatSettings, reminderDays: reminderDays}
+}
+
+// activityLead is a lead or co-lead who can be sent direct messages
+type activityLead struct {
+	ID     int64
+	CoLead bool
+}
+
+func (lead activityLead) role(l *Localizer) string {
+	if lead.CoLead {
+		return l.T(msgRoleCoLead)
+	}
+	return l.T(msgRoleLead)
+}
+
+// contactableLeads returns the lead and co-leads with known user IDs, the lead first.
+// Someone who is both the lead and a co-lead is only the lead
+func (a Activity) contactableLeads() []activityLead {
+	var leads []activityLead
+	seen := make(map[int64]bool)
+	add := func(id int64, coLead bool) {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			leads = append(leads, activityLead{ID: id, CoLead: coLead})
+		}
+	}
+	add(a.LeadID, false)
+	for i := range a.CoLeads {
+		add(a.coLeadID(i), true)
+	}
+	return leads
+}
+
+// changedDetails reports whether anything the leads care about differs between the activities
+func (a Activity) changedDetails(other Activity) bool {
+	return a.Name != other.Name || a.Org != other.Org || !a.StartedAt.Equal(other.StartedAt) ||
+		!a.endTime().Equal(other.endTime()) || a.Location != other.Location ||
+		a.Description != other.Description || a.status() != other.status()
+}
+
+// notifyChange tells the leads about a new activity, prev is nil, or a changed one.
+// Leads are told when they are assigned or removed, the others what has changed.
+// The user who made the change is not told
+func (n *LeadNotifier) notifyChange(ctx context.Context, b Messenger, prev *Activity, activity Activity, changedByID int64) {
+	before := make(map[int64]activityLead)
+	if prev != nil {
+		for _, lead := range prev.contactableLeads() {
+			before[lead.ID] = lead
+		}
+	}
+	for _, lead := range activity.contactableLeads() {
+		prevLead, wasLead := before[lead.ID]
+		delete(before, lead.ID)
+		if lead.ID == changedByID {
+			continue
+		}
+		switch {
+		case !wasLead || prevLead.CoLead != lead.CoLead:
+			n.send(ctx, b, lead, activity, msgLeadAssigned)
+		case activity.changedDetails(*prev):
+			n.send(ctx, b, lead, activity, msgLeadActivityChanged)
+		}
+	}
+	for _, lead := range before {
+		if lead.ID != changedByID {
+			n.send(ctx, b, lead, activity, msgLeadUnassigned)
+		}
+	}
+}
+
+// notifyDeleted tells the leads, except the user who deleted it, that the activity was moved to the trash
+func (n *LeadNotifier) notifyDeleted(ctx context.Context, b Messenger, activity Activity, deletedByID int64) {
+	for _, lead := range activity.contactableLeads() {
+		if lead.ID != deletedByID {
+			n.send(ctx, b, lead, activity, msgLeadActivityDeleted)
+		}
+	}
+}
+
+// sendReminders reminds the leads of the activities starting within reminderDays which they were not reminded of.
+// Each reminder is claimed before it is sent, so replicas sharing the database send it once
+func (n *LeadNotifier) sendReminders(ctx context.Context, b Messenger, now time.Time) {
+	activities, err := n.activities.GetByDuration(now, now.AddDate(0, 0, n.reminderDays))
+	if err != nil {
+		slog.ErrorContext(ctx, "error retrieving activities to remind", "err", err)
+		return
+	}
+	for _, activity := range activities {
+		status := activity.status()
+		leads := activity.contactableLeads()
+		if activity.RemindedAt != nil || len(leads) == 0 || (status != ActivityPlanned && status != ActivityConfirmed) {
+			continue
+		}
+		claimed, err := n.activities.MarkReminded(activity.ID, now)
+		if err != nil {
+			slog.ErrorContext(ctx, "error marking activity reminded", "activity_id", activity.ID, "err", err)
+			continue
+		}
+		if claimed != 1 {
+			continue
+		}
+		for _, lead := range leads {
+			n.send(ctx, b, lead, activity, msgLeadReminder)
+		}
+	}
+}
+
+// run sends the reminders until the context is done
+func (n *LeadNotifier) run(ctx context.Context, b Messenger) {
+	ticker := time.NewTicker(leadReminderCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			n.sendReminders(ctx, b, now)
+		}
+	}
+}
+
+// send messages the lead in the language of their private chat with the bot
+func (n *LeadNotifier) send(ctx context.Context, b Messenger, lead activityLead, activity Activity, key MessageKey) {
+	l := n.chatSettings.GetLocalizer(ctx, lead.ID)
+	_, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    lead.ID,
+		Text:      l.T(key, lead.role(l), activity.string(l)),
+		ParseMode: "HTML",
+	})
+	if err != nil {
+		// most likely the lead has not started a private chat with the bot
+		slog.WarnContext(ctx, "error sending lead notification", "activity_id", activity.ID, "lead_id", lead.ID, "err", err)
+		return
+	}
+	slog.InfoContext(ctx, "lead notified", "activity_id", activity.ID, "lead_id", lead.ID, "message", key)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// sentTo returns the chat IDs of the sent messages, in order
+func (m *recordingMessenger) sentTo() []any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var chats []any
+	for _, call := range m.calls {
+		if call.Method == "sendMessage" {
+			chats = append(chats, call.ChatID)
+		}
+	}
+	return chats
+}
+
+func TestLeadNotifierNotifyChange(t *testing.T) {
+	start := time.Date(2030, 5, 1, 10, 0, 0, 0, time.UTC)
+	hike := Activity{ID: 1, Name: "Hike", Org: OrgPEAK, Lead: "Alice", LeadID: 1, CoLeads: []string{"Bob", "Carol"},
+		CoLeadIDs: []int64{2, 0}, StartedAt: start}
+	moved := hike
+	moved.StartedAt = start.Add(time.Hour)
+	swapped := hike
+	swapped.Lead, swapped.LeadID = "Bob", 2
+	swapped.CoLeads, swapped.CoLeadIDs = []string{"Dave"}, []int64{4}
+
+	tests := []struct {
+		name        string
+		prev        *Activity
+		activity    Activity
+		changedByID int64
+		wantTo      []any
+		wantTexts   []string
+	}{
+		{
+			name:      "new activity",
+			activity:  hike,
+			wantTo:    []any{int64(1), int64(2)},
+			wantTexts: []string{"You are now the lead", "You are now the co-lead"},
+		},
+		{
+			name:        "not sent to the creator",
+			activity:    hike,
+			changedByID: 1,
+			wantTo:      []any{int64(2)},
+			wantTexts:   []string{"You are now the co-lead"},
+		},
+		{
+			name:      "details changed",
+			prev:      &hike,
+			activity:  moved,
+			wantTo:    []any{int64(1), int64(2)},
+			wantTexts: []string{"An activity you are the lead of has changed", "An activity you are the co-lead of has changed"},
+		},
+		{
+			name:     "nothing changed",
+			prev:     &hike,
+			activity: hike,
+		},
+		{
+			name:      "leads changed",
+			prev:      &hike,
+			activity:  swapped,
+			wantTo:    []any{int64(2), int64(4), int64(1)},
+			wantTexts: []string{"You are now the lead", "You are now the co-lead", "You are no longer the lead"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := newTestBot(t)
+			n := NewLeadNotifier(tb.activityDAO, tb.chatSettings, 2)
+			n.notifyChange(context.Background(), tb.messenger, tt.prev, tt.activity, tt.changedByID)
+			assertTexts(t, tb.messenger.texts(), tt.wantTexts)
+			if got := tb.messenger.sentTo(); !reflect.DeepEqual(got, tt.wantTo) {
+				t.Errorf("Expected messages to %v, got %v", tt.wantTo, got)
+			}
+		})
+	}
+}
+
+func TestLeadNotifierSendReminders(t *testing.T) {
+	tb := newTestBot(t)
+	now := time.Date(2030, 5, 1, 10, 0, 0, 0, time.UTC)
+	for _, a := range []Activity{
+		{Name: "Tomorrow", LeadID: 1, StartedAt: now.Add(24 * time.Hour)},
+		{Name: "Without ids", StartedAt: now.Add(24 * time.Hour)},
+		{Name: "Cancelled", LeadID: 1, StartedAt: now.Add(24 * time.Hour), Status: ActivityCancelled},
+		{Name: "Next week", LeadID: 1, StartedAt: now.Add(7 * 24 * time.Hour)},
+	} {
+		a.Org, a.Lead = OrgCC, "Alice"
+		if _, err := tb.activityDAO.Save(&a); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+	}
+	n := NewLeadNotifier(tb.activityDAO, tb.chatSettings, 2)
+	n.sendReminders(context.Background(), tb.messenger, now)
+	assertTexts(t, tb.messenger.texts(), []string{"Reminder: you are the lead of this upcoming activity:\n\n<b>Thu, 2030-05-02 10:00 Tomorrow"})
+
+	// each activity is only reminded of once
+	n.sendReminders(context.Background(), tb.messenger, now.Add(time.Hour))
+	if got := len(tb.messenger.texts()); got != 1 {
+		t.Errorf("Expected no more reminders, got %d messages", got)
+	}
+	activity, err := tb.activityDAO.GetByID(1)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if activity.RemindedAt == nil || !activity.RemindedAt.Equal(now) {
+		t.Errorf("Expected the activity to be marked reminded at %v, got %v", now, activity.RemindedAt)
+	}
+
+	// a replica which loaded the activity before it was reminded loses the claim
+	stale := NewLeadNotifier(staleReminders{tb.activityDAO}, tb.chatSettings, 2)
+	stale.sendReminders(context.Background(), tb.messenger, now.Add(2*time.Hour))
+	if got := len(tb.messenger.texts()); got != 1 {
+		t.Errorf("Expected the claimed reminder not to be sent again, got %d messages", got)
+	}
+}
+
+// staleReminders hides that activities were reminded, as if they were loaded before another replica claimed them
+type staleReminders struct {
+	ActivityRepository
+}
+
+func (r staleReminders) GetByDuration(startTime, endTime time.Time) ([]Activity, error) {
+	activities, err := r.ActivityRepository.GetByDuration(startTime, endTime)
+	for i := range activities {
+		activities[i].RemindedAt = nil
+	}
+	return activities, err
+}
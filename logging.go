@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/go-telegram/bot/models"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const defaultLogLevel = "info"
+
+type logAttrsKey struct{}
+
+// withLogAttrs returns a context whose log lines carry the given attributes
+// in addition to the ones already attached to ctx
+func withLogAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	parent, _ := ctx.Value(logAttrsKey{}).([]slog.Attr)
+	merged := make([]slog.Attr, 0, len(parent)+len(attrs))
+	merged = append(merged, parent...)
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, logAttrsKey{}, merged)
+}
+
+// withUpdateLogAttrs attaches the update, chat and user IDs and the handler name to ctx
+func withUpdateLogAttrs(ctx context.Context, handler string, update *models.Update) context.Context {
+	attrs := []slog.Attr{
+		slog.Int64("update_id", update.ID),
+		slog.String("handler", handler),
+	}
+	switch {
+	case update.Message != nil:
+		attrs = append(attrs, slog.Int64("chat_id", update.Message.Chat.ID))
+		if update.Message.From != nil {
+			attrs = append(attrs, slog.Int64("user_id", update.Message.From.ID))
+		}
+	case update.CallbackQuery != nil:
+		if msg := update.CallbackQuery.Message.Message; msg != nil {
+			attrs = append(attrs, slog.Int64("chat_id", msg.Chat.ID))
+		}
+		attrs = append(attrs, slog.Int64("user_id", update.CallbackQuery.From.ID))
+	}
+	return withLogAttrs(ctx, attrs...)
+}
+
+// contextHandler adds the attributes attached with withLogAttrs to every record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs, ok := ctx.Value(logAttrsKey{}).([]slog.Attr); ok {
+		r.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// newLogger returns a JSON logger writing to w
+func newLogger(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(contextHandler{slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})})
+}
+
+// parseLogLevel parses debug, info, warn or error
+func parseLogLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(s))
+	return level, err
+}
+
+func setupLogging(logger LogConfig) {
+	// Configure the lumberjack logger
+	logFile := &lumberjack.Logger{
+		Filename:   logger.Filename,   // Log file path
+		MaxSize:    logger.MaxSize,    // Max megabytes before rotation
+		MaxBackups: logger.MaxBackups, // Max number of old log files to keep
+		MaxAge:     logger.MaxAge,     // Max number of days to retain logs
+		Compress:   logger.Compress,   // Compress the old logs
+	}
+
+	level, err := parseLogLevel(logger.Level)
+	if err != nil {
+		level = slog.LevelInfo
+	}
+
+	// Optionally, also log to console
+	multiWriter := io.MultiWriter(os.Stdout, logFile)
+	slog.SetDefault(newLogger(multiWriter, level))
+}
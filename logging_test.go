@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+)
+
+func TestLoggerAddsUpdateAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newLogger(&buf, slog.LevelInfo)
+
+	update := &models.Update{
+		ID: 42,
+		Message: &models.Message{
+			Chat: models.Chat{ID: -100},
+			From: &models.User{ID: 7},
+		},
+	}
+	ctx := withUpdateLogAttrs(context.Background(), "poll", update)
+	logger.InfoContext(ctx, "handling update", "event_id", 3)
+	logger.DebugContext(ctx, "filtered by level")
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("expected a single JSON line, got %q: %v", buf.String(), err)
+	}
+	want := map[string]any{
+		"msg":       "handling update",
+		"level":     "INFO",
+		"update_id": float64(42),
+		"chat_id":   float64(-100),
+		"user_id":   float64(7),
+		"handler":   "poll",
+		"event_id":  float64(3),
+	}
+	for key, value := range want {
+		if line[key] != value {
+			t.Errorf("%s = %v, want %v", key, line[key], value)
+		}
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	for input, want := range map[string]slog.Level{
+		"debug": slog.LevelDebug,
+		"info":  slog.LevelInfo,
+		"WARN":  slog.LevelWarn,
+		"error": slog.LevelError,
+	} {
+		got, err := parseLogLevel(input)
+		if err != nil || got != want {
+			t.Errorf("parseLogLevel(%q) = %v, %v, want %v", input, got, err, want)
+		}
+	}
+	if _, err := parseLogLevel("verbose"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
@@ -2,16 +2,17 @@ package main
 
 import (
 	"context"
-	"database/sql"
-	"io"
-	"log"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"time"
 
 	"github.com/go-telegram/bot"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
+const telegramPollTimeout = time.Minute
+
 func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
@@ -24,76 +25,129 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	// administrative subcommands work on the database and exit
+	if len(os.Args) > 1 {
+		code := runCLI(ctx, config, dbFileName, os.Args[1:], os.Stdout, os.Stderr)
+		cancel()
+		os.Exit(code)
+	}
 	// setup logging
 	setupLogging(config.Logger)
-	defer log.Println("Stopping app")
+	defer slog.Info("stopping app")
 
 	// Add database connection
-	db, err := sql.Open("sqlite", "events.db")
+	db, err := openInstrumentedDB("sqlite", dbFileName)
 	if err != nil {
 		panic(err)
 	}
 	defer db.Close()
 
-	err = MigrateDB(db)
+	err = MigrateDB(db, config.Timezone)
 	if err != nil {
 		panic(err)
 	}
 
-	// Initialize the DAO
-	eventDAO := NewEventDAO(db)
-	err = eventDAO.Initialize()
+	repos, err := OpenRepositories(config.Database, db)
 	if err != nil {
 		panic(err)
 	}
+	defer repos.Close()
+	if err := repos.Migrate(); err != nil {
+		panic(err)
+	}
+	eventDAO := repos.Events
+	activityDAO := repos.Activities
+	orgDAO := repos.Orgs
+	chatSettingsDAO := repos.ChatSettings
+	knownUserDAO := repos.KnownUsers
+	leadNotifier := NewLeadNotifier(activityDAO, chatSettingsDAO, config.Notifications.LeadReminderDays)
 
-	activityDAO := NewActivityDAO(db)
-	err = activityDAO.Initialize()
+	userStates, err := NewStateStore(config.StateStore, repos)
 	if err != nil {
 		panic(err)
 	}
+	registerStateStoreMetrics(userStates)
 
-	createEventHandler := NewCreateEventHandler(eventDAO, config.BotName)
-	eventPollResponseHandler := NewEventPollResponseHandler(eventDAO)
-	activityHandler := NewActivityHandler(activityDAO)
-	userHandler := NewUserHandler(eventDAO)
-	defaultHandler := NewDefaultHandler(createEventHandler, activityHandler)
+	createEventHandler := NewCreateEventHandler(eventDAO, userStates, chatSettingsDAO, config.BotName)
+	eventPollResponseHandler := NewEventPollResponseHandler(eventDAO, chatSettingsDAO)
+	activityHandler := NewActivityHandler(activityDAO, orgDAO, eventDAO, knownUserDAO, leadNotifier, userStates, chatSettingsDAO)
+	userHandler := NewUserHandler(eventDAO, chatSettingsDAO)
+	chatSettingsHandler := NewChatSettingsHandler(chatSettingsDAO)
+	backups := NewBackups(repos, config.Backup, config.Timezone)
+	backupHandler := NewBackupHandler(backups, config, chatSettingsDAO)
+	orgHandler := NewOrgHandler(orgDAO, config, chatSettingsDAO)
+	wizards := append(createEventHandler.wizards(), activityHandler.wizards()...)
+	defaultHandler := NewDefaultHandler(userStates, chatSettingsDAO, wizards...)
 
 	opts := []bot.Option{
-		bot.WithDefaultHandler(defaultHandler.handle),
-		bot.WithMessageTextHandler("/poll", bot.MatchTypeExact, createEventHandler.handleStart), // start to create a new poll
-		bot.WithMessageTextHandler("/send", bot.MatchTypePrefix, createEventHandler.handleSend), // send a poll by id
-		bot.WithMessageTextHandler("/myvotes", bot.MatchTypeExact, userHandler.sendMyVotedEvents),
-		bot.WithMessageTextHandler("/workplan", bot.MatchTypePrefix, activityHandler.handleWorkplan),
+		bot.WithHTTPClient(telegramPollTimeout, &instrumentedHTTPClient{client: &http.Client{Timeout: telegramPollTimeout}}),
+		bot.WithErrorsHandler(func(err error) { slog.Error("telegram bot error", "err", err) }),
+		bot.WithMiddlewares(knownUsersMiddleware(knownUserDAO)),
+		bot.WithDefaultHandler(instrumentHandler("default", defaultHandler.handle)),
+		bot.WithMessageTextHandler("/poll", bot.MatchTypeExact, instrumentHandler("poll", createEventHandler.handleStart)), // start to create a new poll
+		bot.WithMessageTextHandler("/send", bot.MatchTypePrefix, instrumentHandler("send", createEventHandler.handleSend)), // send a poll by id
+		bot.WithMessageTextHandler("/myvotes", bot.MatchTypeExact, instrumentHandler("myvotes", userHandler.sendMyVotedEvents)),
+		bot.WithMessageTextHandler("/workplan", bot.MatchTypePrefix, instrumentHandler("workplan", activityHandler.handleWorkplan)),
+		bot.WithMessageTextHandler("/settimezone", bot.MatchTypePrefix, instrumentHandler("settimezone", chatSettingsHandler.handleSetTimezone)),
+		bot.WithMessageTextHandler("/setlanguage", bot.MatchTypePrefix, instrumentHandler("setlanguage", chatSettingsHandler.handleSetLanguage)),
+		bot.WithMessageTextHandler("/backup", bot.MatchTypeExact, instrumentHandler("backup", backupHandler.handleBackup)),
+		bot.WithMessageTextHandler("/org", bot.MatchTypePrefix, instrumentHandler("org", orgHandler.handleOrg)),
+		bot.WithMessageTextHandler("/cancel", bot.MatchTypeExact, instrumentHandler("cancel", defaultHandler.handleCancel)),
+		bot.WithMessageTextHandler("/back", bot.MatchTypeExact, instrumentHandler("back", defaultHandler.handleBack)),
+		bot.WithCallbackQueryDataHandler(cancelStateCallbackPrefix, bot.MatchTypeExact, instrumentHandler("cancelCallback", defaultHandler.handleCancelCallback)),
+		bot.WithCallbackQueryDataHandler(datePickerCallbackPrefix+callbackSeparator, bot.MatchTypePrefix, instrumentHandler("datePicker", defaultHandler.handleDatePickerCallback)),
+		bot.WithCallbackQueryDataHandler(wizardInputCallbackPrefix+callbackSeparator, bot.MatchTypePrefix, instrumentHandler("wizardInput", defaultHandler.handleWizardInputCallback)),
 		// poll callbacks
-		bot.WithCallbackQueryDataHandler(updatePollCallbackPrefix, bot.MatchTypePrefix, createEventHandler.handleUpdatePollCallback),
-		bot.WithCallbackQueryDataHandler(pollDeleteOptionCallbackPrefix, bot.MatchTypePrefix, createEventHandler.handleDeleteOptionCallback),
-		bot.WithCallbackQueryDataHandler(eventCallbackPrefix, bot.MatchTypePrefix, eventPollResponseHandler.handle),
+		bot.WithCallbackQueryDataHandler(updatePollCallbackPrefix, bot.MatchTypePrefix, instrumentHandler("updatePoll", createEventHandler.handleUpdatePollCallback)),
+		bot.WithCallbackQueryDataHandler(pollDeleteOptionCallbackPrefix, bot.MatchTypePrefix, instrumentHandler("deleteOption", createEventHandler.handleDeleteOptionCallback)),
+		bot.WithCallbackQueryDataHandler(eventCallbackPrefix, bot.MatchTypePrefix, instrumentHandler("vote", eventPollResponseHandler.handle)),
 		// workplan callbacks
-		bot.WithCallbackQueryDataHandler(workplanCallbackPrefix, bot.MatchTypePrefix, activityHandler.handleWorkplanCallback),
-		bot.WithCallbackQueryDataHandler(workplanViewByMonthCallbackPrefix, bot.MatchTypePrefix, activityHandler.handleViewByMonth),
-		bot.WithCallbackQueryDataHandler(workplanUpdateEventCallbackPrefix, bot.MatchTypePrefix, activityHandler.handleUpdateActivityCallback),
+		bot.WithCallbackQueryDataHandler(workplanCallbackPrefix, bot.MatchTypePrefix, instrumentHandler("workplanMenu", activityHandler.handleWorkplanCallback)),
+		bot.WithCallbackQueryDataHandler(workplanViewByMonthCallbackPrefix, bot.MatchTypePrefix, instrumentHandler("workplanViewByMonth", activityHandler.handleViewByMonth)),
+		bot.WithCallbackQueryDataHandler(workplanUpdateEventCallbackPrefix, bot.MatchTypePrefix, instrumentHandler("workplanUpdate", activityHandler.handleUpdateActivityCallback)),
+		bot.WithCallbackQueryDataHandler(workplanFilterOrgCallbackPrefix, bot.MatchTypePrefix, instrumentHandler("workplanFilterOrg", activityHandler.handleFilterByOrg)),
+		bot.WithCallbackQueryDataHandler(workplanRestoreCallbackPrefix, bot.MatchTypePrefix, instrumentHandler("workplanRestore", activityHandler.handleRestoreActivity)),
+	}
+	if config.Webhook.enabled() {
+		opts = append(opts, bot.WithWebhookSecretToken(config.Webhook.SecretToken))
 	}
 	b, err := bot.New(config.TelegramToken, opts...)
 	if err != nil {
 		panic(err)
 	}
 
-	log.Println("Starting App,", "bot name:", config.BotName, "timezone:", config.Timezone)
-	b.Start(ctx)
-}
-
-func setupLogging(logger LogConfig) {
-	// Configure the lumberjack logger
-	logFile := &lumberjack.Logger{
-		Filename:   logger.Filename,   // Log file path
-		MaxSize:    logger.MaxSize,    // Max megabytes before rotation
-		MaxBackups: logger.MaxBackups, // Max number of old log files to keep
-		MaxAge:     logger.MaxAge,     // Max number of days to retain logs
-		Compress:   logger.Compress,   // Compress the old logs
+	go runStateExpiry(ctx, b, userStates)
+	go activityHandler.runTrashPurge(ctx)
+	if backups.enabled() {
+		go backups.run(ctx)
+	} else if backups.shared && config.Backup.Dir != "" {
+		slog.Warn("backups are not taken of the postgres database, back it up with pg_dump instead", "dir", config.Backup.Dir)
+	}
+	if config.Notifications.LeadReminderDays > 0 {
+		go leadNotifier.run(ctx, b)
+	}
+	if config.HTTP.ListenAddr != "" {
+		var api *API
+		if len(config.HTTP.APITokens) > 0 {
+			api = NewAPI(eventDAO, activityDAO, orgDAO, chatSettingsDAO, leadNotifier, b, config.HTTP.APITokens, config.Timezone)
+		}
+		var dashboard *Dashboard
+		if config.HTTP.DashboardToken != "" {
+			dashboard = NewDashboard(activityDAO, orgDAO, config.HTTP.DashboardToken, NewLocalizer(config.Language, config.Timezone))
+		}
+		go func() {
+			if err := runHTTPServer(ctx, config.HTTP, db, repos.postgres, api, dashboard); err != nil {
+				slog.Error("error running http server", "err", err)
+			}
+		}()
 	}
 
-	// Optionally, also log to console
-	multiWriter := io.MultiWriter(os.Stdout, logFile)
-	log.SetOutput(multiWriter)
+	slog.Info("starting app", "bot_name", config.BotName, "timezone", config.Timezone.String(), "webhook", config.Webhook.enabled())
+	if config.Webhook.enabled() {
+		if err := runWebhook(ctx, b, config.Webhook); err != nil {
+			slog.Error("error running webhook", "err", err)
+		}
+		return
+	}
+	b.Start(ctx)
 }
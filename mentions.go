@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf16"
+
+	"github.com/go-telegram/bot/models"
+)
+
+// mentionedUser is a name entered by a user, with the Telegram user ID if it is known
+type mentionedUser struct {
+	Name string
+	ID   int64
+}
+
+// textMention is a mention of a user without a username, between the byte offsets start and end of the text
+type textMention struct {
+	start, end int
+	user       *models.User
+}
+
+// parseMentionedUsers splits the text on sep, or keeps it whole if sep is empty, into
+// the names of users. A text mention gives the name and ID of the user, an @username
+// the ID lookup finds for it. Other names are kept as they are without an ID
+func parseMentionedUsers(text string, entities []models.MessageEntity, sep string, lookup func(username string) int64) []mentionedUser {
+	var mentions []textMention
+	for _, entity := range entities {
+		if entity.Type == models.MessageEntityTypeTextMention && entity.User != nil {
+			mentions = append(mentions, textMention{
+				start: utf16ToByteOffset(text, entity.Offset),
+				end:   utf16ToByteOffset(text, entity.Offset+entity.Length),
+				user:  entity.User,
+			})
+		}
+	}
+
+	parts := []string{text}
+	if sep != "" {
+		parts = strings.Split(text, sep)
+	}
+	var users []mentionedUser
+	start := 0
+	for _, part := range parts {
+		end := start + len(part)
+		name := strings.TrimSpace(part)
+		user := mentionedUser{Name: name}
+		for _, m := range mentions {
+			if m.start >= start && m.end <= end {
+				user = mentionedUser{Name: getUserFullName(m.user), ID: m.user.ID}
+				break
+			}
+		}
+		if user.ID == 0 && isUsername(name) {
+			user.ID = lookup(name)
+		}
+		if user.Name != "" {
+			users = append(users, user)
+		}
+		start = end + len(sep)
+	}
+	return users
+}
+
+// isUsername reports whether the name is an @username
+func isUsername(name string) bool {
+	return len(name) > 1 && strings.HasPrefix(name, "@") && !strings.ContainsAny(name, " \t")
+}
+
+// utf16ToByteOffset converts an offset in UTF-16 code units, which Telegram uses
+// for entities, to the byte offset in the text
+func utf16ToByteOffset(text string, offset int) int {
+	units := 0
+	for i, r := range text {
+		if units >= offset {
+			return i
+		}
+		units += utf16.RuneLen(r)
+	}
+	return len(text)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+)
+
+func TestParseMentionedUsers(t *testing.T) {
+	carol := &models.User{ID: 3, FirstName: "Carol", LastName: "Chan"}
+	lookup := func(username string) int64 {
+		if username == "@bob" {
+			return 2
+		}
+		return 0
+	}
+	tests := []struct {
+		name     string
+		text     string
+		entities []models.MessageEntity
+		sep      string
+		want     []mentionedUser
+	}{
+		{
+			name: "plain names",
+			text: " Alice ;; Bob ",
+			sep:  ";",
+			want: []mentionedUser{{Name: "Alice"}, {Name: "Bob"}},
+		},
+		{
+			name: "usernames",
+			text: "@bob; @dave",
+			sep:  ";",
+			want: []mentionedUser{{Name: "@bob", ID: 2}, {Name: "@dave"}},
+		},
+		{
+			name:     "text mention after emoji",
+			text:     "🥾 Alice; Carol",
+			entities: []models.MessageEntity{{Type: models.MessageEntityTypeTextMention, Offset: 10, Length: 5, User: carol}},
+			sep:      ";",
+			want:     []mentionedUser{{Name: "🥾 Alice"}, {Name: "Carol Chan", ID: 3}},
+		},
+		{
+			name:     "whole text",
+			text:     "Carol; the hiker",
+			entities: []models.MessageEntity{{Type: models.MessageEntityTypeTextMention, Offset: 0, Length: 5, User: carol}},
+			want:     []mentionedUser{{Name: "Carol Chan", ID: 3}},
+		},
+		{
+			name: "empty",
+			text: "  ",
+			sep:  ";",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseMentionedUsers(tt.text, tt.entities, tt.sep, lookup); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMentionedUsers() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// Messenger is the part of the Telegram Bot API the handlers use.
+// *bot.Bot implements it; tests use a recording fake instead
+type Messenger interface {
+	SendMessage(ctx context.Context, params *bot.SendMessageParams) (*models.Message, error)
+	EditMessageText(ctx context.Context, params *bot.EditMessageTextParams) (*models.Message, error)
+	EditMessageReplyMarkup(ctx context.Context, params *bot.EditMessageReplyMarkupParams) (*models.Message, error)
+	AnswerCallbackQuery(ctx context.Context, params *bot.AnswerCallbackQueryParams) (bool, error)
+	SendDocument(ctx context.Context, params *bot.SendDocumentParams) (*models.Message, error)
+	GetFile(ctx context.Context, params *bot.GetFileParams) (*models.File, error)
+	FileDownloadLink(f *models.File) string
+}
+
+var _ Messenger = (*bot.Bot)(nil)
+
+// HandlerFunc handles an update, talking to Telegram only through the Messenger.
+// Wrap it with instrumentHandler to register it with the bot
+type HandlerFunc func(ctx context.Context, b Messenger, update *models.Update)
+
+// fileDownloadTimeout limits downloading a file sent to the bot
+const fileDownloadTimeout = time.Minute
+
+// downloadFile reads a file sent to the bot, failing if it is larger than maxSize bytes
+func downloadFile(ctx context.Context, b Messenger, fileID string, maxSize int64) ([]byte, error) {
+	file, err := b.GetFile(ctx, &bot.GetFileParams{FileID: fileID})
+	if err != nil {
+		return nil, err
+	}
+	if file.FileSize > maxSize {
+		return nil, fmt.Errorf("file of %d bytes exceeds %d bytes", file.FileSize, maxSize)
+	}
+	ctx, cancel := context.WithTimeout(ctx, fileDownloadTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.FileDownloadLink(file), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading file: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("file exceeds %d bytes", maxSize)
+	}
+	return data, nil
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	_ "modernc.org/sqlite"
+)
+
+// recordedCall is a Bot API call captured by recordingMessenger
+type recordedCall struct {
+	Method      string
+	ChatID      any
+	MessageID   int
+	Text        string
+	ReplyMarkup models.ReplyMarkup
+	Document    []byte
+}
+
+// recordingMessenger is a Messenger which records every call instead of talking to Telegram
+type recordingMessenger struct {
+	mu            sync.Mutex
+	lastMessageID int
+	calls         []recordedCall
+	// files are served by fileServer to be downloaded by file ID
+	files      map[string][]byte
+	fileServer *httptest.Server
+}
+
+func (m *recordingMessenger) record(call recordedCall) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, call)
+}
+
+func (m *recordingMessenger) SendMessage(ctx context.Context, params *bot.SendMessageParams) (*models.Message, error) {
+	m.mu.Lock()
+	m.lastMessageID++
+	id := m.lastMessageID
+	m.mu.Unlock()
+	m.record(recordedCall{Method: "sendMessage", ChatID: params.ChatID, MessageID: id, Text: params.Text, ReplyMarkup: params.ReplyMarkup})
+	return &models.Message{ID: id, Text: params.Text}, nil
+}
+
+func (m *recordingMessenger) EditMessageText(ctx context.Context, params *bot.EditMessageTextParams) (*models.Message, error) {
+	m.record(recordedCall{Method: "editMessageText", ChatID: params.ChatID, MessageID: params.MessageID, Text: params.Text, ReplyMarkup: params.ReplyMarkup})
+	return &models.Message{ID: params.MessageID, Text: params.Text}, nil
+}
+
+func (m *recordingMessenger) EditMessageReplyMarkup(ctx context.Context, params *bot.EditMessageReplyMarkupParams) (*models.Message, error) {
+	m.record(recordedCall{Method: "editMessageReplyMarkup", ChatID: params.ChatID, MessageID: params.MessageID, ReplyMarkup: params.ReplyMarkup})
+	return &models.Message{ID: params.MessageID}, nil
+}
+
+func (m *recordingMessenger) AnswerCallbackQuery(ctx context.Context, params *bot.AnswerCallbackQueryParams) (bool, error) {
+	m.record(recordedCall{Method: "answerCallbackQuery", Text: params.Text})
+	return true, nil
+}
+
+func (m *recordingMessenger) SendDocument(ctx context.Context, params *bot.SendDocumentParams) (*models.Message, error) {
+	m.mu.Lock()
+	m.lastMessageID++
+	id := m.lastMessageID
+	m.mu.Unlock()
+	call := recordedCall{Method: "sendDocument", ChatID: params.ChatID, MessageID: id, Text: params.Caption}
+	if upload, ok := params.Document.(*models.InputFileUpload); ok {
+		data, err := io.ReadAll(upload.Data)
+		if err != nil {
+			return nil, err
+		}
+		call.Document = data
+	}
+	m.record(call)
+	return &models.Message{ID: id, Caption: params.Caption}, nil
+}
+
+func (m *recordingMessenger) GetFile(ctx context.Context, params *bot.GetFileParams) (*models.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.files[params.FileID]
+	if !ok {
+		return nil, fmt.Errorf("unknown file %q", params.FileID)
+	}
+	return &models.File{FileID: params.FileID, FilePath: params.FileID, FileSize: int64(len(data))}, nil
+}
+
+func (m *recordingMessenger) FileDownloadLink(f *models.File) string {
+	return m.fileServer.URL + "/" + f.FilePath
+}
+
+// addFile makes the data downloadable as a file sent to the bot
+func (m *recordingMessenger) addFile(t *testing.T, fileID string, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.files == nil {
+		m.files = make(map[string][]byte)
+		m.fileServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			m.mu.Lock()
+			data, ok := m.files[strings.TrimPrefix(req.URL.Path, "/")]
+			m.mu.Unlock()
+			if !ok {
+				http.NotFound(w, req)
+				return
+			}
+			w.Write(data)
+		}))
+		t.Cleanup(m.fileServer.Close)
+	}
+	m.files[fileID] = data
+}
+
+// texts returns the text of every sent or edited message, in order
+func (m *recordingMessenger) texts() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var texts []string
+	for _, call := range m.calls {
+		if call.Method == "sendMessage" || call.Method == "editMessageText" {
+			texts = append(texts, call.Text)
+		}
+	}
+	return texts
+}
+
+// alerts returns the non-empty texts shown in answer to callback queries
+func (m *recordingMessenger) alerts() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var alerts []string
+	for _, call := range m.calls {
+		if call.Method == "answerCallbackQuery" && call.Text != "" {
+			alerts = append(alerts, call.Text)
+		}
+	}
+	return alerts
+}
+
+// testBot wires the handlers to a fresh database and a recordingMessenger
+type testBot struct {
+	db           *sql.DB
+	messenger    *recordingMessenger
+	eventDAO     *EventDAO
+	activityDAO  *ActivityDAO
+	orgDAO       *OrgDAO
+	knownUsers   *KnownUserDAO
+	chatSettings *ChatSettingsDAO
+	events       *CreateEventHandler
+	activities   *ActivityHandler
+	defaults     *DefaultHandler
+}
+
+func newTestBot(t *testing.T) *testBot {
+	prevConfig := AppConfig
+	AppConfig = &Config{BotName: "testbot", Timezone: time.UTC, Language: langEnglish}
+	t.Cleanup(func() { AppConfig = prevConfig })
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "events.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	tb := &testBot{
+		db:           db,
+		messenger:    &recordingMessenger{},
+		eventDAO:     NewEventDAO(db),
+		activityDAO:  NewActivityDAO(db),
+		orgDAO:       NewOrgDAO(db),
+		knownUsers:   NewKnownUserDAO(db),
+		chatSettings: NewChatSettingsDAO(db),
+	}
+	if err := MigrateDB(db, time.UTC); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	userStates := NewMemoryStateStore(defaultStateTTL)
+	tb.events = NewCreateEventHandler(tb.eventDAO, userStates, tb.chatSettings, "testbot")
+	tb.activities = NewActivityHandler(tb.activityDAO, tb.orgDAO, tb.eventDAO, tb.knownUsers,
+		NewLeadNotifier(tb.activityDAO, tb.chatSettings, 2), userStates, tb.chatSettings)
+	tb.defaults = NewDefaultHandler(userStates, tb.chatSettings, append(tb.events.wizards(), tb.activities.wizards()...)...)
+	return tb
+}
+
+// testStep is a single update fed to a handler
+type testStep struct {
+	handler func(tb *testBot) HandlerFunc
+	update  *models.Update
+}
+
+// run feeds the steps to their handlers and returns the texts sent in reply
+func (tb *testBot) run(t *testing.T, steps []testStep) []string {
+	t.Helper()
+	for _, step := range steps {
+		step.handler(tb)(context.Background(), tb.messenger, step.update)
+	}
+	return tb.messenger.texts()
+}
+
+const (
+	testChatID = int64(-1001)
+)
+
+var (
+	testAlice = &models.User{ID: 1, FirstName: "Alice"}
+	testBob   = &models.User{ID: 2, FirstName: "Bob"}
+)
+
+func textUpdate(from *models.User, text string) *models.Update {
+	return &models.Update{Message: &models.Message{
+		Chat: models.Chat{ID: testChatID},
+		From: from,
+		Text: text,
+	}}
+}
+
+func callbackUpdate(from *models.User, data string) *models.Update {
+	return &models.Update{CallbackQuery: &models.CallbackQuery{
+		ID:   "query",
+		From: *from,
+		Data: data,
+		Message: models.MaybeInaccessibleMessage{
+			Type:    models.MaybeInaccessibleMessageTypeMessage,
+			Message: &models.Message{ID: 100, Chat: models.Chat{ID: testChatID}},
+		},
+	}}
+}
+
+// assertTexts checks that each sent text contains the expected substring
+func assertTexts(t *testing.T, got []string, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d messages, got %d: %q", len(want), len(got), got)
+	}
+	for i := range want {
+		if !strings.Contains(got[i], want[i]) {
+			t.Errorf("Message %d = %q, want it to contain %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRecordingMessenger(t *testing.T) {
+	m := &recordingMessenger{}
+	var _ Messenger = m
+	first, _ := m.SendMessage(context.Background(), &bot.SendMessageParams{ChatID: testChatID, Text: "one"})
+	second, _ := m.SendMessage(context.Background(), &bot.SendMessageParams{ChatID: testChatID, Text: "two"})
+	m.EditMessageText(context.Background(), &bot.EditMessageTextParams{ChatID: testChatID, MessageID: first.ID, Text: "edited"})
+	m.AnswerCallbackQuery(context.Background(), &bot.AnswerCallbackQueryParams{CallbackQueryID: "query"})
+
+	if first.ID == second.ID {
+		t.Errorf("Expected distinct message IDs, got %d twice", first.ID)
+	}
+	assertTexts(t, m.texts(), []string{"one", "two", "edited"})
+	if len(m.calls) != 4 || m.calls[3].Method != "answerCallbackQuery" {
+		t.Errorf("Unexpected calls %+v", m.calls)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"log/slog"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "eventpoll"
+
+var (
+	updatesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "updates_total",
+		Help:      "Telegram updates received by update type and handler.",
+	}, []string{"type", "handler"})
+	handlerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "handler_duration_seconds",
+		Help:      "Time spent handling an update by handler.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"handler"})
+	votesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "votes_total",
+		Help:      "Poll votes by action.",
+	}, []string{"action"})
+	telegramAPIErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "telegram_api_errors_total",
+		Help:      "Failed Telegram Bot API requests by method and HTTP status code.",
+	}, []string{"method", "code"})
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Duration of database queries by operation.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation"})
+	lastUpdateTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "last_update_timestamp_seconds",
+		Help:      "Unix time the last Telegram update was received.",
+	})
+	lastBackupTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "last_backup_timestamp_seconds",
+		Help:      "Unix time of the last successful database backup.",
+	})
+
+	// lastUpdateReceived is the unix time in nanoseconds of the last update, 0 if none
+	lastUpdateReceived atomic.Int64
+)
+
+func init() {
+	prometheus.MustRegister(updatesTotal, handlerDuration, votesTotal, telegramAPIErrorsTotal, dbQueryDuration, lastUpdateTimestamp, lastBackupTimestamp)
+}
+
+// registerStateStoreMetrics exposes the number of in-progress wizard flows
+func registerStateStoreMetrics(store StateStore) {
+	prometheus.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "active_wizard_states",
+		Help:      "Users in the middle of a multi-step flow.",
+	}, func() float64 {
+		count, err := store.Count()
+		if err != nil {
+			slog.Error("error counting user states", "err", err)
+		}
+		return float64(count)
+	}))
+}
+
+// instrumentHandler counts the updates and measures the latency of a handler.
+// Log lines written while handling the update carry its IDs and the handler name
+func instrumentHandler(name string, next HandlerFunc) bot.HandlerFunc {
+	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+		ctx = withUpdateLogAttrs(ctx, name, update)
+		now := time.Now()
+		lastUpdateReceived.Store(now.UnixNano())
+		lastUpdateTimestamp.Set(float64(now.Unix()))
+		updatesTotal.WithLabelValues(getUpdateType(update), name).Inc()
+		defer func() {
+			handlerDuration.WithLabelValues(name).Observe(time.Since(now).Seconds())
+		}()
+		next(ctx, b, update)
+	}
+}
+
+func getUpdateType(update *models.Update) string {
+	switch {
+	case update.Message != nil:
+		return "message"
+	case update.CallbackQuery != nil:
+		return "callback_query"
+	case update.EditedMessage != nil:
+		return "edited_message"
+	case update.MyChatMember != nil:
+		return "my_chat_member"
+	default:
+		return "other"
+	}
+}
+
+// instrumentedHTTPClient counts failed Telegram Bot API requests
+type instrumentedHTTPClient struct {
+	client bot.HttpClient
+}
+
+func (c *instrumentedHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	method := path.Base(req.URL.Path)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// cancelled requests, e.g. long polling on shutdown, are no API errors
+		if req.Context().Err() == nil {
+			telegramAPIErrorsTotal.WithLabelValues(method, "network").Inc()
+		}
+		return resp, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		telegramAPIErrorsTotal.WithLabelValues(method, strconv.Itoa(resp.StatusCode)).Inc()
+	}
+	return resp, nil
+}
+
+// openInstrumentedDB opens a database whose queries are measured in dbQueryDuration
+func openInstrumentedDB(driverName, dataSourceName string) (*sql.DB, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	drv := db.Driver()
+	if err := db.Close(); err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(&instrumentedConnector{driver: drv, dsn: dataSourceName}), nil
+}
+
+type instrumentedConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func (c *instrumentedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedConn{Conn: conn}, nil
+}
+
+func (c *instrumentedConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// instrumentedConn measures queries and passes everything else to the wrapped connection
+type instrumentedConn struct {
+	driver.Conn
+}
+
+func observeQuery(query string, start time.Time) {
+	operation := "other"
+	if fields := strings.Fields(query); len(fields) > 0 {
+		operation = strings.ToLower(fields[0])
+	}
+	dbQueryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	defer observeQuery(query, time.Now())
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	defer observeQuery(query, time.Now())
+	return queryer.QueryContext(ctx, query, args)
+}
+
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+// CheckNamedValue lets the wrapped driver accept its own argument types, e.g. slices for PostgreSQL arrays
+func (c *instrumentedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (c *instrumentedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestInstrumentedDB(t *testing.T) {
+	db, err := openInstrumentedDB("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`CREATE TABLE t (id INTEGER PRIMARY KEY, created_at DATETIME)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin: %v", err)
+	}
+	if _, err := tx.Exec(`INSERT INTO t (id) VALUES (?)`, 1); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM t`).Scan(&count); err != nil || count != 1 {
+		t.Fatalf("Expected 1 row, got %d (%v)", count, err)
+	}
+	// one series per operation: create, insert and select
+	if got := testutil.CollectAndCount(dbQueryDuration); got < 3 {
+		t.Errorf("Expected query durations for create, insert and select, got %d series", got)
+	}
+}
+
+func TestHealthz(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := openInstrumentedDB("sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	handler := getHTTPHandler(db, nil, nil, nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	var status HealthStatus
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode health status: %v", err)
+	}
+	if rec.Code != http.StatusOK || status.Status != healthStatusOK {
+		t.Errorf("Expected healthy status, got %d %+v", rec.Code, status)
+	}
+
+	db.Close()
+	os.Remove(path)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected %d after closing the db, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+
+	if status.Postgres != "" {
+		t.Errorf("Expected no postgres status without a shared database, got %q", status.Postgres)
+	}
+
+	// any *sql.DB stands in for the shared database
+	local, err := openInstrumentedDB("sqlite", filepath.Join(t.TempDir(), "local.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer local.Close()
+	shared, err := openInstrumentedDB("sqlite", filepath.Join(t.TempDir(), "shared.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	sharedHandler := getHTTPHandler(local, shared, nil, nil)
+	rec = httptest.NewRecorder()
+	sharedHandler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	status = HealthStatus{}
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode health status: %v", err)
+	}
+	if rec.Code != http.StatusOK || status.Postgres != healthStatusOK {
+		t.Errorf("Expected the shared database to be healthy, got %d %+v", rec.Code, status)
+	}
+	shared.Close()
+	rec = httptest.NewRecorder()
+	sharedHandler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	status = HealthStatus{}
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode health status: %v", err)
+	}
+	if rec.Code != http.StatusServiceUnavailable || status.DB != healthStatusOK || status.Postgres == healthStatusOK {
+		t.Errorf("Expected %d after closing the shared database, got %d %+v", http.StatusServiceUnavailable, rec.Code, status)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected metrics to be served, got %d", rec.Code)
+	}
+}
+
+func TestInstrumentedHTTPClient(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"ok":false,"error_code":403,"description":"Forbidden: bot was blocked by the user"}`))
+	}))
+	defer api.Close()
+
+	client := &instrumentedHTTPClient{client: api.Client()}
+	req, _ := http.NewRequest(http.MethodPost, api.URL+"/bot123:token/sendMessage", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if got := testutil.ToFloat64(telegramAPIErrorsTotal.WithLabelValues("sendMessage", "403")); got != 1 {
+		t.Errorf("Expected 1 sendMessage error, got %v", got)
+	}
+}
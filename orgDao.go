@@ -0,0 +1,172 @@
+package main
+
+import (
+	"database/sql"
+	"strings"
+	"unicode"
+)
+
+// Org is the name of an organization, kept with each activity
+type Org string
+
+// OrgCC and OrgPEAK are the organizations created by the migration adding the orgs table
+const (
+	OrgCC   = "CC"
+	OrgPEAK = "PEAK"
+)
+
+// orgNameMaxLength keeps the name short enough for the callback data of the wizard buttons
+const orgNameMaxLength = 16
+
+// Organization is a committee organizing activities. Archived organizations are
+// kept for the existing activities but cannot be picked for new ones
+type Organization struct {
+	Name        Org
+	DisplayName string
+	Emoji       string // a colour or emoji shown before the display name
+	Active      bool
+}
+
+// Label is the emoji and display name of the organization
+func (o Organization) Label() string {
+	return strings.TrimSpace(o.Emoji + " " + o.DisplayName)
+}
+
+// parseOrgName returns the upper case name if it is a valid organization name
+func parseOrgName(input string) (Org, bool) {
+	name := strings.ToUpper(strings.TrimSpace(input))
+	if name == "" || len(name) > orgNameMaxLength {
+		return "", false
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' {
+			return "", false
+		}
+	}
+	return Org(name), true
+}
+
+// findOrg returns the organization whose name or display name matches the case-insensitive input
+func findOrg(orgs []Organization, input string) (*Organization, bool) {
+	input = strings.TrimSpace(input)
+	for i := range orgs {
+		if strings.EqualFold(string(orgs[i].Name), input) || strings.EqualFold(orgs[i].DisplayName, input) {
+			return &orgs[i], true
+		}
+	}
+	return nil, false
+}
+
+// activeOrgs returns the organizations which can be picked for new activities
+func activeOrgs(orgs []Organization) []Organization {
+	var active []Organization
+	for _, o := range orgs {
+		if o.Active {
+			active = append(active, o)
+		}
+	}
+	return active
+}
+
+// orgNames returns the names of the organizations, e.g. for error messages
+func orgNames(orgs []Organization) []Org {
+	names := make([]Org, 0, len(orgs))
+	for _, o := range orgs {
+		names = append(names, o.Name)
+	}
+	return names
+}
+
+// OrgDAO is the OrgRepository kept in SQLite
+type OrgDAO struct {
+	db *sql.DB
+}
+
+func NewOrgDAO(db *sql.DB) *OrgDAO {
+	return &OrgDAO{db: db}
+}
+
+func (dao *OrgDAO) ListOrgs() ([]Organization, error) {
+	rows, err := dao.db.Query(`SELECT name, display_name, emoji, active FROM orgs ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	return scanOrgs(rows)
+}
+
+func (dao *OrgDAO) GetOrg(name Org) (*Organization, error) {
+	var o Organization
+	err := dao.db.QueryRow(`SELECT name, display_name, emoji, active FROM orgs WHERE name = ?`, name).
+		Scan(&o.Name, &o.DisplayName, &o.Emoji, &o.Active)
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (dao *OrgDAO) SaveOrg(org *Organization) error {
+	_, err := dao.db.Exec(`INSERT INTO orgs (name, display_name, emoji, active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
+		org.Name, org.DisplayName, org.Emoji, org.Active)
+	return err
+}
+
+func (dao *OrgDAO) UpdateOrg(org *Organization) (int64, error) {
+	res, err := dao.db.Exec(`UPDATE orgs SET display_name = ?, emoji = ?, active = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE name = ?`,
+		org.DisplayName, org.Emoji, org.Active, org.Name)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GetOrgAdmins returns the user IDs of the admins of the organization
+func (dao *OrgDAO) GetOrgAdmins(name Org) ([]int64, error) {
+	return scanIDs(dao.db.Query(`SELECT user_id FROM org_admins WHERE org = ? ORDER BY user_id`, name))
+}
+
+// AddOrgAdmin makes the user an admin of the organization, if they are not already
+func (dao *OrgDAO) AddOrgAdmin(name Org, userID int64) error {
+	_, err := dao.db.Exec(`INSERT OR IGNORE INTO org_admins (org, user_id) VALUES (?, ?)`, name, userID)
+	return err
+}
+
+// RemoveOrgAdmin returns the number of removed admins
+func (dao *OrgDAO) RemoveOrgAdmin(name Org, userID int64) (int64, error) {
+	res, err := dao.db.Exec(`DELETE FROM org_admins WHERE org = ? AND user_id = ?`, name, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// scanIDs returns the user IDs of the rows
+func scanIDs(rows *sql.Rows, err error) ([]int64, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func scanOrgs(rows *sql.Rows) ([]Organization, error) {
+	defer rows.Close()
+	var orgs []Organization
+	for rows.Next() {
+		var o Organization
+		if err := rows.Scan(&o.Name, &o.DisplayName, &o.Emoji, &o.Active); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, o)
+	}
+	return orgs, rows.Err()
+}
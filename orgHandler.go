@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+const (
+	orgCommandAdd     = "add"
+	orgCommandRename  = "rename"
+	orgCommandArchive = "archive"
+	orgCommandRestore = "restore"
+	// the admins of an organization can delete and restore all of its activities
+	orgCommandAddAdmin    = "addadmin"
+	orgCommandRemoveAdmin = "removeadmin"
+)
+
+// OrgHandler handles the /org admin command managing the organizations of activities
+type OrgHandler struct {
+	orgs         OrgRepository
+	config       *Config
+	chatSettings ChatSettingsRepository
+}
+
+func NewOrgHandler(orgs OrgRepository, config *Config, chatSettings ChatSettingsRepository) *OrgHandler {
+	return &OrgHandler{orgs: orgs, config: config, chatSettings: chatSettings}
+}
+
+// handleOrg lists the organizations or changes one of them, e.g. /org add PEAK Peak Outdoors 🏔
+func (h *OrgHandler) handleOrg(ctx context.Context, b Messenger, update *models.Update) {
+	chatID := update.Message.Chat.ID
+	msgThreadID := update.Message.MessageThreadID
+	l := h.chatSettings.GetLocalizer(ctx, chatID)
+	reply := func(key MessageKey, args ...any) {
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:          chatID,
+			MessageThreadID: msgThreadID,
+			Text:            l.T(key, args...),
+		})
+	}
+
+	if !h.config.isAdmin(update.Message.From) {
+		slog.WarnContext(ctx, "org command by non-admin")
+		reply(msgNotAdmin)
+		return
+	}
+
+	args := getCommandArguments(update)
+	if len(args) == 0 {
+		h.sendOrgs(ctx, b, chatID, msgThreadID, l)
+		return
+	}
+	if len(args) < 2 {
+		reply(msgOrgUsage)
+		return
+	}
+	name, ok := parseOrgName(args[1])
+	if !ok {
+		reply(msgOrgInvalidName, args[1])
+		return
+	}
+	displayName, emoji := parseOrgDisplayName(args[2:])
+
+	switch args[0] {
+	case orgCommandAddAdmin, orgCommandRemoveAdmin:
+		if len(args) != 3 {
+			reply(msgOrgUsage)
+			return
+		}
+		userID, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil || userID <= 0 {
+			reply(msgOrgInvalidUserID, args[2])
+			return
+		}
+		h.updateOrgAdmin(ctx, name, userID, args[0] == orgCommandAddAdmin, reply)
+	case orgCommandAdd:
+		if displayName == "" {
+			displayName = string(name)
+		}
+		if _, err := h.orgs.GetOrg(name); err == nil {
+			reply(msgOrgExists, name)
+			return
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			slog.ErrorContext(ctx, "error getting org", "org", name, "err", err)
+			reply(msgOrgSaveFailed)
+			return
+		}
+		org := &Organization{Name: name, DisplayName: displayName, Emoji: emoji, Active: true}
+		if err := h.orgs.SaveOrg(org); err != nil {
+			slog.ErrorContext(ctx, "error saving org", "org", name, "err", err)
+			reply(msgOrgSaveFailed)
+			return
+		}
+		slog.InfoContext(ctx, "org added", "org", name)
+		reply(msgOrgAdded, org.Label())
+	case orgCommandRename:
+		if displayName == "" {
+			reply(msgOrgUsage)
+			return
+		}
+		h.updateOrg(ctx, name, reply, func(org *Organization) {
+			org.DisplayName = displayName
+			if emoji != "" {
+				org.Emoji = emoji
+			}
+		}, msgOrgRenamed)
+	case orgCommandArchive:
+		h.updateOrg(ctx, name, reply, func(org *Organization) { org.Active = false }, msgOrgArchived)
+	case orgCommandRestore:
+		h.updateOrg(ctx, name, reply, func(org *Organization) { org.Active = true }, msgOrgRestored)
+	default:
+		reply(msgOrgUsage)
+	}
+}
+
+// updateOrg applies the change to the organization and confirms it with the message
+func (h *OrgHandler) updateOrg(ctx context.Context, name Org, reply func(MessageKey, ...any), change func(*Organization), done MessageKey) {
+	org, err := h.orgs.GetOrg(name)
+	if errors.Is(err, sql.ErrNoRows) {
+		reply(msgOrgNotFound, name)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "error getting org", "org", name, "err", err)
+		reply(msgOrgSaveFailed)
+		return
+	}
+	change(org)
+	if _, err := h.orgs.UpdateOrg(org); err != nil {
+		slog.ErrorContext(ctx, "error updating org", "org", name, "err", err)
+		reply(msgOrgSaveFailed)
+		return
+	}
+	slog.InfoContext(ctx, "org updated", "org", name, "active", org.Active)
+	reply(done, org.Label())
+}
+
+// updateOrgAdmin adds or removes the admin of the organization
+func (h *OrgHandler) updateOrgAdmin(ctx context.Context, name Org, userID int64, add bool, reply func(MessageKey, ...any)) {
+	org, err := h.orgs.GetOrg(name)
+	if errors.Is(err, sql.ErrNoRows) {
+		reply(msgOrgNotFound, name)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "error getting org", "org", name, "err", err)
+		reply(msgOrgSaveFailed)
+		return
+	}
+	if add {
+		err = h.orgs.AddOrgAdmin(name, userID)
+	} else {
+		var n int64
+		n, err = h.orgs.RemoveOrgAdmin(name, userID)
+		if err == nil && n == 0 {
+			reply(msgOrgAdminNotFound, userID, org.Label())
+			return
+		}
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "error updating org admin", "org", name, "user_id", userID, "err", err)
+		reply(msgOrgSaveFailed)
+		return
+	}
+	slog.InfoContext(ctx, "org admin updated", "org", name, "user_id", userID, "admin", add)
+	if add {
+		reply(msgOrgAdminAdded, userID, org.Label())
+	} else {
+		reply(msgOrgAdminRemoved, userID, org.Label())
+	}
+}
+
+func (h *OrgHandler) sendOrgs(ctx context.Context, b Messenger, chatID int64, msgThreadID int, l *Localizer) {
+	orgs, err := h.orgs.ListOrgs()
+	text := l.T(msgNoOrgs)
+	if err != nil {
+		slog.ErrorContext(ctx, "error listing orgs", "err", err)
+		text = l.T(msgOrgGetFailed)
+	} else if len(orgs) > 0 {
+		lines := make([]string, 0, len(orgs)+1)
+		for _, org := range orgs {
+			line := l.T(msgOrgLine, org.Name, org.Label())
+			if !org.Active {
+				line += l.T(msgOrgArchivedTag)
+			}
+			if admins, err := h.orgs.GetOrgAdmins(org.Name); err != nil {
+				slog.ErrorContext(ctx, "error getting org admins", "org", org.Name, "err", err)
+			} else if len(admins) > 0 {
+				line += l.T(msgOrgAdminsTag, joinIDs(admins))
+			}
+			lines = append(lines, line)
+		}
+		text = strings.Join(append(lines, "", l.T(msgOrgUsage)), "\n")
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          chatID,
+		MessageThreadID: msgThreadID,
+		Text:            text,
+	})
+}
+
+// parseOrgDisplayName joins the words of the display name. The last word is
+// taken as the emoji if it has neither letters nor digits
+func parseOrgDisplayName(words []string) (displayName, emoji string) {
+	if n := len(words); n > 0 && !strings.ContainsFunc(words[n-1], func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}) {
+		emoji, words = words[n-1], words[:n-1]
+	}
+	return strings.Join(words, " "), emoji
+}
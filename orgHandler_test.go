@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOrgHandler(t *testing.T) {
+	en := NewLocalizer(langEnglish, time.UTC)
+	tb := newTestBot(t)
+	AppConfig.Admins = []int64{testAlice.ID}
+	h := NewOrgHandler(tb.orgDAO, AppConfig, tb.chatSettings)
+	org := func(*testBot) HandlerFunc { return h.handleOrg }
+
+	steps := []testStep{
+		{org, textUpdate(testBob, "/org add HIKE Hiking club")},
+		{org, textUpdate(testAlice, "/org add hike Hiking club 🥾")},
+		{org, textUpdate(testAlice, "/org add HIKE Again")},
+		{org, textUpdate(testAlice, "/org add no/pe Nope")},
+		{org, textUpdate(testAlice, "/org rename hike Hikers")},
+		{org, textUpdate(testAlice, "/org archive PEAK")},
+		{org, textUpdate(testAlice, "/org archive NOPE")},
+		{org, textUpdate(testAlice, "/org")},
+		{org, textUpdate(testAlice, "/org restore peak")},
+		{org, textUpdate(testAlice, "/org delete CC")},
+		{org, textUpdate(testAlice, "/org addadmin peak 2")},
+		{org, textUpdate(testAlice, "/org addadmin PEAK bob")},
+		{org, textUpdate(testAlice, "/org addadmin NOPE 2")},
+		{org, textUpdate(testAlice, "/org")},
+		{org, textUpdate(testAlice, "/org removeadmin PEAK 2")},
+		{org, textUpdate(testAlice, "/org removeadmin PEAK 2")},
+	}
+	texts := tb.run(t, steps)
+	assertTexts(t, texts, []string{
+		en.T(msgNotAdmin),
+		en.T(msgOrgAdded, "🥾 Hiking club"),
+		en.T(msgOrgExists, "HIKE"),
+		en.T(msgOrgInvalidName, "no/pe"),
+		en.T(msgOrgRenamed, "🥾 Hikers"),
+		en.T(msgOrgArchived, "PEAK"),
+		en.T(msgOrgNotFound, "NOPE"),
+		"CC - CC\nHIKE - 🥾 Hikers\nPEAK - PEAK" + en.T(msgOrgArchivedTag),
+		en.T(msgOrgRestored, "PEAK"),
+		en.T(msgOrgUsage),
+		en.T(msgOrgAdminAdded, 2, "PEAK"),
+		en.T(msgOrgInvalidUserID, "bob"),
+		en.T(msgOrgNotFound, "NOPE"),
+		"PEAK - PEAK" + en.T(msgOrgAdminsTag, "2"),
+		en.T(msgOrgAdminRemoved, 2, "PEAK"),
+		en.T(msgOrgAdminNotFound, 2, "PEAK"),
+	})
+
+	orgs, err := tb.orgDAO.ListOrgs()
+	if err != nil {
+		t.Fatalf("ListOrgs failed: %v", err)
+	}
+	if names := orgNames(activeOrgs(orgs)); len(names) != 3 {
+		t.Errorf("Expected all orgs to be active, got %v", names)
+	}
+}
+
+func TestParseOrgDisplayName(t *testing.T) {
+	tests := []struct {
+		words       []string
+		displayName string
+		emoji       string
+	}{
+		{nil, "", ""},
+		{[]string{"Peak", "Outdoors"}, "Peak Outdoors", ""},
+		{[]string{"Peak", "Outdoors", "🏔"}, "Peak Outdoors", "🏔"},
+		{[]string{"🟢"}, "", "🟢"},
+		{[]string{"Club", "42"}, "Club 42", ""},
+	}
+	for _, tt := range tests {
+		displayName, emoji := parseOrgDisplayName(tt.words)
+		if displayName != tt.displayName || emoji != tt.emoji {
+			t.Errorf("parseOrgDisplayName(%q) = %q, %q, want %q, %q", tt.words, displayName, emoji, tt.displayName, tt.emoji)
+		}
+	}
+}
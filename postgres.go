@@ -0,0 +1,812 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// postgresMigrationLock is the advisory lock key which keeps replicas
+// starting at the same time from migrating concurrently
+const postgresMigrationLock = 7_020_040
+
+// postgresMigrations build the shared schema, which has the same tables and
+// columns as the SQLite schema for events and activities
+var postgresMigrations = []Migration{
+	{
+		Version:     1,
+		Description: "create events, event_users and activities",
+		Queries: []string{
+			`CREATE TABLE IF NOT EXISTS events (
+				id BIGSERIAL PRIMARY KEY,
+				description TEXT,
+				options TEXT,
+				chat_id BIGINT,
+				message_id BIGINT,
+				created_by TEXT,
+				created_by_id BIGINT DEFAULT 0,
+				started_at TIMESTAMPTZ,
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE TABLE IF NOT EXISTS event_users (
+				event_id BIGINT REFERENCES events(id),
+				"user" TEXT,
+				option TEXT,
+				user_id BIGINT DEFAULT 0,
+				deleted BOOLEAN DEFAULT FALSE
+			)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_event_users ON event_users (event_id, user_id, "user", option)`,
+			`CREATE TABLE IF NOT EXISTS activities (
+				id BIGSERIAL PRIMARY KEY,
+				name TEXT NOT NULL,
+				org TEXT NOT NULL,
+				lead TEXT NOT NULL,
+				co_leads TEXT,
+				started_at TIMESTAMPTZ NOT NULL,
+				created_by TEXT DEFAULT 'S',
+				created_by_id BIGINT DEFAULT 0,
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+			)`,
+		},
+	},
+	{
+		Version:     2,
+		Description: "create orgs",
+		Queries: []string{
+			`CREATE TABLE IF NOT EXISTS orgs (
+				name TEXT PRIMARY KEY,
+				display_name TEXT NOT NULL,
+				emoji TEXT NOT NULL DEFAULT '',
+				active BOOLEAN NOT NULL DEFAULT TRUE,
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`INSERT INTO orgs (name, display_name) VALUES ('CC', 'CC'), ('PEAK', 'PEAK') ON CONFLICT DO NOTHING`,
+			`INSERT INTO orgs (name, display_name) SELECT DISTINCT org, org FROM activities ON CONFLICT DO NOTHING`,
+		},
+	},
+	{
+		Version:     3,
+		Description: "add activities.ends_at, location, description and status",
+		Queries: []string{
+			`ALTER TABLE activities ADD COLUMN IF NOT EXISTS ends_at TIMESTAMPTZ`,
+			`ALTER TABLE activities ADD COLUMN IF NOT EXISTS location TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE activities ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE activities ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'planned'`,
+		},
+	},
+	{
+		Version:     4,
+		Description: "add events.activity_id",
+		Queries: []string{
+			`ALTER TABLE events ADD COLUMN IF NOT EXISTS activity_id BIGINT REFERENCES activities(id) ON DELETE SET NULL`,
+			`CREATE INDEX IF NOT EXISTS idx_events_activity_id ON events (activity_id)`,
+		},
+	},
+	{
+		Version:     5,
+		Description: "add activities.lead_id, co_lead_ids and reminded_at",
+		Queries: []string{
+			`ALTER TABLE activities ADD COLUMN IF NOT EXISTS lead_id BIGINT NOT NULL DEFAULT 0`,
+			`ALTER TABLE activities ADD COLUMN IF NOT EXISTS co_lead_ids TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE activities ADD COLUMN IF NOT EXISTS reminded_at TIMESTAMPTZ`,
+		},
+	},
+	{
+		Version:     6,
+		Description: "create activity_leads and index activities by org",
+		Queries: []string{
+			`CREATE TABLE IF NOT EXISTS activity_leads (
+				activity_id BIGINT NOT NULL REFERENCES activities (id) ON DELETE CASCADE,
+				position INTEGER NOT NULL,
+				name TEXT NOT NULL,
+				user_id BIGINT NOT NULL DEFAULT 0,
+				PRIMARY KEY (activity_id, position)
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_activity_leads_name ON activity_leads (lower(name))`,
+			`CREATE INDEX IF NOT EXISTS idx_activity_leads_user_id ON activity_leads (user_id)`,
+			`CREATE INDEX IF NOT EXISTS idx_activities_org ON activities (org, started_at)`,
+			`INSERT INTO activity_leads (activity_id, position, name, user_id)
+				SELECT id, 0, lead, lead_id FROM activities
+				UNION ALL
+				SELECT a.id, c.n, c.name, COALESCE(NULLIF(split_part(a.co_lead_ids, ',', c.n::int), '')::BIGINT, 0)
+				FROM activities a, unnest(string_to_array(a.co_leads, ',')) WITH ORDINALITY AS c(name, n)
+				WHERE a.co_leads <> ''
+			ON CONFLICT DO NOTHING`,
+		},
+	},
+	{
+		Version:     7,
+		Description: "add activities.deleted_at",
+		Queries: []string{
+			`ALTER TABLE activities ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ`,
+			`CREATE INDEX IF NOT EXISTS idx_activities_deleted_at ON activities (deleted_at)`,
+		},
+	},
+	{
+		Version:     8,
+		Description: "create org_admins",
+		Queries: []string{
+			`CREATE TABLE IF NOT EXISTS org_admins (
+				org TEXT NOT NULL,
+				user_id BIGINT NOT NULL,
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (org, user_id)
+			)`,
+		},
+	},
+	{
+		Version:     9,
+		Description: "create user_states, chat_settings and known_users",
+		Queries: []string{
+			`CREATE TABLE IF NOT EXISTS user_states (
+				key TEXT PRIMARY KEY,
+				state TEXT NOT NULL,
+				expires_at BIGINT NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_user_states_expires_at ON user_states (expires_at)`,
+			`CREATE TABLE IF NOT EXISTS chat_settings (
+				chat_id BIGINT PRIMARY KEY,
+				timezone TEXT,
+				language TEXT,
+				updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE TABLE IF NOT EXISTS known_users (
+				id BIGINT PRIMARY KEY,
+				username TEXT NOT NULL DEFAULT '',
+				full_name TEXT NOT NULL DEFAULT '',
+				updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_known_users_username ON known_users (lower(username))`,
+		},
+	},
+}
+
+// PlanPostgresMigrations returns the migrations the shared database is missing without changing it
+func PlanPostgresMigrations(q dbQuerier) ([]Migration, error) {
+	var tableCount int
+	err := q.QueryRow(`SELECT COUNT(*) FROM information_schema.tables
+		WHERE table_schema = current_schema() AND table_name = 'schema_migrations'`).Scan(&tableCount)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int]string)
+	if tableCount > 0 {
+		if applied, err = appliedMigrations(q); err != nil {
+			return nil, err
+		}
+	}
+	return pendingMigrations(postgresMigrations, applied, nil)
+}
+
+// MigratePostgres applies the pending migrations of the shared database in a single transaction
+func MigratePostgres(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, postgresMigrationLock); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return err
+	}
+	pending, err := PlanPostgresMigrations(tx)
+	if err != nil {
+		return err
+	}
+	// no data was ever stored in local time in PostgreSQL
+	recordQuery := `INSERT INTO schema_migrations (version, description, checksum) VALUES ($1, $2, $3)`
+	if err := applyMigrations(tx, pending, recordQuery, time.UTC); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// PostgresEventDAO is the EventRepository kept in PostgreSQL
+type PostgresEventDAO struct {
+	db *sql.DB
+}
+
+func NewPostgresEventDAO(db *sql.DB) *PostgresEventDAO {
+	return &PostgresEventDAO{db: db}
+}
+
+func (dao *PostgresEventDAO) GetEventByID(eventID int64) (*Event, error) {
+	row := dao.db.QueryRow(`SELECT `+eventColumns+` FROM events WHERE id = $1`, eventID)
+	return scanEvent(row)
+}
+
+func (dao *PostgresEventDAO) GetEventByMessageID(messageID int) (*Event, error) {
+	row := dao.db.QueryRow(`SELECT `+eventColumns+` FROM events WHERE message_id = $1`, messageID)
+	return scanEvent(row)
+}
+
+func (dao *PostgresEventDAO) ListEvents(limit int) ([]*Event, error) {
+	rows, err := dao.db.Query(`SELECT `+eventColumns+` FROM events ORDER BY id DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanEvents(rows)
+}
+
+func (dao *PostgresEventDAO) SaveEvent(event *Event) (int64, error) {
+	query := `INSERT INTO events (
+		description, options, chat_id, message_id, created_by, created_by_id,
+		started_at, activity_id, created_at, updated_at
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP) RETURNING id`
+	var id int64
+	err := dao.db.QueryRow(query,
+		event.Description,
+		strings.Join(event.Options, ";"),
+		event.ChatID,
+		event.MessageID,
+		event.CreatedBy,
+		event.CreatedByID,
+		event.StartedAt,
+		nullID(event.ActivityID),
+	).Scan(&id)
+	return id, err
+}
+
+func (dao *PostgresEventDAO) UpdateEvent(event *Event) error {
+	query := `UPDATE events
+		SET description = $1, options = $2, chat_id = $3, message_id = $4, created_by = $5, created_by_id = $6,
+		started_at = $7, activity_id = $8, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $9`
+	_, err := dao.db.Exec(query,
+		event.Description,
+		strings.Join(event.Options, ";"),
+		event.ChatID,
+		event.MessageID,
+		event.CreatedBy,
+		event.CreatedByID,
+		event.StartedAt,
+		nullID(event.ActivityID),
+		event.ID,
+	)
+	return err
+}
+
+func (dao *PostgresEventDAO) DeleteEvent(eventID int64) (int64, error) {
+	tx, err := dao.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM event_users WHERE event_id = $1`, eventID); err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec(`DELETE FROM events WHERE id = $1`, eventID)
+	if err != nil {
+		return 0, err
+	}
+	affectedRows, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return affectedRows, tx.Commit()
+}
+
+func (dao *PostgresEventDAO) GetEventByActivityID(activityID int64) (*Event, error) {
+	row := dao.db.QueryRow(`SELECT `+eventColumns+` FROM events WHERE activity_id = $1 ORDER BY id DESC LIMIT 1`, activityID)
+	return scanEvent(row)
+}
+
+func (dao *PostgresEventDAO) GetHeadcounts(activityIDs []int64) (map[int64]int, error) {
+	rows, err := dao.db.Query(`SELECT e.activity_id, COUNT(DISTINCT eu."user")
+		FROM events e
+		LEFT JOIN event_users eu ON eu.event_id = e.id AND NOT eu.deleted
+		WHERE e.activity_id = ANY($1)
+		GROUP BY e.activity_id`, activityIDs)
+	if err != nil {
+		return nil, err
+	}
+	return scanHeadcounts(rows, make(map[int64]int))
+}
+
+func (dao *PostgresEventDAO) GetEventUsers(eventID int64) ([]EventUser, error) {
+	rows, err := dao.db.Query(`SELECT event_id, "user", option, user_id FROM event_users WHERE event_id = $1 AND NOT deleted`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	return scanEventUsers(rows)
+}
+
+func (dao *PostgresEventDAO) SaveEventUser(eventUser *EventUser) error {
+	query := `INSERT INTO event_users (event_id, "user", option, user_id) VALUES ($1, $2, $3, $4)`
+	_, err := dao.db.Exec(query, eventUser.EventID, eventUser.User, eventUser.Option, eventUser.UserID)
+	return err
+}
+
+func (dao *PostgresEventDAO) ToggleEventUser(eventUser *EventUser) error {
+	query := `INSERT INTO event_users (event_id, "user", option, user_id) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (event_id, user_id, "user", option) DO UPDATE SET deleted = NOT event_users.deleted, user_id = excluded.user_id`
+	_, err := dao.db.Exec(query, eventUser.EventID, eventUser.User, eventUser.Option, eventUser.UserID)
+	return err
+}
+
+func (dao *PostgresEventDAO) DeleteEventUser(eventUser *EventUser) (int64, error) {
+	query := `DELETE FROM event_users WHERE event_id = $1 AND option = $2 AND ((user_id = $3) OR ("user" = $4 AND user_id = 0))`
+	result, err := dao.db.Exec(query, eventUser.EventID, eventUser.Option, eventUser.UserID, eventUser.User)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (dao *PostgresEventDAO) GetEventsVotedByUser(user string, userID int64) ([]*Event, []EventUser, error) {
+	rows, err := dao.db.Query(`SELECT event_id, "user", option, user_id FROM event_users
+		WHERE ((user_id = $1 AND user_id != 0) OR ("user" = $2 AND user_id = 0)) AND NOT deleted`, userID, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	eventUsers, err := scanEventUsers(rows)
+	if err != nil || len(eventUsers) == 0 {
+		return nil, eventUsers, err
+	}
+	eventIDs := make([]int64, 0, len(eventUsers))
+	for _, eu := range eventUsers {
+		eventIDs = append(eventIDs, eu.EventID)
+	}
+	rows, err = dao.db.Query(`SELECT `+eventColumns+` FROM events WHERE id = ANY($1)`, eventIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	events, err := scanEvents(rows)
+	return events, eventUsers, err
+}
+
+// PostgresActivityDAO is the ActivityRepository kept in PostgreSQL
+type PostgresActivityDAO struct {
+	db *sql.DB
+}
+
+func NewPostgresActivityDAO(db *sql.DB) *PostgresActivityDAO {
+	return &PostgresActivityDAO{db: db}
+}
+
+func (dao *PostgresActivityDAO) Save(activity *Activity) (int64, error) {
+	tx, err := dao.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := savePostgresActivity(tx, activity); err != nil {
+		return 0, err
+	}
+	return activity.ID, tx.Commit()
+}
+
+func (dao *PostgresActivityDAO) SaveAll(activities []Activity) error {
+	tx, err := dao.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i := range activities {
+		if _, err := savePostgresActivity(tx, &activities[i]); err != nil {
+			return fmt.Errorf("activity %q: %w", activities[i].Name, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func savePostgresActivity(q dbQuerier, activity *Activity) (int64, error) {
+	query := `INSERT INTO activities (name, org, lead, co_leads, started_at, ends_at, location, description, status,
+			lead_id, co_lead_ids, created_by, created_by_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP) RETURNING id`
+	activity.Status = activity.status()
+	err := q.QueryRow(query,
+		activity.Name,
+		activity.Org,
+		activity.Lead,
+		strings.Join(activity.CoLeads, ","),
+		activity.StartedAt.UTC(),
+		nullTime(activity.EndsAt),
+		activity.Location,
+		activity.Description,
+		activity.Status,
+		activity.LeadID,
+		joinIDs(activity.CoLeadIDs),
+		activity.CreatedBy,
+		activity.CreatedByID,
+	).Scan(&activity.ID)
+	if err != nil {
+		return 0, err
+	}
+	return activity.ID, savePostgresActivityLeads(q, activity)
+}
+
+// savePostgresActivityLeads replaces the rows of activity_leads of the activity
+func savePostgresActivityLeads(q dbQuerier, activity *Activity) error {
+	if _, err := q.Exec(`DELETE FROM activity_leads WHERE activity_id = $1`, activity.ID); err != nil {
+		return err
+	}
+	for i, lead := range activity.leadNames() {
+		_, err := q.Exec(`INSERT INTO activity_leads (activity_id, position, name, user_id) VALUES ($1, $2, $3, $4)`,
+			activity.ID, i, lead.Name, lead.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (dao *PostgresActivityDAO) GetByID(id int64) (*Activity, error) {
+	rows, err := dao.db.Query(`SELECT `+activityColumns+` FROM activities WHERE id = $1 AND deleted_at IS NULL`, id)
+	if err != nil {
+		return nil, err
+	}
+	activities, err := scanActivities(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(activities) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &activities[0], nil
+}
+
+func (dao *PostgresActivityDAO) GetByDuration(startTime, endTime time.Time) ([]Activity, error) {
+	rows, err := dao.db.Query(`SELECT `+activityColumns+` FROM activities
+		WHERE started_at BETWEEN $1 AND $2 AND deleted_at IS NULL
+		ORDER BY started_at ASC`, startTime.UTC(), endTime.UTC())
+	if err != nil {
+		return nil, err
+	}
+	return scanActivities(rows)
+}
+
+func (dao *PostgresActivityDAO) GetOverlapping(startTime, endTime time.Time) ([]Activity, error) {
+	rows, err := dao.db.Query(`SELECT `+activityColumns+` FROM activities
+		WHERE deleted_at IS NULL AND status <> $1 AND started_at < $2 AND (ends_at > $3 OR (ends_at IS NULL AND started_at > $4))
+		ORDER BY started_at ASC`, ActivityCancelled, endTime.UTC(), startTime.UTC(), startTime.Add(-activityDefaultDuration).UTC())
+	if err != nil {
+		return nil, err
+	}
+	return scanActivities(rows)
+}
+
+func (dao *PostgresActivityDAO) Search(filter ActivityFilter, limit int) ([]Activity, error) {
+	query, args := searchActivitiesQuery(filter, limit, true)
+	rows, err := dao.db.Query(numberPlaceholders(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	activities, err := scanActivities(rows)
+	slices.Reverse(activities)
+	return activities, err
+}
+
+// numberPlaceholders replaces the ? placeholders with the numbered ones of PostgreSQL
+func numberPlaceholders(query string) string {
+	var sb strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			fmt.Fprintf(&sb, "$%d", n)
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func (dao *PostgresActivityDAO) GetAll() ([]Activity, error) {
+	rows, err := dao.db.Query(`SELECT ` + activityColumns + ` FROM activities WHERE deleted_at IS NULL ORDER BY started_at ASC`)
+	if err != nil {
+		return nil, err
+	}
+	return scanActivities(rows)
+}
+
+func (dao *PostgresActivityDAO) Update(activity *Activity) error {
+	query := `UPDATE activities
+		SET name = $1, org = $2, lead = $3, co_leads = $4, started_at = $5, ends_at = $6, location = $7, description = $8, status = $9,
+			lead_id = $10, co_lead_ids = $11, created_by = $12, created_by_id = $13, updated_at = CURRENT_TIMESTAMP,
+			reminded_at = CASE WHEN started_at = $5 THEN reminded_at ELSE NULL END
+		WHERE id = $14 AND deleted_at IS NULL`
+	activity.Status = activity.status()
+	tx, err := dao.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(query,
+		activity.Name,
+		activity.Org,
+		activity.Lead,
+		strings.Join(activity.CoLeads, ","),
+		activity.StartedAt.UTC(),
+		nullTime(activity.EndsAt),
+		activity.Location,
+		activity.Description,
+		activity.Status,
+		activity.LeadID,
+		joinIDs(activity.CoLeadIDs),
+		activity.CreatedBy,
+		activity.CreatedByID,
+		activity.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if err := savePostgresActivityLeads(tx, activity); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (dao *PostgresActivityDAO) MarkReminded(id int64, at time.Time) (int64, error) {
+	res, err := dao.db.Exec(`UPDATE activities SET reminded_at = $1 WHERE id = $2 AND reminded_at IS NULL`, at.UTC(), id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (dao *PostgresActivityDAO) Delete(id int64) (int64, error) {
+	res, err := dao.db.Exec(`UPDATE activities SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`, time.Now().UTC(), id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (dao *PostgresActivityDAO) GetDeleted(since time.Time) ([]Activity, error) {
+	rows, err := dao.db.Query(`SELECT `+activityColumns+` FROM activities
+		WHERE deleted_at >= $1
+		ORDER BY deleted_at DESC`, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	return scanActivities(rows)
+}
+
+func (dao *PostgresActivityDAO) GetDeletedByID(id int64, since time.Time) (*Activity, error) {
+	rows, err := dao.db.Query(`SELECT `+activityColumns+` FROM activities WHERE id = $1 AND deleted_at >= $2`, id, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	activities, err := scanActivities(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(activities) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &activities[0], nil
+}
+
+func (dao *PostgresActivityDAO) Restore(id int64, since time.Time) (int64, error) {
+	res, err := dao.db.Exec(`UPDATE activities SET deleted_at = NULL WHERE id = $1 AND deleted_at >= $2`, id, since.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Purge relies on the foreign keys to unlink the polls and remove the leads of the purged activities
+func (dao *PostgresActivityDAO) Purge(before time.Time) (int64, error) {
+	res, err := dao.db.Exec(`DELETE FROM activities WHERE deleted_at < $1`, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// PostgresOrgDAO is the OrgRepository kept in PostgreSQL
+type PostgresOrgDAO struct {
+	db *sql.DB
+}
+
+func NewPostgresOrgDAO(db *sql.DB) *PostgresOrgDAO {
+	return &PostgresOrgDAO{db: db}
+}
+
+func (dao *PostgresOrgDAO) ListOrgs() ([]Organization, error) {
+	rows, err := dao.db.Query(`SELECT name, display_name, emoji, active FROM orgs ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	return scanOrgs(rows)
+}
+
+func (dao *PostgresOrgDAO) GetOrg(name Org) (*Organization, error) {
+	var o Organization
+	err := dao.db.QueryRow(`SELECT name, display_name, emoji, active FROM orgs WHERE name = $1`, name).
+		Scan(&o.Name, &o.DisplayName, &o.Emoji, &o.Active)
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (dao *PostgresOrgDAO) SaveOrg(org *Organization) error {
+	_, err := dao.db.Exec(`INSERT INTO orgs (name, display_name, emoji, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`,
+		org.Name, org.DisplayName, org.Emoji, org.Active)
+	return err
+}
+
+func (dao *PostgresOrgDAO) UpdateOrg(org *Organization) (int64, error) {
+	res, err := dao.db.Exec(`UPDATE orgs SET display_name = $1, emoji = $2, active = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE name = $4`,
+		org.DisplayName, org.Emoji, org.Active, org.Name)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (dao *PostgresOrgDAO) GetOrgAdmins(name Org) ([]int64, error) {
+	return scanIDs(dao.db.Query(`SELECT user_id FROM org_admins WHERE org = $1 ORDER BY user_id`, name))
+}
+
+func (dao *PostgresOrgDAO) AddOrgAdmin(name Org, userID int64) error {
+	_, err := dao.db.Exec(`INSERT INTO org_admins (org, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, name, userID)
+	return err
+}
+
+func (dao *PostgresOrgDAO) RemoveOrgAdmin(name Org, userID int64) (int64, error) {
+	res, err := dao.db.Exec(`DELETE FROM org_admins WHERE org = $1 AND user_id = $2`, name, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// PostgresChatSettingsDAO is the ChatSettingsRepository kept in PostgreSQL
+type PostgresChatSettingsDAO struct {
+	db *sql.DB
+}
+
+func NewPostgresChatSettingsDAO(db *sql.DB) *PostgresChatSettingsDAO {
+	return &PostgresChatSettingsDAO{db: db}
+}
+
+func (dao *PostgresChatSettingsDAO) GetLocalizer(ctx context.Context, chatID int64) *Localizer {
+	var tzName, language sql.NullString
+	err := dao.db.QueryRowContext(ctx, `SELECT timezone, language FROM chat_settings WHERE chat_id = $1`, chatID).Scan(&tzName, &language)
+	if err != nil && err != sql.ErrNoRows {
+		slog.ErrorContext(ctx, "error getting chat settings", "settings_chat_id", chatID, "err", err)
+	}
+	return chatLocalizer(ctx, chatID, tzName, language)
+}
+
+func (dao *PostgresChatSettingsDAO) SetTimezone(chatID int64, tz *time.Location) error {
+	_, err := dao.db.Exec(`INSERT INTO chat_settings (chat_id, timezone, updated_at) VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (chat_id) DO UPDATE SET timezone = excluded.timezone, updated_at = CURRENT_TIMESTAMP`, chatID, tz.String())
+	return err
+}
+
+func (dao *PostgresChatSettingsDAO) SetLanguage(chatID int64, language string) error {
+	_, err := dao.db.Exec(`INSERT INTO chat_settings (chat_id, language, updated_at) VALUES ($1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (chat_id) DO UPDATE SET language = excluded.language, updated_at = CURRENT_TIMESTAMP`, chatID, language)
+	return err
+}
+
+// PostgresKnownUserDAO is the KnownUserRepository kept in PostgreSQL
+type PostgresKnownUserDAO struct {
+	db *sql.DB
+}
+
+func NewPostgresKnownUserDAO(db *sql.DB) *PostgresKnownUserDAO {
+	return &PostgresKnownUserDAO{db: db}
+}
+
+func (dao *PostgresKnownUserDAO) Remember(user *models.User) error {
+	_, err := dao.db.Exec(`INSERT INTO known_users (id, username, full_name, updated_at) VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		ON CONFLICT (id) DO UPDATE SET username = excluded.username, full_name = excluded.full_name, updated_at = CURRENT_TIMESTAMP`,
+		user.ID, user.Username, getUserFullName(user))
+	return err
+}
+
+func (dao *PostgresKnownUserDAO) GetByUsername(username string) (*KnownUser, error) {
+	user := &KnownUser{}
+	err := dao.db.QueryRow(`SELECT id, username, full_name FROM known_users WHERE lower(username) = lower($1)
+		ORDER BY updated_at DESC LIMIT 1`, strings.TrimPrefix(username, "@")).Scan(&user.ID, &user.Username, &user.FullName)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// PostgresStateStore keeps the states in PostgreSQL, so that a flow started
+// on one replica can be finished on another
+type PostgresStateStore struct {
+	db  *sql.DB
+	ttl time.Duration
+}
+
+func NewPostgresStateStore(db *sql.DB, ttl time.Duration) *PostgresStateStore {
+	return &PostgresStateStore{db: db, ttl: ttl}
+}
+
+func (s *PostgresStateStore) Get(key string) (*UserState, bool) {
+	var stateStr string
+	err := s.db.QueryRow(`SELECT state FROM user_states WHERE key = $1 AND expires_at > $2`, key, time.Now().Unix()).Scan(&stateStr)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			slog.Error("error getting user state", "key", key, "err", err)
+		}
+		return nil, false
+	}
+	var state UserState
+	if err := json.Unmarshal([]byte(stateStr), &state); err != nil {
+		slog.Error("error decoding user state", "key", key, "err", err)
+		return nil, false
+	}
+	return &state, true
+}
+
+func (s *PostgresStateStore) Set(key string, state *UserState) error {
+	state.ExpiresAt = time.Now().Add(s.ttl)
+	stateStr, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO user_states (key, state, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (key) DO UPDATE SET state = excluded.state, expires_at = excluded.expires_at`,
+		key, string(stateStr), state.ExpiresAt.Unix())
+	return err
+}
+
+func (s *PostgresStateStore) Delete(key string) error {
+	_, err := s.db.Exec(`DELETE FROM user_states WHERE key = $1`, key)
+	return err
+}
+
+func (s *PostgresStateStore) Count() (int, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM user_states WHERE expires_at > $1`, time.Now().Unix()).Scan(&count)
+	return count, err
+}
+
+// PopExpired deletes and returns the expired states in one statement, so that
+// each of them is returned to only one of the replicas
+func (s *PostgresStateStore) PopExpired(now time.Time) ([]*UserState, error) {
+	rows, err := s.db.Query(`DELETE FROM user_states WHERE expires_at <= $1 RETURNING key, state`, now.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var expired []*UserState
+	for rows.Next() {
+		var key, stateStr string
+		if err := rows.Scan(&key, &stateStr); err != nil {
+			return nil, err
+		}
+		var state UserState
+		if err := json.Unmarshal([]byte(stateStr), &state); err != nil {
+			slog.Error("error decoding expired user state", "key", key, "err", err)
+			continue
+		}
+		expired = append(expired, &state)
+	}
+	return expired, rows.Err()
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+)
+
+const (
+	databaseSQLite   = "sqlite"
+	databasePostgres = "postgres"
+)
+
+// EventRepository stores events and their votes
+type EventRepository interface {
+	GetEventByID(eventID int64) (*Event, error)
+	GetEventByMessageID(messageID int) (*Event, error)
+	// ListEvents returns the most recently created events first
+	ListEvents(limit int) ([]*Event, error)
+	SaveEvent(event *Event) (int64, error)
+	UpdateEvent(event *Event) error
+	// DeleteEvent removes an event together with its votes and returns the number of deleted events
+	DeleteEvent(eventID int64) (int64, error)
+	// GetEventByActivityID returns the most recent poll of the activity, sql.ErrNoRows if it has none
+	GetEventByActivityID(activityID int64) (*Event, error)
+	// GetHeadcounts returns the number of people who voted in the polls of each of the activities which have one
+	GetHeadcounts(activityIDs []int64) (map[int64]int, error)
+
+	GetEventUsers(eventID int64) ([]EventUser, error)
+	SaveEventUser(eventUser *EventUser) error
+	// ToggleEventUser adds the vote, or removes it if the user already voted for the option
+	ToggleEventUser(eventUser *EventUser) error
+	DeleteEventUser(eventUser *EventUser) (int64, error)
+	// GetEventsVotedByUser returns the events the user voted in and the votes
+	GetEventsVotedByUser(user string, userID int64) ([]*Event, []EventUser, error)
+}
+
+// ActivityRepository stores the activities of the work plan
+type ActivityRepository interface {
+	Save(activity *Activity) (int64, error)
+	// SaveAll creates all of the activities in one transaction, or none if any of them fails
+	SaveAll(activities []Activity) error
+	GetByID(id int64) (*Activity, error)
+	// GetByDuration returns the activities starting within the range, earliest first
+	GetByDuration(startTime, endTime time.Time) ([]Activity, error)
+	// GetOverlapping returns the activities which are not cancelled and take place
+	// at some time between startTime and endTime, earliest first
+	GetOverlapping(startTime, endTime time.Time) ([]Activity, error)
+	// GetAll returns all activities, earliest first
+	GetAll() ([]Activity, error)
+	// Search returns the latest limit activities matching the filter, earliest first
+	Search(filter ActivityFilter, limit int) ([]Activity, error)
+	// Update changes the activity, its reminder is reset if the start time changes
+	Update(activity *Activity) error
+	// MarkReminded claims the reminder of the activity if its leads were not reminded yet,
+	// it returns 0 if the reminder was claimed already
+	MarkReminded(id int64, at time.Time) (int64, error)
+	// Delete moves the activity to the trash, where it is hidden from the other methods
+	Delete(id int64) (int64, error)
+	// GetDeleted returns the activities moved to the trash since the given time, most recently deleted first
+	GetDeleted(since time.Time) ([]Activity, error)
+	// GetDeletedByID returns the activity if it was moved to the trash since the given time, sql.ErrNoRows otherwise
+	GetDeletedByID(id int64, since time.Time) (*Activity, error)
+	// Restore takes the activity out of the trash if it was deleted since the given time
+	Restore(id int64, since time.Time) (int64, error)
+	// Purge removes the activities deleted before the given time for good
+	Purge(before time.Time) (int64, error)
+}
+
+// OrgRepository stores the organizations activities belong to
+type OrgRepository interface {
+	// ListOrgs returns all organizations, including archived ones, by name
+	ListOrgs() ([]Organization, error)
+	GetOrg(name Org) (*Organization, error)
+	SaveOrg(org *Organization) error
+	// UpdateOrg changes the display name, emoji and active flag and returns the number of updated organizations
+	UpdateOrg(org *Organization) (int64, error)
+	// GetOrgAdmins returns the user IDs of the admins of the organization, who can delete and restore its activities
+	GetOrgAdmins(name Org) ([]int64, error)
+	// AddOrgAdmin makes the user an admin of the organization, if they are not already
+	AddOrgAdmin(name Org, userID int64) error
+	// RemoveOrgAdmin returns the number of removed admins
+	RemoveOrgAdmin(name Org, userID int64) (int64, error)
+}
+
+// ChatSettingsRepository stores the timezone and language of each chat
+type ChatSettingsRepository interface {
+	// GetLocalizer returns the language and timezone of the chat, falling back to the app defaults
+	GetLocalizer(ctx context.Context, chatID int64) *Localizer
+	SetTimezone(chatID int64, tz *time.Location) error
+	SetLanguage(chatID int64, language string) error
+}
+
+// KnownUserRepository stores the Telegram users the bot has seen
+type KnownUserRepository interface {
+	// Remember stores the user, or updates the username and name if they changed
+	Remember(user *models.User) error
+	// GetByUsername returns the user with the case-insensitive username, without the leading @
+	GetByUsername(username string) (*KnownUser, error)
+}
+
+var (
+	_ EventRepository    = (*EventDAO)(nil)
+	_ ActivityRepository = (*ActivityDAO)(nil)
+	_ OrgRepository      = (*OrgDAO)(nil)
+	_ EventRepository    = (*PostgresEventDAO)(nil)
+	_ ActivityRepository = (*PostgresActivityDAO)(nil)
+	_ OrgRepository      = (*PostgresOrgDAO)(nil)
+
+	_ ChatSettingsRepository = (*ChatSettingsDAO)(nil)
+	_ ChatSettingsRepository = (*PostgresChatSettingsDAO)(nil)
+	_ KnownUserRepository    = (*KnownUserDAO)(nil)
+	_ KnownUserRepository    = (*PostgresKnownUserDAO)(nil)
+	_ StateStore             = (*PostgresStateStore)(nil)
+)
+
+// Repositories are the stores selected by DatabaseConfig
+type Repositories struct {
+	Events     EventRepository
+	Activities ActivityRepository
+	Orgs       OrgRepository
+	// ChatSettings and KnownUsers are shared by the replicas along with the events
+	ChatSettings ChatSettingsRepository
+	KnownUsers   KnownUserRepository
+	// sqlite is the local database, which holds the data unless postgres is set
+	sqlite *sql.DB
+	// postgres is the shared database, nil if everything is kept in SQLite
+	postgres *sql.DB
+}
+
+// OpenRepositories returns the stores selected by the config. sqliteDB is used
+// unless PostgreSQL is configured
+func OpenRepositories(config DatabaseConfig, sqliteDB *sql.DB) (*Repositories, error) {
+	switch config.Type {
+	case "", databaseSQLite:
+		return &Repositories{
+			Events:       NewEventDAO(sqliteDB),
+			Activities:   NewActivityDAO(sqliteDB),
+			Orgs:         NewOrgDAO(sqliteDB),
+			ChatSettings: NewChatSettingsDAO(sqliteDB),
+			KnownUsers:   NewKnownUserDAO(sqliteDB),
+			sqlite:       sqliteDB,
+		}, nil
+	case databasePostgres:
+		db, err := openInstrumentedDB("pgx", config.URL)
+		if err != nil {
+			return nil, err
+		}
+		return &Repositories{
+			Events:       NewPostgresEventDAO(db),
+			Activities:   NewPostgresActivityDAO(db),
+			Orgs:         NewPostgresOrgDAO(db),
+			ChatSettings: NewPostgresChatSettingsDAO(db),
+			KnownUsers:   NewPostgresKnownUserDAO(db),
+			sqlite:       sqliteDB,
+			postgres:     db,
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported database type %q", config.Type)
+}
+
+// Migrate applies the pending migrations of the shared database, if any
+func (r *Repositories) Migrate() error {
+	if r.postgres == nil {
+		return nil
+	}
+	return MigratePostgres(r.postgres)
+}
+
+// PlanMigrations returns the migrations the shared database is missing, if any
+func (r *Repositories) PlanMigrations() ([]Migration, error) {
+	if r.postgres == nil {
+		return nil, nil
+	}
+	return PlanPostgresMigrations(r.postgres)
+}
+
+func (r *Repositories) Close() error {
+	if r.postgres == nil {
+		return nil
+	}
+	return r.postgres.Close()
+}
@@ -0,0 +1,757 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot/models"
+)
+
+// postgresTestURLEnv names the database the PostgreSQL contract tests run against.
+// Its events, activities, chat settings, known users and user states are deleted by the tests
+const postgresTestURLEnv = "EVENTPOLL_TEST_POSTGRES_URL"
+
+// repositoryFactory returns empty repositories for a single test
+type repositoryFactory func(t *testing.T) (EventRepository, ActivityRepository)
+
+func TestSQLiteRepositories(t *testing.T) {
+	newRepos := func(t *testing.T) (EventRepository, ActivityRepository) {
+		db := setupTestDB(t)
+		t.Cleanup(func() { db.Close() })
+		if err := MigrateDB(db, time.UTC); err != nil {
+			t.Fatalf("MigrateDB failed: %v", err)
+		}
+		return NewEventDAO(db), NewActivityDAO(db)
+	}
+	testRepositoryContract(t, newRepos)
+	testOrgRepositoryContract(t, func(t *testing.T) OrgRepository {
+		db := setupTestDB(t)
+		t.Cleanup(func() { db.Close() })
+		if err := MigrateDB(db, time.UTC); err != nil {
+			t.Fatalf("MigrateDB failed: %v", err)
+		}
+		return NewOrgDAO(db)
+	})
+	testSharedStateContract(t, func(t *testing.T) *Repositories {
+		db := setupTestDB(t)
+		t.Cleanup(func() { db.Close() })
+		if err := MigrateDB(db, time.UTC); err != nil {
+			t.Fatalf("MigrateDB failed: %v", err)
+		}
+		repos, err := OpenRepositories(DatabaseConfig{}, db)
+		if err != nil {
+			t.Fatalf("OpenRepositories failed: %v", err)
+		}
+		return repos
+	})
+}
+
+func TestPostgresRepositories(t *testing.T) {
+	url := os.Getenv(postgresTestURLEnv)
+	if url == "" {
+		t.Skipf("%s is not set", postgresTestURLEnv)
+	}
+	repos, err := OpenRepositories(DatabaseConfig{Type: databasePostgres, URL: url}, nil)
+	if err != nil {
+		t.Fatalf("OpenRepositories failed: %v", err)
+	}
+	defer repos.Close()
+	if err := repos.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	// migrating again is a no-op
+	if err := repos.Migrate(); err != nil {
+		t.Fatalf("Second Migrate failed: %v", err)
+	}
+	if pending, err := repos.PlanMigrations(); err != nil || len(pending) != 0 {
+		t.Fatalf("Expected no pending migrations, got %d, %v", len(pending), err)
+	}
+
+	newRepos := func(t *testing.T) (EventRepository, ActivityRepository) {
+		if _, err := repos.postgres.Exec(`TRUNCATE event_users, events, activity_leads, activities RESTART IDENTITY`); err != nil {
+			t.Fatalf("Failed to empty tables: %v", err)
+		}
+		return repos.Events, repos.Activities
+	}
+	testRepositoryContract(t, newRepos)
+	testOrgRepositoryContract(t, func(t *testing.T) OrgRepository {
+		if _, err := repos.postgres.Exec(`DELETE FROM org_admins`); err != nil {
+			t.Fatalf("Failed to empty org admins: %v", err)
+		}
+		if _, err := repos.postgres.Exec(`DELETE FROM orgs WHERE name NOT IN ('CC', 'PEAK')`); err != nil {
+			t.Fatalf("Failed to empty orgs: %v", err)
+		}
+		return repos.Orgs
+	})
+	testSharedStateContract(t, func(t *testing.T) *Repositories {
+		if _, err := repos.postgres.Exec(`TRUNCATE user_states, chat_settings, known_users`); err != nil {
+			t.Fatalf("Failed to empty tables: %v", err)
+		}
+		return repos
+	})
+}
+
+// testSharedStateContract checks the chat settings, known users and user states,
+// which the replicas share when the repositories are kept in PostgreSQL
+func testSharedStateContract(t *testing.T, newRepos func(t *testing.T) *Repositories) {
+	prevConfig := AppConfig
+	AppConfig = &Config{Timezone: time.UTC, Language: langEnglish}
+	t.Cleanup(func() { AppConfig = prevConfig })
+
+	t.Run("chat settings", func(t *testing.T) {
+		settings := newRepos(t).ChatSettings
+		ctx := context.Background()
+		if l := settings.GetLocalizer(ctx, 1); l.Location != time.UTC || l.Language != langEnglish {
+			t.Errorf("Expected the app defaults, got %s %s", l.Language, l.Location)
+		}
+		berlin, err := time.LoadLocation("Europe/Berlin")
+		if err != nil {
+			t.Fatalf("LoadLocation failed: %v", err)
+		}
+		if err := settings.SetTimezone(1, berlin); err != nil {
+			t.Fatalf("SetTimezone failed: %v", err)
+		}
+		if err := settings.SetLanguage(1, langChinese); err != nil {
+			t.Fatalf("SetLanguage failed: %v", err)
+		}
+		if l := settings.GetLocalizer(ctx, 1); l.Location.String() != "Europe/Berlin" || l.Language != langChinese {
+			t.Errorf("Expected zh in Europe/Berlin, got %s %s", l.Language, l.Location)
+		}
+		if l := settings.GetLocalizer(ctx, 2); l.Location != time.UTC || l.Language != langEnglish {
+			t.Errorf("Expected the app defaults for another chat, got %s %s", l.Language, l.Location)
+		}
+	})
+
+	t.Run("known users", func(t *testing.T) {
+		users := newRepos(t).KnownUsers
+		if _, err := users.GetByUsername("alice"); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows for an unknown user, got %v", err)
+		}
+		for _, user := range []*models.User{
+			{ID: 1, FirstName: "Alice", Username: "alice"},
+			{ID: 1, FirstName: "Alice", LastName: "Chan", Username: "AliceC"},
+		} {
+			if err := users.Remember(user); err != nil {
+				t.Fatalf("Remember failed: %v", err)
+			}
+		}
+		got, err := users.GetByUsername("@alicec")
+		if err != nil {
+			t.Fatalf("GetByUsername failed: %v", err)
+		}
+		if *got != (KnownUser{ID: 1, Username: "AliceC", FullName: "Alice Chan"}) {
+			t.Errorf("Unexpected known user %+v", got)
+		}
+		if _, err := users.GetByUsername("alice"); err != sql.ErrNoRows {
+			t.Errorf("Expected the former username to be forgotten, got %v", err)
+		}
+	})
+
+	t.Run("user states", func(t *testing.T) {
+		store, err := NewStateStore(StateStoreConfig{}, newRepos(t))
+		if err != nil {
+			t.Fatalf("NewStateStore failed: %v", err)
+		}
+		testStateStore(t, store)
+	})
+}
+
+// testOrgRepositoryContract checks the organizations, starting from the ones created by the migrations
+func testOrgRepositoryContract(t *testing.T, newOrgs func(t *testing.T) OrgRepository) {
+	t.Run("orgs", func(t *testing.T) {
+		orgs := newOrgs(t)
+		all, err := orgs.ListOrgs()
+		if err != nil {
+			t.Fatalf("ListOrgs failed: %v", err)
+		}
+		if names := orgNames(all); !reflect.DeepEqual(names, []Org{OrgCC, OrgPEAK}) {
+			t.Errorf("Expected the migrated orgs, got %v", names)
+		}
+
+		hike := &Organization{Name: "HIKE", DisplayName: "Hiking club", Emoji: "🥾", Active: true}
+		if err := orgs.SaveOrg(hike); err != nil {
+			t.Fatalf("SaveOrg failed: %v", err)
+		}
+		if err := orgs.SaveOrg(hike); err == nil {
+			t.Error("Expected saving a duplicate org to fail")
+		}
+		got, err := orgs.GetOrg("HIKE")
+		if err != nil || !reflect.DeepEqual(got, hike) {
+			t.Fatalf("Expected %+v, got %+v, %v", hike, got, err)
+		}
+
+		hike.DisplayName, hike.Active = "Hikers", false
+		if n, err := orgs.UpdateOrg(hike); err != nil || n != 1 {
+			t.Fatalf("UpdateOrg = %d, %v", n, err)
+		}
+		if n, err := orgs.UpdateOrg(&Organization{Name: "NOPE"}); err != nil || n != 0 {
+			t.Errorf("Expected no org to be updated, got %d, %v", n, err)
+		}
+		all, err = orgs.ListOrgs()
+		if err != nil {
+			t.Fatalf("ListOrgs failed: %v", err)
+		}
+		if len(all) != 3 || !reflect.DeepEqual(all[1], *hike) || len(activeOrgs(all)) != 2 {
+			t.Errorf("Expected HIKE to be archived, got %+v", all)
+		}
+		if _, err := orgs.GetOrg("NOPE"); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows, got %v", err)
+		}
+	})
+
+	t.Run("org admins", func(t *testing.T) {
+		orgs := newOrgs(t)
+		for _, id := range []int64{2, 1, 2} {
+			if err := orgs.AddOrgAdmin(OrgPEAK, id); err != nil {
+				t.Fatalf("AddOrgAdmin failed: %v", err)
+			}
+		}
+		if admins, err := orgs.GetOrgAdmins(OrgPEAK); err != nil || !reflect.DeepEqual(admins, []int64{1, 2}) {
+			t.Errorf("Expected admins [1 2], got %v, %v", admins, err)
+		}
+		if admins, err := orgs.GetOrgAdmins(OrgCC); err != nil || len(admins) != 0 {
+			t.Errorf("Expected no CC admins, got %v, %v", admins, err)
+		}
+		if n, err := orgs.RemoveOrgAdmin(OrgPEAK, 1); err != nil || n != 1 {
+			t.Fatalf("RemoveOrgAdmin = %d, %v", n, err)
+		}
+		if n, err := orgs.RemoveOrgAdmin(OrgPEAK, 1); err != nil || n != 0 {
+			t.Errorf("Expected no admin to be removed twice, got %d, %v", n, err)
+		}
+		if admins, _ := orgs.GetOrgAdmins(OrgPEAK); !reflect.DeepEqual(admins, []int64{2}) {
+			t.Errorf("Expected admins [2], got %v", admins)
+		}
+	})
+}
+
+// testRepositoryContract checks the behavior every implementation must have
+func testRepositoryContract(t *testing.T, newRepos repositoryFactory) {
+	startedAt := time.Date(2025, 3, 8, 18, 30, 0, 0, time.UTC)
+
+	t.Run("event round trip", func(t *testing.T) {
+		events, _ := newRepos(t)
+		event := &Event{Description: "Board games", Options: []string{"Yes", "Maybe"}, ChatID: -1001,
+			CreatedBy: "Alice", CreatedByID: 1, StartedAt: &startedAt}
+		id, err := events.SaveEvent(event)
+		if err != nil {
+			t.Fatalf("SaveEvent failed: %v", err)
+		}
+		got, err := events.GetEventByID(id)
+		if err != nil {
+			t.Fatalf("GetEventByID failed: %v", err)
+		}
+		if got.ID != id || got.Description != "Board games" || !reflect.DeepEqual(got.Options, event.Options) ||
+			got.ChatID != -1001 || got.CreatedBy != "Alice" || got.CreatedByID != 1 ||
+			got.StartedAt == nil || !got.StartedAt.Equal(startedAt) {
+			t.Errorf("Unexpected event %+v", got)
+		}
+
+		got.MessageID = 42
+		got.StartedAt = nil
+		if err := events.UpdateEvent(got); err != nil {
+			t.Fatalf("UpdateEvent failed: %v", err)
+		}
+		got, err = events.GetEventByMessageID(42)
+		if err != nil {
+			t.Fatalf("GetEventByMessageID failed: %v", err)
+		}
+		if got.ID != id || got.StartedAt != nil {
+			t.Errorf("Unexpected updated event %+v", got)
+		}
+
+		if _, err := events.GetEventByID(id + 100); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows for a missing event, got %v", err)
+		}
+	})
+
+	t.Run("list events", func(t *testing.T) {
+		events, _ := newRepos(t)
+		var ids []int64
+		for _, description := range []string{"first", "second", "third"} {
+			id, err := events.SaveEvent(&Event{Description: description, Options: []string{"Yes"}})
+			if err != nil {
+				t.Fatalf("SaveEvent failed: %v", err)
+			}
+			ids = append(ids, id)
+		}
+		got, err := events.ListEvents(2)
+		if err != nil {
+			t.Fatalf("ListEvents failed: %v", err)
+		}
+		if len(got) != 2 || got[0].ID != ids[2] || got[1].ID != ids[1] {
+			t.Errorf("Expected the two most recent events, got %+v", got)
+		}
+	})
+
+	t.Run("votes", func(t *testing.T) {
+		events, _ := newRepos(t)
+		eventID, err := events.SaveEvent(&Event{Description: "Hike", Options: []string{"Yes", "No"}})
+		if err != nil {
+			t.Fatalf("SaveEvent failed: %v", err)
+		}
+		bob := &EventUser{EventID: eventID, User: "Bob", UserID: 2, Option: "Yes"}
+		carol := &EventUser{EventID: eventID, User: "Carol", UserID: 3, Option: "No"}
+		for _, vote := range []*EventUser{bob, carol, bob, bob} {
+			if err := events.ToggleEventUser(vote); err != nil {
+				t.Fatalf("ToggleEventUser failed: %v", err)
+			}
+		}
+		if got := voters(t, events, eventID); !reflect.DeepEqual(got, []string{"Bob:Yes", "Carol:No"}) {
+			t.Errorf("Expected Bob and Carol to have voted, got %v", got)
+		}
+		if err := events.ToggleEventUser(bob); err != nil {
+			t.Fatalf("ToggleEventUser failed: %v", err)
+		}
+		if got := voters(t, events, eventID); !reflect.DeepEqual(got, []string{"Carol:No"}) {
+			t.Errorf("Expected Bob's vote to be withdrawn, got %v", got)
+		}
+
+		n, err := events.DeleteEventUser(carol)
+		if err != nil || n != 1 {
+			t.Fatalf("Expected one vote to be deleted, got %d, %v", n, err)
+		}
+		if got := voters(t, events, eventID); len(got) != 0 {
+			t.Errorf("Expected no votes, got %v", got)
+		}
+	})
+
+	t.Run("events voted by user", func(t *testing.T) {
+		events, _ := newRepos(t)
+		var ids []int64
+		for _, description := range []string{"voted", "withdrawn", "legacy", "other"} {
+			id, err := events.SaveEvent(&Event{Description: description, Options: []string{"Yes"}})
+			if err != nil {
+				t.Fatalf("SaveEvent failed: %v", err)
+			}
+			ids = append(ids, id)
+		}
+		votes := []*EventUser{
+			{EventID: ids[0], User: "Bob", UserID: 2, Option: "Yes"},
+			{EventID: ids[1], User: "Bob", UserID: 2, Option: "Yes"},
+			{EventID: ids[1], User: "Bob", UserID: 2, Option: "Yes"},
+			// votes from before user IDs were stored are matched by name
+			{EventID: ids[2], User: "Bob", Option: "Yes"},
+			{EventID: ids[3], User: "Carol", UserID: 3, Option: "Yes"},
+		}
+		for _, vote := range votes {
+			if err := events.ToggleEventUser(vote); err != nil {
+				t.Fatalf("ToggleEventUser failed: %v", err)
+			}
+		}
+
+		got, eventUsers, err := events.GetEventsVotedByUser("Bob", 2)
+		if err != nil {
+			t.Fatalf("GetEventsVotedByUser failed: %v", err)
+		}
+		var descriptions []string
+		for _, event := range got {
+			descriptions = append(descriptions, event.Description)
+		}
+		sort.Strings(descriptions)
+		if !reflect.DeepEqual(descriptions, []string{"legacy", "voted"}) || len(eventUsers) != 2 {
+			t.Errorf("Expected Bob's current votes, got events %v and votes %+v", descriptions, eventUsers)
+		}
+
+		if got, _, err := events.GetEventsVotedByUser("Dave", 4); err != nil || len(got) != 0 {
+			t.Errorf("Expected no events for a user without votes, got %v, %v", got, err)
+		}
+	})
+
+	t.Run("delete event", func(t *testing.T) {
+		events, _ := newRepos(t)
+		eventID, err := events.SaveEvent(&Event{Description: "Hike", Options: []string{"Yes"}})
+		if err != nil {
+			t.Fatalf("SaveEvent failed: %v", err)
+		}
+		if err := events.SaveEventUser(&EventUser{EventID: eventID, User: "Bob", UserID: 2, Option: "Yes"}); err != nil {
+			t.Fatalf("SaveEventUser failed: %v", err)
+		}
+		if n, err := events.DeleteEvent(eventID); err != nil || n != 1 {
+			t.Fatalf("Expected one event to be deleted, got %d, %v", n, err)
+		}
+		if got := voters(t, events, eventID); len(got) != 0 {
+			t.Errorf("Expected the votes to be deleted, got %v", got)
+		}
+		if n, err := events.DeleteEvent(eventID); err != nil || n != 0 {
+			t.Errorf("Expected nothing to delete, got %d, %v", n, err)
+		}
+	})
+
+	t.Run("activity round trip", func(t *testing.T) {
+		_, activities := newRepos(t)
+		activity := &Activity{Name: "Hike", Org: OrgPEAK, Lead: "Alice", CoLeads: []string{"Bob", "Carol"},
+			StartedAt: startedAt, CreatedBy: "Alice", CreatedByID: 1}
+		id, err := activities.Save(activity)
+		if err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		if activity.ID != id {
+			t.Errorf("Expected the ID to be set on the activity, got %d and %d", activity.ID, id)
+		}
+		got, err := activities.GetByID(id)
+		if err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+		if got.Name != "Hike" || got.Org != OrgPEAK || got.Lead != "Alice" || !reflect.DeepEqual(got.CoLeads, []string{"Bob", "Carol"}) ||
+			!got.StartedAt.Equal(startedAt) || got.CreatedBy != "Alice" || got.CreatedByID != 1 ||
+			got.EndsAt != nil || got.Location != "" || got.Status != ActivityPlanned {
+			t.Errorf("Unexpected activity %+v", got)
+		}
+
+		endsAt := startedAt.Add(3 * time.Hour)
+		got.Name = "Long hike"
+		got.CoLeads = []string{}
+		got.StartedAt = startedAt.Add(time.Hour)
+		got.EndsAt = &endsAt
+		got.Location = "Lion Rock"
+		got.Description = "Bring water"
+		got.Status = ActivityConfirmed
+		if err := activities.Update(got); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		got, err = activities.GetByID(id)
+		if err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+		if got.Name != "Long hike" || len(got.CoLeads) != 0 || !got.StartedAt.Equal(startedAt.Add(time.Hour)) ||
+			got.EndsAt == nil || !got.EndsAt.Equal(endsAt) || got.Location != "Lion Rock" || got.Description != "Bring water" ||
+			got.Status != ActivityConfirmed {
+			t.Errorf("Unexpected updated activity %+v", got)
+		}
+
+		if n, err := activities.Delete(id); err != nil || n != 1 {
+			t.Fatalf("Expected one activity to be deleted, got %d, %v", n, err)
+		}
+		if _, err := activities.GetByID(id); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows for a deleted activity, got %v", err)
+		}
+	})
+
+	t.Run("save all activities", func(t *testing.T) {
+		_, activities := newRepos(t)
+		batch := []Activity{
+			{Name: "Quiz", Org: OrgCC, Lead: "Bob", CoLeads: []string{"Carol"}, StartedAt: startedAt.Add(time.Hour)},
+			{Name: "Hike", Org: OrgPEAK, Lead: "Alice", StartedAt: startedAt},
+		}
+		if err := activities.SaveAll(batch); err != nil {
+			t.Fatalf("SaveAll failed: %v", err)
+		}
+		if batch[0].ID == 0 || batch[1].ID == 0 {
+			t.Errorf("Expected the IDs to be set, got %+v", batch)
+		}
+		all, err := activities.GetAll()
+		if err != nil {
+			t.Fatalf("GetAll failed: %v", err)
+		}
+		if names := activityNames(all); !reflect.DeepEqual(names, []string{"Hike", "Quiz"}) {
+			t.Errorf("Expected both activities to be saved, got %v", names)
+		}
+	})
+
+	t.Run("activities by duration", func(t *testing.T) {
+		_, activities := newRepos(t)
+		for i, name := range []string{"third", "first", "outside", "second"} {
+			offsets := []time.Duration{72 * time.Hour, 0, 30 * 24 * time.Hour, 24 * time.Hour}
+			if _, err := activities.Save(&Activity{Name: name, Org: OrgCC, Lead: "Alice", StartedAt: startedAt.Add(offsets[i])}); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+		}
+		got, err := activities.GetByDuration(startedAt, startedAt.Add(7*24*time.Hour))
+		if err != nil {
+			t.Fatalf("GetByDuration failed: %v", err)
+		}
+		if names := activityNames(got); !reflect.DeepEqual(names, []string{"first", "second", "third"}) {
+			t.Errorf("Expected the activities of the week by start time, got %v", names)
+		}
+		all, err := activities.GetAll()
+		if err != nil {
+			t.Fatalf("GetAll failed: %v", err)
+		}
+		if names := activityNames(all); !reflect.DeepEqual(names, []string{"first", "second", "third", "outside"}) {
+			t.Errorf("Expected all activities by start time, got %v", names)
+		}
+	})
+
+	t.Run("activity polls", func(t *testing.T) {
+		events, activities := newRepos(t)
+		var ids []int64
+		for _, name := range []string{"Hike", "Quiz", "Camp"} {
+			id, err := activities.Save(&Activity{Name: name, Org: OrgCC, Lead: "Alice", StartedAt: startedAt})
+			if err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+			ids = append(ids, id)
+		}
+		pollID, err := events.SaveEvent(&Event{Description: "Hike", Options: []string{"Available", "Maybe"}, ActivityID: ids[0]})
+		if err != nil {
+			t.Fatalf("SaveEvent failed: %v", err)
+		}
+		if _, err := events.SaveEvent(&Event{Description: "Quiz", Options: []string{"Available"}, ActivityID: ids[1]}); err != nil {
+			t.Fatalf("SaveEvent failed: %v", err)
+		}
+		for _, vote := range []EventUser{
+			{EventID: pollID, User: "Alice", UserID: 1, Option: "Available"},
+			{EventID: pollID, User: "Alice", UserID: 1, Option: "Maybe"},
+			{EventID: pollID, User: "Bob", UserID: 2, Option: "Available"},
+		} {
+			if err := events.ToggleEventUser(&vote); err != nil {
+				t.Fatalf("ToggleEventUser failed: %v", err)
+			}
+		}
+
+		poll, err := events.GetEventByActivityID(ids[0])
+		if err != nil {
+			t.Fatalf("GetEventByActivityID failed: %v", err)
+		}
+		if poll.ID != pollID || poll.ActivityID != ids[0] {
+			t.Errorf("Expected poll %d of activity %d, got %+v", pollID, ids[0], poll)
+		}
+		if _, err := events.GetEventByActivityID(ids[2]); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows for an activity without a poll, got %v", err)
+		}
+		headcounts, err := events.GetHeadcounts(ids)
+		if err != nil {
+			t.Fatalf("GetHeadcounts failed: %v", err)
+		}
+		if want := map[int64]int{ids[0]: 2, ids[1]: 0}; !reflect.DeepEqual(headcounts, want) {
+			t.Errorf("Expected headcounts %v, got %v", want, headcounts)
+		}
+	})
+
+	t.Run("lead user ids and reminders", func(t *testing.T) {
+		_, activities := newRepos(t)
+		activity := &Activity{Name: "Hike", Org: OrgCC, Lead: "Alice", LeadID: 1, CoLeads: []string{"Bob", "Carol"},
+			CoLeadIDs: []int64{2, 0}, StartedAt: startedAt}
+		id, err := activities.Save(activity)
+		if err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		got, err := activities.GetByID(id)
+		if err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+		if got.LeadID != 1 || !reflect.DeepEqual(got.CoLeadIDs, []int64{2, 0}) || got.RemindedAt != nil {
+			t.Errorf("Unexpected lead user ids %+v", got)
+		}
+
+		if claimed, err := activities.MarkReminded(id, startedAt.Add(-24*time.Hour)); err != nil || claimed != 1 {
+			t.Fatalf("Expected the first MarkReminded to claim the reminder, got %d (%v)", claimed, err)
+		}
+		if claimed, err := activities.MarkReminded(id, startedAt.Add(-23*time.Hour)); err != nil || claimed != 0 {
+			t.Fatalf("Expected the second MarkReminded to be a no-op, got %d (%v)", claimed, err)
+		}
+		if got, _ := activities.GetByID(id); got.RemindedAt == nil || !got.RemindedAt.Equal(startedAt.Add(-24*time.Hour)) {
+			t.Errorf("Expected the first reminder time to be kept, got %v", got.RemindedAt)
+		}
+		got.Location = "Lion Rock"
+		if err := activities.Update(got); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		if got, _ = activities.GetByID(id); got.RemindedAt == nil {
+			t.Errorf("Expected the reminder to be kept when the start time is unchanged")
+		}
+		got.StartedAt = startedAt.Add(48 * time.Hour)
+		if err := activities.Update(got); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		if got, _ = activities.GetByID(id); got.RemindedAt != nil {
+			t.Errorf("Expected the reminder to be reset when the start time changes, got %v", got.RemindedAt)
+		}
+	})
+
+	t.Run("search activities", func(t *testing.T) {
+		_, activities := newRepos(t)
+		for i, a := range []Activity{
+			{Name: "Hike", Org: OrgPEAK, Lead: "Alice", LeadID: 1, CoLeads: []string{"Bob"}},
+			{Name: "Quiz night", Org: OrgCC, Lead: "bob", Location: "Hall"},
+			{Name: "Fair", Org: OrgCC, Lead: "Carol", CoLeads: []string{"Dave", "Alice"}, CoLeadIDs: []int64{4, 1}, Description: "100% fun"},
+			{Name: "Talk", Org: OrgCC, Lead: "Dave", CoLeads: []string{"Bob"}, Description: "Quiz_master"},
+		} {
+			a.StartedAt = startedAt.Add(time.Duration(i) * time.Hour)
+			if _, err := activities.Save(&a); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+		}
+		// the co-leads of an updated activity are found by their new names only
+		talk, err := activities.GetByID(4)
+		if err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+		talk.CoLeads = []string{"Erin"}
+		if err := activities.Update(talk); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+
+		for _, tt := range []struct {
+			name   string
+			filter ActivityFilter
+			limit  int
+			want   []string
+		}{
+			{"all", ActivityFilter{}, 10, []string{"Hike", "Quiz night", "Fair", "Talk"}},
+			{"latest", ActivityFilter{}, 2, []string{"Fair", "Talk"}},
+			{"org", ActivityFilter{Org: OrgCC}, 10, []string{"Quiz night", "Fair", "Talk"}},
+			{"text in name or description", ActivityFilter{Text: "QUIZ"}, 10, []string{"Quiz night", "Talk"}},
+			{"text in location", ActivityFilter{Text: "hall"}, 10, []string{"Quiz night"}},
+			{"wildcards are literal", ActivityFilter{Text: "0%"}, 10, []string{"Fair"}},
+			{"lead or co-lead by name", ActivityFilter{LeadName: "BOB"}, 10, []string{"Hike", "Quiz night"}},
+			{"lead or co-lead by id", ActivityFilter{LeadID: 1}, 10, []string{"Hike", "Fair"}},
+			{"by name or id", ActivityFilter{LeadName: "Dave", LeadID: 1}, 10, []string{"Hike", "Fair", "Talk"}},
+			{"updated co-lead", ActivityFilter{LeadName: "erin"}, 10, []string{"Talk"}},
+			{"all conditions", ActivityFilter{Org: OrgCC, LeadName: "Dave", Text: "fun"}, 10, []string{"Fair"}},
+		} {
+			got, err := activities.Search(tt.filter, tt.limit)
+			if err != nil {
+				t.Fatalf("Search %s failed: %v", tt.name, err)
+			}
+			if names := activityNames(got); !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("Search %s = %v, want %v", tt.name, names, tt.want)
+			}
+		}
+
+		if _, err := activities.Delete(2); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if got, _ := activities.Search(ActivityFilter{LeadName: "bob"}, 10); !reflect.DeepEqual(activityNames(got), []string{"Hike"}) {
+			t.Errorf("Expected a deleted activity not to be found, got %v", activityNames(got))
+		}
+	})
+
+	t.Run("trash and restore activities", func(t *testing.T) {
+		events, activities := newRepos(t)
+		var ids []int64
+		for _, name := range []string{"Hike", "Quiz"} {
+			id, err := activities.Save(&Activity{Name: name, Org: OrgCC, Lead: "Alice", CoLeads: []string{"Bob"}, StartedAt: startedAt})
+			if err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+			ids = append(ids, id)
+		}
+		pollID, err := events.SaveEvent(&Event{Description: "Hike", Options: []string{"Available"}, ActivityID: ids[0]})
+		if err != nil {
+			t.Fatalf("SaveEvent failed: %v", err)
+		}
+		before := time.Now().Add(-time.Minute)
+		if n, err := activities.Delete(ids[0]); err != nil || n != 1 {
+			t.Fatalf("Expected one activity to be deleted, got %d, %v", n, err)
+		}
+		if n, err := activities.Delete(ids[0]); err != nil || n != 0 {
+			t.Errorf("Expected a deleted activity not to be deleted again, got %d, %v", n, err)
+		}
+		if all, _ := activities.GetAll(); !reflect.DeepEqual(activityNames(all), []string{"Quiz"}) {
+			t.Errorf("Expected the deleted activity to be hidden, got %v", activityNames(all))
+		}
+		if got, _ := activities.Search(ActivityFilter{LeadName: "bob"}, 10); !reflect.DeepEqual(activityNames(got), []string{"Quiz"}) {
+			t.Errorf("Expected the deleted activity not to be found, got %v", activityNames(got))
+		}
+		deleted, err := activities.GetDeleted(before)
+		if err != nil {
+			t.Fatalf("GetDeleted failed: %v", err)
+		}
+		if len(deleted) != 1 || deleted[0].ID != ids[0] || deleted[0].DeletedAt == nil {
+			t.Fatalf("Unexpected deleted activities %+v", deleted)
+		}
+		if got, _ := activities.GetDeleted(time.Now().Add(time.Minute)); len(got) != 0 {
+			t.Errorf("Expected nothing deleted after now, got %v", activityNames(got))
+		}
+		if got, err := activities.GetDeletedByID(ids[0], before); err != nil || got.Name != "Hike" {
+			t.Errorf("Expected the deleted hike, got %+v, %v", got, err)
+		}
+		if _, err := activities.GetDeletedByID(ids[1], before); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows for an activity which is not deleted, got %v", err)
+		}
+		if _, err := activities.GetDeletedByID(ids[0], time.Now().Add(time.Minute)); err != sql.ErrNoRows {
+			t.Errorf("Expected sql.ErrNoRows for an activity deleted before the window, got %v", err)
+		}
+		if n, err := activities.Restore(ids[0], time.Now().Add(time.Minute)); err != nil || n != 0 {
+			t.Errorf("Expected an activity deleted before the window not to be restored, got %d, %v", n, err)
+		}
+
+		if n, err := activities.Restore(ids[0], before); err != nil || n != 1 {
+			t.Fatalf("Expected one activity to be restored, got %d, %v", n, err)
+		}
+		got, err := activities.GetByID(ids[0])
+		if err != nil {
+			t.Fatalf("GetByID failed: %v", err)
+		}
+		if got.DeletedAt != nil || !reflect.DeepEqual(got.CoLeads, []string{"Bob"}) {
+			t.Errorf("Unexpected restored activity %+v", got)
+		}
+		if got, _ := activities.Search(ActivityFilter{LeadName: "bob"}, 10); len(got) != 2 {
+			t.Errorf("Expected the leads of the restored activity to be found, got %v", activityNames(got))
+		}
+
+		if _, err := activities.Delete(ids[0]); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+		if n, err := activities.Purge(before); err != nil || n != 0 {
+			t.Errorf("Expected nothing deleted before the window to be purged, got %d, %v", n, err)
+		}
+		if n, err := activities.Purge(time.Now().Add(time.Minute)); err != nil || n != 1 {
+			t.Fatalf("Expected one activity to be purged, got %d, %v", n, err)
+		}
+		if got, _ := activities.GetDeleted(before); len(got) != 0 {
+			t.Errorf("Expected the purged activity to be gone, got %v", activityNames(got))
+		}
+		poll, err := events.GetEventByID(pollID)
+		if err != nil {
+			t.Fatalf("GetEventByID failed: %v", err)
+		}
+		if poll.ActivityID != 0 {
+			t.Errorf("Expected the poll to be unlinked from the purged activity, got %d", poll.ActivityID)
+		}
+	})
+
+	t.Run("overlapping activities", func(t *testing.T) {
+		_, activities := newRepos(t)
+		allDay := startedAt.Add(-2 * time.Hour)
+		allDayEnd := allDay.Add(12 * time.Hour)
+		for _, a := range []Activity{
+			{Name: "before", StartedAt: startedAt.Add(-4 * time.Hour)},
+			{Name: "all day", StartedAt: allDay, EndsAt: &allDayEnd},
+			{Name: "earlier", StartedAt: startedAt.Add(-90 * time.Minute)},
+			{Name: "cancelled", StartedAt: startedAt, Status: ActivityCancelled},
+			{Name: "later", StartedAt: startedAt.Add(time.Hour)},
+			{Name: "after", StartedAt: startedAt.Add(2 * time.Hour)},
+		} {
+			a.Org, a.Lead = OrgCC, "Alice"
+			if _, err := activities.Save(&a); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+		}
+		got, err := activities.GetOverlapping(startedAt, startedAt.Add(2*time.Hour))
+		if err != nil {
+			t.Fatalf("GetOverlapping failed: %v", err)
+		}
+		if names := activityNames(got); !reflect.DeepEqual(names, []string{"all day", "earlier", "later"}) {
+			t.Errorf("Expected the activities taking place in the range, got %v", names)
+		}
+	})
+}
+
+func voters(t *testing.T, events EventRepository, eventID int64) []string {
+	t.Helper()
+	users, err := events.GetEventUsers(eventID)
+	if err != nil {
+		t.Fatalf("GetEventUsers failed: %v", err)
+	}
+	var got []string
+	for _, user := range users {
+		got = append(got, user.User+":"+user.Option)
+	}
+	sort.Strings(got)
+	return got
+}
+
+func activityNames(activities []Activity) []string {
+	var names []string
+	for _, a := range activities {
+		names = append(names, a.Name)
+	}
+	return names
+}
@@ -3,7 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"sort"
 
 	"github.com/go-telegram/bot"
@@ -12,32 +12,34 @@ import (
 
 // UserHandler handles user-related operations
 type UserHandler struct {
-	eventDAO *EventDAO
+	eventDAO     EventRepository
+	chatSettings ChatSettingsRepository
 }
 
 // NewUserHandler creates a new UserHandler instance
-func NewUserHandler(eventDAO *EventDAO) *UserHandler {
+func NewUserHandler(eventDAO EventRepository, chatSettings ChatSettingsRepository) *UserHandler {
 	return &UserHandler{
-		eventDAO: eventDAO,
+		eventDAO:     eventDAO,
+		chatSettings: chatSettings,
 	}
 }
 
 // sendMyVotedEvents send events that a user has voted for
 // only contains events starting from 2 months ago
-func (h *UserHandler) sendMyVotedEvents(ctx context.Context, b *bot.Bot, update *models.Update) {
-	// startTime := getCurrentMonthInUTC().AddDate(0, -2, 0)
+func (h *UserHandler) sendMyVotedEvents(ctx context.Context, b Messenger, update *models.Update) {
 	chatID := update.Message.Chat.ID
 	msgThreadID := update.Message.MessageThreadID
+	l := h.chatSettings.GetLocalizer(ctx, chatID)
 
 	user := getUserFullName(update.Message.From)
 	userID := update.Message.From.ID
 	events, eventUsers, err := h.eventDAO.GetEventsVotedByUser(user, userID)
 	if err != nil {
-		log.Println("failed to get events voted", user, err)
+		slog.ErrorContext(ctx, "failed to get events voted", "err", err)
 		return
 	}
 	filteredEvents := make([]*Event, 0, len(events))
-	startTime := getCurrentMonthInUTC().AddDate(0, -2, 0)
+	startTime := getCurrentMonth(l.Location).AddDate(0, -2, 0)
 	for _, event := range events {
 		if event.StartedAt == nil || event.StartedAt.After(startTime) {
 			filteredEvents = append(filteredEvents, event)
@@ -60,18 +62,18 @@ func (h *UserHandler) sendMyVotedEvents(ctx context.Context, b *bot.Bot, update
 		userOptions[eu.EventID] = append(userOptions[eu.EventID], eu.Option)
 	}
 
-	text := fmt.Sprintf("You Voted Events: %d\n", len(filteredEvents))
+	text := l.T(msgMyVotesTitle, len(filteredEvents)) + "\n"
 	for i, e := range filteredEvents {
-		text += fmt.Sprintf("*%d. Description:* %s", i+1, e.Description)
+		text += l.T(msgMyVotesEventTitle, i+1, e.Description)
 		if e.StartedAt != nil {
-			text += fmt.Sprintf("\n*Starts at:* %s", e.StartedAt.Format(displayTimeFormat))
+			text += "\n" + l.T(msgEventStartsAt, l.FormatTime(*e.StartedAt))
 		} else {
-			text += "\n*Starts at:* Not set"
+			text += "\n" + l.T(msgEventStartsAt, l.T(msgNotSet))
 		}
-		text += "\n*Voted Option(s):*\n"
+		text += "\n" + l.T(msgMyVotesVotedOptions) + "\n"
 		opts := userOptions[e.ID]
 		if len(opts) == 0 {
-			text += "None\n"
+			text += l.T(msgNone) + "\n"
 		} else {
 			for _, opt := range opts {
 				text += fmt.Sprintf("• %s\n", opt)
@@ -1,22 +1,326 @@
 package main
 
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+)
+
 type StateType string
 
 const (
-	CREATE_EVENT    StateType = "CREATE_EVENT"
-	UPDATE_EVENT    StateType = "UPDATE_EVENT"
-	ADD_ACTIVITY    StateType = "ADD_ACTIVITY"
-	UPDATE_ACTIVITY StateType = "UPDATE_ACTIVITY"
-	DELETE_ACTIVITY StateType = "DELETE_ACTIVITY"
+	CREATE_EVENT         StateType = "CREATE_EVENT"
+	UPDATE_EVENT         StateType = "UPDATE_EVENT"
+	ADD_ACTIVITY         StateType = "ADD_ACTIVITY"
+	UPDATE_ACTIVITY      StateType = "UPDATE_ACTIVITY"
+	DELETE_ACTIVITY      StateType = "DELETE_ACTIVITY"
+	CREATE_ACTIVITY_POLL StateType = "CREATE_ACTIVITY_POLL"
+	IMPORT_ACTIVITIES    StateType = "IMPORT_ACTIVITIES"
+
+	FILTER_ACTIVITIES_BY_LEAD StateType = "FILTER_ACTIVITIES_BY_LEAD"
+
+	stateStoreMemory = "memory"
+	// stateStoreDatabase keeps the states in the database selected by DatabaseConfig
+	stateStoreDatabase = "database"
+	// stateStoreSQLite is the former name of stateStoreDatabase
+	stateStoreSQLite = "sqlite"
+
+	defaultStateTTL          = 30 * time.Minute
+	stateExpiryCheckInterval = time.Minute
 )
 
 // State tracking for each user
 type UserState struct {
-	Step      int
-	StateType StateType
+	Field       string
+	PrevFields  []string
+	StateType   StateType
+	ChatID      int64
+	MsgThreadID int
+	// UserID is the user running the flow, only set by the flows which need it
+	UserID    int64
+	Timezone  string
+	Language  string
 	Event     Event
 	Activity  Activity
+	ExpiresAt time.Time
+	// Confirming is set while the user is asked to confirm the input of Field
+	Confirming bool
+	// Imports are the activities read by /workplan import, saved once confirmed
+	Imports []Activity
+}
+
+// clone returns a deep copy so that callers never share slices with the store
+func (s *UserState) clone() *UserState {
+	c := *s
+	c.PrevFields = append([]string(nil), s.PrevFields...)
+	c.Event.Options = append([]string(nil), s.Event.Options...)
+	c.Activity = cloneActivity(s.Activity)
+	c.Imports = nil
+	for _, activity := range s.Imports {
+		c.Imports = append(c.Imports, cloneActivity(activity))
+	}
+	return &c
+}
+
+func cloneActivity(a Activity) Activity {
+	a.CoLeads = append([]string(nil), a.CoLeads...)
+	a.CoLeadIDs = append([]int64(nil), a.CoLeadIDs...)
+	return a
+}
+
+// location returns the timezone of the chat the state belongs to
+func (s *UserState) location() *time.Location {
+	if tz, err := time.LoadLocation(s.Timezone); err == nil && s.Timezone != "" {
+		return tz
+	}
+	return AppConfig.Timezone
+}
+
+// localizer returns the language and timezone of the chat the state belongs to
+func (s *UserState) localizer() *Localizer {
+	if s.Language == "" {
+		return NewLocalizer(AppConfig.Language, s.location())
+	}
+	return NewLocalizer(s.Language, s.location())
 }
 
-// Map to track state for each user
-var userStates = make(map[string]*UserState)
+// goToField moves to the given wizard field and remembers the current one for /back
+func (s *UserState) goToField(field string) {
+	s.PrevFields = append(s.PrevFields, s.Field)
+	s.Field = field
+}
+
+// goBack returns to the previous field. It returns false if there is none
+func (s *UserState) goBack() bool {
+	if len(s.PrevFields) == 0 {
+		return false
+	}
+	s.Field = s.PrevFields[len(s.PrevFields)-1]
+	s.PrevFields = s.PrevFields[:len(s.PrevFields)-1]
+	return true
+}
+
+// StateStore keeps the in-progress conversation state of each user.
+// States returned by Get are copies; call Set after changing them.
+type StateStore interface {
+	Get(key string) (*UserState, bool)
+	Set(key string, state *UserState) error
+	Delete(key string) error
+	// PopExpired removes and returns all states expired at the given time
+	PopExpired(now time.Time) ([]*UserState, error)
+	// Count returns the number of states which have not expired
+	Count() (int, error)
+}
+
+// NewStateStore returns the store selected by the config. The database store is
+// shared by the replicas if the repositories are kept in PostgreSQL
+func NewStateStore(config StateStoreConfig, repos *Repositories) (StateStore, error) {
+	ttl := time.Duration(config.TTLMinutes) * time.Minute
+	if ttl <= 0 {
+		ttl = defaultStateTTL
+	}
+	switch config.Type {
+	case stateStoreMemory:
+		return NewMemoryStateStore(ttl), nil
+	case "", stateStoreDatabase, stateStoreSQLite:
+		if repos.postgres != nil {
+			return NewPostgresStateStore(repos.postgres, ttl), nil
+		}
+		return NewSQLiteStateStore(repos.sqlite, ttl), nil
+	}
+	return nil, fmt.Errorf("unsupported state store type %q", config.Type)
+}
+
+// MemoryStateStore is a mutex protected in-memory StateStore
+type MemoryStateStore struct {
+	mu     sync.Mutex
+	ttl    time.Duration
+	states map[string]*UserState
+}
+
+func NewMemoryStateStore(ttl time.Duration) *MemoryStateStore {
+	return &MemoryStateStore{ttl: ttl, states: make(map[string]*UserState)}
+}
+
+func (s *MemoryStateStore) Get(key string) (*UserState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[key]
+	if !ok || !state.ExpiresAt.After(time.Now()) {
+		return nil, false
+	}
+	return state.clone(), true
+}
+
+func (s *MemoryStateStore) Set(key string, state *UserState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state.ExpiresAt = time.Now().Add(s.ttl)
+	s.states[key] = state.clone()
+	return nil
+}
+
+func (s *MemoryStateStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, key)
+	return nil
+}
+
+func (s *MemoryStateStore) PopExpired(now time.Time) ([]*UserState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var expired []*UserState
+	for key, state := range s.states {
+		if !state.ExpiresAt.After(now) {
+			expired = append(expired, state)
+			delete(s.states, key)
+		}
+	}
+	return expired, nil
+}
+
+func (s *MemoryStateStore) Count() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := 0
+	now := time.Now()
+	for _, state := range s.states {
+		if state.ExpiresAt.After(now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// SQLiteStateStore persists states in the user_states table so that
+// in-progress flows survive a restart
+type SQLiteStateStore struct {
+	db  *sql.DB
+	ttl time.Duration
+}
+
+func NewSQLiteStateStore(db *sql.DB, ttl time.Duration) *SQLiteStateStore {
+	return &SQLiteStateStore{db: db, ttl: ttl}
+}
+
+func (s *SQLiteStateStore) Get(key string) (*UserState, bool) {
+	query := `SELECT state FROM user_states WHERE key = ? AND expires_at > ?`
+	var stateStr string
+	err := s.db.QueryRow(query, key, time.Now().Unix()).Scan(&stateStr)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			slog.Error("error getting user state", "key", key, "err", err)
+		}
+		return nil, false
+	}
+	var state UserState
+	if err := json.Unmarshal([]byte(stateStr), &state); err != nil {
+		slog.Error("error decoding user state", "key", key, "err", err)
+		return nil, false
+	}
+	return &state, true
+}
+
+func (s *SQLiteStateStore) Set(key string, state *UserState) error {
+	state.ExpiresAt = time.Now().Add(s.ttl)
+	stateStr, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO user_states (key, state, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET state = excluded.state, expires_at = excluded.expires_at`
+	_, err = s.db.Exec(query, key, string(stateStr), state.ExpiresAt.Unix())
+	return err
+}
+
+func (s *SQLiteStateStore) Delete(key string) error {
+	_, err := s.db.Exec(`DELETE FROM user_states WHERE key = ?`, key)
+	return err
+}
+
+func (s *SQLiteStateStore) Count() (int, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM user_states WHERE expires_at > ?`, time.Now().Unix()).Scan(&count)
+	return count, err
+}
+
+func (s *SQLiteStateStore) PopExpired(now time.Time) ([]*UserState, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT key, state FROM user_states WHERE expires_at <= ?`, now.Unix())
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	var expired []*UserState
+	for rows.Next() {
+		var key, stateStr string
+		if err := rows.Scan(&key, &stateStr); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		keys = append(keys, key)
+		var state UserState
+		if err := json.Unmarshal([]byte(stateStr), &state); err != nil {
+			slog.Error("error decoding expired user state", "key", key, "err", err)
+			continue
+		}
+		expired = append(expired, &state)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		if _, err := tx.Exec(`DELETE FROM user_states WHERE key = ?`, key); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return expired, nil
+}
+
+// runStateExpiry periodically drops abandoned states and lets the user know
+func runStateExpiry(ctx context.Context, b Messenger, store StateStore) {
+	ticker := time.NewTicker(stateExpiryCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			expired, err := store.PopExpired(now)
+			if err != nil {
+				slog.ErrorContext(ctx, "error expiring user states", "err", err)
+				continue
+			}
+			for _, state := range expired {
+				if state.ChatID == 0 {
+					continue
+				}
+				_, err := b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID:          state.ChatID,
+					MessageThreadID: state.MsgThreadID,
+					Text:            state.localizer().T(msgStateExpired),
+				})
+				if err != nil {
+					slog.ErrorContext(ctx, "error sending state timeout message", "chat_id", state.ChatID, "err", err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testStateStore(t *testing.T, store StateStore) {
+	key := "1:0:1"
+
+	if _, ok := store.Get(key); ok {
+		t.Fatalf("Expected no state for %s", key)
+	}
+
+	state := &UserState{Field: "name", StateType: ADD_ACTIVITY, ChatID: 1}
+	if err := store.Set(key, state); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	got, ok := store.Get(key)
+	if !ok {
+		t.Fatalf("Expected state for %s", key)
+	}
+	if count, err := store.Count(); err != nil || count != 1 {
+		t.Errorf("Expected 1 state, got %d (%v)", count, err)
+	}
+	if got.Field != "name" || got.StateType != ADD_ACTIVITY || got.ChatID != 1 {
+		t.Errorf("Unexpected state %+v", got)
+	}
+
+	// changes to a returned state are not visible until Set is called
+	got.Field = "org"
+	if again, _ := store.Get(key); again.Field != "name" {
+		t.Errorf("Expected stored field to be name, got %s", again.Field)
+	}
+
+	expired, err := store.PopExpired(time.Now())
+	if err != nil {
+		t.Fatalf("PopExpired failed: %v", err)
+	}
+	if len(expired) != 0 {
+		t.Errorf("Expected no expired states, got %d", len(expired))
+	}
+
+	expired, err = store.PopExpired(time.Now().Add(defaultStateTTL + time.Minute))
+	if err != nil {
+		t.Fatalf("PopExpired failed: %v", err)
+	}
+	if len(expired) != 1 || expired[0].ChatID != 1 {
+		t.Errorf("Expected the state to expire, got %+v", expired)
+	}
+	if _, ok := store.Get(key); ok {
+		t.Errorf("Expected expired state to be removed")
+	}
+
+	if err := store.Set(key, state); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := store.Delete(key); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, ok := store.Get(key); ok {
+		t.Errorf("Expected deleted state to be removed")
+	}
+}
+
+func TestMemoryStateStore(t *testing.T) {
+	testStateStore(t, NewMemoryStateStore(defaultStateTTL))
+}
+
+func TestSQLiteStateStore(t *testing.T) {
+	db := setupTestDB(t)
+	defer cleanupTestDB(t, db)
+
+	if err := MigrateDB(db, time.UTC); err != nil {
+		t.Fatalf("MigrateDB failed: %v", err)
+	}
+	testStateStore(t, NewSQLiteStateStore(db, defaultStateTTL))
+}
+
+func TestNewStateStore(t *testing.T) {
+	repos := &Repositories{sqlite: setupTestDB(t)}
+	defer cleanupTestDB(t, repos.sqlite)
+
+	for _, tt := range []struct {
+		storeType string
+		want      StateStore
+	}{
+		{"", &SQLiteStateStore{}},
+		{stateStoreDatabase, &SQLiteStateStore{}},
+		{stateStoreSQLite, &SQLiteStateStore{}},
+		{stateStoreMemory, &MemoryStateStore{}},
+	} {
+		store, err := NewStateStore(StateStoreConfig{Type: tt.storeType}, repos)
+		if err != nil || reflect.TypeOf(store) != reflect.TypeOf(tt.want) {
+			t.Errorf("NewStateStore(%q) = %T, %v, want %T", tt.storeType, store, err, tt.want)
+		}
+	}
+	if _, err := NewStateStore(StateStoreConfig{Type: "redis"}, repos); err == nil {
+		t.Error("Expected an unsupported state store type to fail")
+	}
+}
+
+func TestUserStateGoBack(t *testing.T) {
+	state := &UserState{Field: "name"}
+	if state.goBack() {
+		t.Errorf("Expected no previous field at the first field")
+	}
+	state.goToField("startedAt")
+	state.goToField("org")
+	if !state.goBack() || state.Field != "startedAt" {
+		t.Errorf("Expected to go back to startedAt, got %s", state.Field)
+	}
+	if !state.goBack() || state.Field != "name" {
+		t.Errorf("Expected to go back to name, got %s", state.Field)
+	}
+	if state.goBack() {
+		t.Errorf("Expected no previous field at the first field")
+	}
+}
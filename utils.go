@@ -26,6 +26,18 @@ func getCommandArgument(update *models.Update) string {
 	return parts[1]
 }
 
+// getCommandArguments returns the words after the command
+func getCommandArguments(update *models.Update) []string {
+	if update.Message == nil {
+		return nil
+	}
+	parts := strings.Fields(update.Message.Text)
+	if len(parts) < 2 {
+		return nil
+	}
+	return parts[1:]
+}
+
 func getUserStateKey(chatID int64, msgThreadID int, user *models.User) string {
 	return fmt.Sprintf("%d:%d:%d", chatID, msgThreadID, user.ID)
 }
@@ -37,17 +49,20 @@ func isSameUser(toCheck *models.User, fullname string, userID int64) bool {
 	return getUserFullName(toCheck) == fullname
 }
 
-func getCurrentMonthInUTC() time.Time {
-	// for the ease of user interaction, user input time is just stored as UTC while user has the expectation of in local time
-	// hence, we would need to convert to UTC while still preserving the local time's month
-	now := time.Now().In(AppConfig.Timezone)
-	// Convert to UTC while preserving the local time's month
-	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+// getCurrentMonth returns the beginning of the current month in the given timezone
+func getCurrentMonth(loc *time.Location) time.Time {
+	now := time.Now().In(loc)
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
 }
 
-// add app local timezone to the event but keep the clock unchanged
-func addLocalTimezone(t time.Time) time.Time {
-	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), AppConfig.Timezone)
+// parseUserInputTime parses a user friendly date time in the given timezone.
+// Times are stored as UTC instants
+func parseUserInputTime(input string, loc *time.Location) (time.Time, error) {
+	t, err := parseDateTime(input, time.Now(), loc)
+	if err != nil {
+		return t, err
+	}
+	return t.UTC(), nil
 }
 
 func getBeginingOfDay(t time.Time) time.Time {
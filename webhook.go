@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-telegram/bot"
+)
+
+const (
+	webhookSecretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+	webhookShutdownTimeout   = 10 * time.Second
+)
+
+// enabled reports whether the bot receives updates by webhook instead of long polling
+func (c WebhookConfig) enabled() bool {
+	return c.PublicURL != ""
+}
+
+// runWebhook registers the webhook, serves updates on the listen address until ctx is done
+// and deregisters the webhook on shutdown
+func runWebhook(ctx context.Context, b *bot.Bot, cfg WebhookConfig) error {
+	ln, err := net.Listen("tcp", cfg.ListenAddr)
+	if err != nil {
+		return err
+	}
+	return serveWebhook(ctx, b, cfg, ln)
+}
+
+func serveWebhook(ctx context.Context, b *bot.Bot, cfg WebhookConfig, ln net.Listener) error {
+	publicURL, err := url.Parse(cfg.PublicURL)
+	if err != nil {
+		ln.Close()
+		return err
+	}
+	path := publicURL.Path
+	if path == "" {
+		path = "/"
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, getWebhookHandler(b, cfg.SecretToken))
+	server := &http.Server{Handler: mux}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Serve(ln)
+	}()
+
+	_, err = b.SetWebhook(ctx, &bot.SetWebhookParams{
+		URL:         cfg.PublicURL,
+		SecretToken: cfg.SecretToken,
+	})
+	if err != nil {
+		server.Close()
+		return err
+	}
+	slog.Info("webhook registered", "url", cfg.PublicURL, "addr", ln.Addr().String())
+
+	go b.StartWebhook(ctx)
+
+	select {
+	case <-ctx.Done():
+	case err = <-serverErr:
+		slog.Error("webhook server stopped", "err", err)
+	}
+
+	// ctx is done already, deregister with a fresh one
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), webhookShutdownTimeout)
+	defer cancel()
+	if _, err := b.DeleteWebhook(shutdownCtx, &bot.DeleteWebhookParams{}); err != nil {
+		slog.Error("error deleting webhook", "err", err)
+	} else {
+		slog.Info("webhook deleted")
+	}
+	if shutdownErr := server.Shutdown(shutdownCtx); shutdownErr != nil {
+		slog.Error("error shutting down webhook server", "err", shutdownErr)
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// getWebhookHandler rejects requests without the secret token before passing them to the bot
+func getWebhookHandler(b *bot.Bot, secretToken string) http.Handler {
+	next := b.WebhookHandler()
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		token := req.Header.Get(webhookSecretTokenHeader)
+		if secretToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(secretToken)) != 1 {
+			slog.Warn("webhook request with invalid secret token", "remote_addr", req.RemoteAddr)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, req)
+	})
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// fakeBotAPI records the methods called on the Bot API
+type fakeBotAPI struct {
+	mu      sync.Mutex
+	methods []string
+	forms   map[string]map[string]string
+}
+
+func (f *fakeBotAPI) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	method := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+	form := make(map[string]string)
+	if err := req.ParseMultipartForm(1 << 20); err == nil {
+		for k, v := range req.MultipartForm.Value {
+			form[k] = v[0]
+		}
+	}
+	f.mu.Lock()
+	f.methods = append(f.methods, method)
+	f.forms[method] = form
+	f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"ok":true,"result":true}`))
+}
+
+func (f *fakeBotAPI) form(method string) (map[string]string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	form, ok := f.forms[method]
+	return form, ok
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServeWebhook(t *testing.T) {
+	api := &fakeBotAPI{forms: make(map[string]map[string]string)}
+	apiServer := httptest.NewServer(api)
+	defer apiServer.Close()
+
+	updates := make(chan *models.Update, 1)
+	b, err := bot.New("123:token",
+		bot.WithServerURL(apiServer.URL),
+		bot.WithSkipGetMe(),
+		bot.WithWebhookSecretToken("secret"),
+		bot.WithDefaultHandler(func(ctx context.Context, b *bot.Bot, update *models.Update) {
+			updates <- update
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create bot: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	cfg := WebhookConfig{PublicURL: "https://example.com/telegram/hook", SecretToken: "secret"}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- serveWebhook(ctx, b, cfg, ln)
+	}()
+
+	waitFor(t, func() bool {
+		_, ok := api.form("setWebhook")
+		return ok
+	})
+	form, _ := api.form("setWebhook")
+	if form["url"] != cfg.PublicURL || form["secret_token"] != cfg.SecretToken {
+		t.Errorf("Unexpected setWebhook params %v", form)
+	}
+
+	hookURL := "http://" + ln.Addr().String() + "/telegram/hook"
+	// an idle keep-alive connection would hold up the server shutdown
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	post := func(token string) int {
+		req, _ := http.NewRequest(http.MethodPost, hookURL, strings.NewReader(`{"update_id":1,"message":{"message_id":2,"text":"hi","chat":{"id":3}}}`))
+		if token != "" {
+			req.Header.Set(webhookSecretTokenHeader, token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to post update: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := post("wrong"); code != http.StatusUnauthorized {
+		t.Errorf("Expected %d for a wrong secret token, got %d", http.StatusUnauthorized, code)
+	}
+	if code := post(""); code != http.StatusUnauthorized {
+		t.Errorf("Expected %d without secret token, got %d", http.StatusUnauthorized, code)
+	}
+	if code := post("secret"); code != http.StatusOK {
+		t.Errorf("Expected %d for a valid update, got %d", http.StatusOK, code)
+	}
+	select {
+	case update := <-updates:
+		if update.Message == nil || update.Message.Text != "hi" {
+			t.Errorf("Unexpected update %+v", update)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Update was not handled")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("serveWebhook returned %v", err)
+		}
+	case <-time.After(webhookShutdownTimeout + 5*time.Second):
+		t.Fatal("serveWebhook did not stop")
+	}
+	if _, ok := api.form("deleteWebhook"); !ok {
+		t.Error("Expected the webhook to be deleted on shutdown")
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// wizardInputCallbackPrefix marks the buttons returned by WizardField.Keyboard,
+// the callback data after the prefix is handled as if the user had typed it
+const wizardInputCallbackPrefix = "wizIn"
+
+// inputs of the buttons shown with the warning of WizardField.Confirm
+const (
+	wizardConfirmInput = "confirm"
+	wizardBackInput    = "back"
+)
+
+// WizardInput is a single user input for the current wizard field
+type WizardInput struct {
+	Text string
+	User *models.User
+	// Entities are the entities of the text message, such as mentions of users
+	Entities []models.MessageEntity
+	// Document is the file sent instead of a text, see ReadDocument
+	Document *models.Document
+
+	messenger Messenger
+}
+
+// ReadDocument downloads the file of the input, up to maxSize bytes
+func (input WizardInput) ReadDocument(ctx context.Context, maxSize int64) ([]byte, error) {
+	return downloadFile(ctx, input.messenger, input.Document.FileID, maxSize)
+}
+
+// WizardField is a named input collected by a Wizard
+type WizardField struct {
+	Name       string
+	Prompt     MessageKey
+	PromptArgs []any
+	// Keyboard returns optional inline buttons shown above the Cancel button, see getWizardInputButton
+	Keyboard func(state *UserState) [][]models.InlineKeyboardButton
+	// Parse validates the input and stores it in the state.
+	// The error message is sent back to the user, who is asked to try again
+	Parse func(ctx context.Context, input WizardInput, state *UserState) error
+	// Echo optionally confirms how the input was interpreted, nothing is sent if it returns ""
+	Echo func(state *UserState) string
+	// Confirm optionally returns an HTML warning about the parsed input. The user
+	// then confirms the input or goes back to enter it again
+	Confirm func(ctx context.Context, state *UserState) string
+	// DatePicker shows an inline calendar and time picker as an alternative to typing
+	DatePicker bool
+}
+
+type WizardFunc func(ctx context.Context, b Messenger, userStateKey string, state *UserState)
+
+// Wizard drives a multi-step flow declared as a list of fields.
+// Fields are collected one after another and OnComplete is called after the last one.
+// EditFields can be selected individually with Edit, OnEdit is called after each of them.
+// While no field is selected, ShowMenu is called to let the user pick the next field.
+type Wizard struct {
+	StateType  StateType
+	Fields     []WizardField
+	EditFields []WizardField
+	OnComplete WizardFunc
+	OnEdit     WizardFunc
+	ShowMenu   WizardFunc
+
+	userStates   StateStore
+	chatSettings ChatSettingsRepository
+}
+
+// Start begins collecting the fields from the first one
+func (w *Wizard) Start(ctx context.Context, b Messenger, userStateKey string, state *UserState) {
+	state.StateType = w.StateType
+	w.setLocale(ctx, state)
+	state.Field = ""
+	state.PrevFields = nil
+	if len(w.Fields) > 0 {
+		state.Field = w.Fields[0].Name
+	}
+	w.save(ctx, userStateKey, state)
+	w.prompt(ctx, b, userStateKey, state)
+}
+
+// Edit selects a single field to be collected
+func (w *Wizard) Edit(ctx context.Context, b Messenger, userStateKey string, state *UserState, fieldName string) {
+	if w.editField(fieldName) == nil {
+		slog.WarnContext(ctx, "unknown wizard field", "state_type", w.StateType, "field", fieldName)
+		return
+	}
+	state.StateType = w.StateType
+	if state.Timezone == "" || state.Language == "" {
+		w.setLocale(ctx, state)
+	}
+	switch {
+	case w.editField(state.Field) != nil:
+		// another field is being edited, switch to the newly selected one
+		state.Field = fieldName
+	case w.ShowMenu != nil:
+		state.goToField(fieldName)
+	default:
+		state.Field = fieldName
+	}
+	w.save(ctx, userStateKey, state)
+	w.prompt(ctx, b, userStateKey, state)
+}
+
+// ShowCurrentMenu leaves the current field and shows the menu
+func (w *Wizard) ShowCurrentMenu(ctx context.Context, b Messenger, userStateKey string, state *UserState) {
+	state.goToField("")
+	w.save(ctx, userStateKey, state)
+	w.prompt(ctx, b, userStateKey, state)
+}
+
+// HandleInput parses the input for the current field and moves on
+func (w *Wizard) HandleInput(ctx context.Context, b Messenger, userStateKey string, state *UserState, input WizardInput) {
+	if state.Field == "" {
+		// waiting for the user to select a field from the menu
+		return
+	}
+	field := w.currentField(state)
+	if field == nil {
+		slog.WarnContext(ctx, "unknown wizard field", "state_type", w.StateType, "field", state.Field)
+		return
+	}
+
+	if state.Confirming {
+		state.Confirming = false
+		switch input.Text {
+		case wizardConfirmInput:
+			w.moveOn(ctx, b, userStateKey, state)
+			return
+		case wizardBackInput:
+			w.save(ctx, userStateKey, state)
+			w.prompt(ctx, b, userStateKey, state)
+			return
+		}
+		// any other input replaces the one to be confirmed
+	}
+	if !w.parse(ctx, b, field, input, state) {
+		return
+	}
+	if field.Confirm != nil {
+		if warning := field.Confirm(ctx, state); warning != "" {
+			state.Confirming = true
+			w.save(ctx, userStateKey, state)
+			w.askToConfirm(ctx, b, state, warning)
+			return
+		}
+	}
+	w.moveOn(ctx, b, userStateKey, state)
+}
+
+// moveOn continues after the current field is collected: back to the menu after
+// editing a field, otherwise to the next field or OnComplete after the last one
+func (w *Wizard) moveOn(ctx context.Context, b Messenger, userStateKey string, state *UserState) {
+	if w.editField(state.Field) != nil && w.field(state.Field) == nil {
+		if !state.goBack() {
+			state.Field = ""
+		}
+		w.save(ctx, userStateKey, state)
+		w.OnEdit(ctx, b, userStateKey, state)
+		return
+	}
+	if next := w.nextField(state.Field); next != nil {
+		state.goToField(next.Name)
+		w.save(ctx, userStateKey, state)
+		w.prompt(ctx, b, userStateKey, state)
+		return
+	}
+	w.save(ctx, userStateKey, state)
+	w.OnComplete(ctx, b, userStateKey, state)
+}
+
+// askToConfirm sends the warning with buttons to confirm the input or go back
+func (w *Wizard) askToConfirm(ctx context.Context, b Messenger, state *UserState, warning string) {
+	l := state.localizer()
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          state.ChatID,
+		MessageThreadID: state.MsgThreadID,
+		Text:            warning,
+		ParseMode:       "HTML",
+		ReplyMarkup: &models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{
+				{
+					getWizardInputButton(l.T(msgButtonConfirm), wizardConfirmInput),
+					getWizardInputButton(l.T(msgButtonBack), wizardBackInput),
+				},
+				getCancelButtonRow(l),
+			},
+		},
+	})
+}
+
+// Back returns to the previous field and prompts for it again
+func (w *Wizard) Back(ctx context.Context, b Messenger, userStateKey string, state *UserState) bool {
+	if !state.goBack() {
+		return false
+	}
+	w.save(ctx, userStateKey, state)
+	w.prompt(ctx, b, userStateKey, state)
+	return true
+}
+
+func (w *Wizard) parse(ctx context.Context, b Messenger, field *WizardField, input WizardInput, state *UserState) bool {
+	err := field.Parse(ctx, input, state)
+	if err == nil {
+		if field.Echo == nil {
+			return true
+		}
+		if text := field.Echo(state); text != "" {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID:          state.ChatID,
+				MessageThreadID: state.MsgThreadID,
+				Text:            text,
+			})
+		}
+		return true
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          state.ChatID,
+		MessageThreadID: state.MsgThreadID,
+		Text:            err.Error(),
+		ReplyMarkup:     w.keyboard(field, state),
+	})
+	return false
+}
+
+func (w *Wizard) prompt(ctx context.Context, b Messenger, userStateKey string, state *UserState) {
+	if state.Field == "" {
+		if w.ShowMenu != nil {
+			w.ShowMenu(ctx, b, userStateKey, state)
+		}
+		return
+	}
+	field := w.currentField(state)
+	if field == nil {
+		slog.WarnContext(ctx, "unknown wizard field", "state_type", w.StateType, "field", state.Field)
+		return
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:          state.ChatID,
+		MessageThreadID: state.MsgThreadID,
+		Text:            state.localizer().T(field.Prompt, field.PromptArgs...),
+		ReplyMarkup:     w.keyboard(field, state),
+	})
+}
+
+func (w *Wizard) keyboard(field *WizardField, state *UserState) *models.InlineKeyboardMarkup {
+	l := state.localizer()
+	var rows [][]models.InlineKeyboardButton
+	if field.DatePicker {
+		rows = getDatePickerMonthRows(getCurrentMonth(l.Location), l)
+	}
+	if field.Keyboard != nil {
+		rows = append(rows, field.Keyboard(state)...)
+	}
+	return &models.InlineKeyboardMarkup{
+		InlineKeyboard: append(rows, getCancelButtonRow(l)),
+	}
+}
+
+// getWizardInputButton returns a button which submits the input to the current field
+func getWizardInputButton(text, input string) models.InlineKeyboardButton {
+	return models.InlineKeyboardButton{
+		Text:         text,
+		CallbackData: strings.Join([]string{wizardInputCallbackPrefix, input}, callbackSeparator),
+	}
+}
+
+// setLocale keeps the timezone and language of the chat for the whole flow
+func (w *Wizard) setLocale(ctx context.Context, state *UserState) {
+	l := w.chatSettings.GetLocalizer(ctx, state.ChatID)
+	state.Timezone = l.Location.String()
+	state.Language = l.Language
+}
+
+func (w *Wizard) save(ctx context.Context, userStateKey string, state *UserState) {
+	if err := w.userStates.Set(userStateKey, state); err != nil {
+		slog.ErrorContext(ctx, "error saving user state", "key", userStateKey, "err", err)
+	}
+}
+
+func (w *Wizard) currentField(state *UserState) *WizardField {
+	if field := w.field(state.Field); field != nil {
+		return field
+	}
+	return w.editField(state.Field)
+}
+
+func (w *Wizard) field(name string) *WizardField {
+	for i := range w.Fields {
+		if w.Fields[i].Name == name {
+			return &w.Fields[i]
+		}
+	}
+	return nil
+}
+
+func (w *Wizard) editField(name string) *WizardField {
+	for i := range w.EditFields {
+		if w.EditFields[i].Name == name {
+			return &w.EditFields[i]
+		}
+	}
+	return nil
+}
+
+func (w *Wizard) nextField(name string) *WizardField {
+	for i := range w.Fields {
+		if w.Fields[i].Name == name && i+1 < len(w.Fields) {
+			return &w.Fields[i+1]
+		}
+	}
+	return nil
+}